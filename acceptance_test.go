@@ -29,6 +29,7 @@ import (
 	"github.com/conduitio/conduit-connector-google-sheets/source"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"go.uber.org/goleak"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
@@ -114,7 +115,7 @@ func TestAcceptance(t *testing.T) {
 	spreadsheetID = conf.GoogleSpreadsheetID
 	sheetID = conf.GoogleSheetID
 
-	client := conf.OAuthConfig.Client(ctx, conf.OAuthToken)
+	client := oauth2.NewClient(ctx, conf.TokenSource(ctx))
 	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		t.Fatal(err)
@@ -44,6 +44,8 @@ var (
 	scopes = []string{
 		"https://www.googleapis.com/auth/spreadsheets.readonly",
 		"https://www.googleapis.com/auth/spreadsheets",
+		"https://www.googleapis.com/auth/drive.metadata.readonly",
+		"https://www.googleapis.com/auth/drive.file",
 	}
 	defaultCredentialFile = "./credentials.json"
 	credFile              string
@@ -17,25 +17,64 @@ limitations under the License.
 package config
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
+	"golang.org/x/oauth2/jwt"
 )
 
 const (
 	// KeyCredentialsFile is the config name for Google access key
 	KeyCredentialsFile = "credentialsFile"
 
+	// KeyCredentialsJSON is the config name for the Google credentials JSON passed inline,
+	// alternative to KeyCredentialsFile
+	KeyCredentialsJSON = "credentialsJSON"
+
+	// KeyCredentialsJSONBase64 is the config name for the base64 encoded Google credentials JSON,
+	// alternative to KeyCredentialsFile
+	KeyCredentialsJSONBase64 = "credentialsJSONBase64"
+
 	// KeyTokensFile is the config name for google generated token file
 	KeyTokensFile = "tokensFile"
 
+	// KeyTokenJSON is the config name for the token JSON passed inline, alternative to KeyTokensFile
+	KeyTokenJSON = "tokenJSON"
+
+	// KeyTokenJSONBase64 is the config name for the base64 encoded token JSON, alternative to KeyTokensFile
+	KeyTokenJSONBase64 = "tokenJSONBase64"
+
 	// KeySheetURL is the config name for google-sheets url
 	KeySheetURL = "sheetsURL"
+
+	// KeySpreadsheetID is the config name for the spreadsheet ID, alternative to KeySheetURL
+	KeySpreadsheetID = "spreadsheetId"
+
+	// KeyAuthMode is the config name for the authentication mode, one of AuthModeOAuth, AuthModeServiceAccount.
+	// The mode is detected from the credentials when omitted.
+	KeyAuthMode = "authMode"
+
+	// KeySubject is the config name for the user to impersonate using domain-wide delegation,
+	// only valid for service account authentication
+	KeySubject = "subject"
+)
+
+const (
+	// AuthModeOAuth authenticates using an OAuth client credentials file and a user token file
+	AuthModeOAuth = "oauth"
+	// AuthModeServiceAccount authenticates using a service account JSON key
+	AuthModeServiceAccount = "serviceAccount"
+
+	// serviceAccountType is the value of the "type" field in a service account JSON key
+	serviceAccountType = "service_account"
 )
 
 var (
@@ -43,77 +82,186 @@ var (
 	scopes = []string{
 		"https://www.googleapis.com/auth/spreadsheets.readonly",
 		"https://www.googleapis.com/auth/spreadsheets",
+		"https://www.googleapis.com/auth/drive.metadata.readonly",
+		"https://www.googleapis.com/auth/drive.file",
 	}
 	sheetsRegexp = regexp.MustCompile(`\/spreadsheets\/d\/([a-zA-Z0-9-_]+)\/(.*)#gid=([0-9]+)`)
 )
 
 // Config represent configuration needed for google-sheets
 type Config struct {
-	OAuthConfig         *oauth2.Config
-	OAuthToken          *oauth2.Token
+	// AuthMode is the authentication mode used to build the Sheets client
+	AuthMode string
+	// OAuthConfig and OAuthToken are set in AuthModeOAuth
+	OAuthConfig *oauth2.Config
+	OAuthToken  *oauth2.Token
+	// TokenStore is used to persist the refreshed OAuth token, nil if the token isn't read from a file
+	TokenStore TokenStore
+	// JWTConfig is set in AuthModeServiceAccount
+	JWTConfig           *jwt.Config
 	GoogleSpreadsheetID string
 	GoogleSheetID       int64
+	// HasSheetID is true if GoogleSheetID is set from the gid in the sheet URL
+	HasSheetID bool
 }
 
 // Parse attempts to parse plugins.Config into a Config struct
 func Parse(config map[string]string) (Config, error) {
-	// check if configs exist
-	credFile := config[KeyCredentialsFile]
-	if credFile == "" {
-		return Config{}, requiredConfigErr(KeyCredentialsFile)
+	authMode := config[KeyAuthMode]
+	if authMode != "" && authMode != AuthModeOAuth && authMode != AuthModeServiceAccount {
+		return Config{}, fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `%s`, `%s`",
+			authMode, KeyAuthMode, AuthModeOAuth, AuthModeServiceAccount,
+		)
 	}
 
-	tokenFile := config[KeyTokensFile]
-	if tokenFile == "" {
-		return Config{}, requiredConfigErr(KeyTokensFile)
+	// parse credentials.json
+	credBytes, err := readSecret(config, "client secret", KeyCredentialsFile, KeyCredentialsJSON, KeyCredentialsJSONBase64)
+	if err != nil {
+		return Config{}, err
 	}
 
-	sheetURL := config[KeySheetURL]
-	if sheetURL == "" {
-		return Config{}, requiredConfigErr(KeySheetURL)
+	// the mode chosen and the reason are added to all the authentication errors
+	modeReason := "set in config"
+	if authMode == "" {
+		authMode = detectAuthMode(credBytes)
+		modeReason = "detected from credentials"
 	}
 
-	// parse credentials.json
-	credBytes, err := ioutil.ReadFile(credFile)
+	cfg := Config{AuthMode: authMode}
+	switch authMode {
+	case AuthModeServiceAccount:
+		cfg.JWTConfig, err = parseServiceAccount(credBytes, config[KeySubject])
+	default:
+		cfg.OAuthConfig, cfg.OAuthToken, err = parseOAuth(credBytes, config)
+		if tokenFile := config[KeyTokensFile]; tokenFile != "" {
+			cfg.TokenStore = FileTokenStore{Path: tokenFile}
+		}
+	}
 	if err != nil {
-		return Config{}, fmt.Errorf("unable to read client secret file: %w", err)
+		return Config{}, fmt.Errorf("auth mode %q (%s): %w", authMode, modeReason, err)
 	}
 
-	// validate if the credentials are google credentials
-	oauthConfig, err := google.ConfigFromJSON(credBytes, scopes...)
+	sheetURL := config[KeySheetURL]
+	spreadsheetID := config[KeySpreadsheetID]
+	switch {
+	case sheetURL == "" && spreadsheetID == "":
+		return Config{}, fmt.Errorf("one of %q, %q config value must be set", KeySheetURL, KeySpreadsheetID)
+	case sheetURL != "" && spreadsheetID != "":
+		return Config{}, fmt.Errorf("only one of %q, %q config value can be set", KeySheetURL, KeySpreadsheetID)
+	case spreadsheetID != "":
+		// the sheet is selected by the connector specific config
+		cfg.GoogleSpreadsheetID = spreadsheetID
+		return cfg, nil
+	}
+
+	// parse sheets url
+	spreadSheetID, sheetID, err := parseSheetURL(sheetURL)
 	if err != nil {
-		return Config{}, fmt.Errorf("unable to parse client secret file to config: %w", err)
+		// skip wrapping error, getting wrapped error from parseSheetURL function
+		return Config{}, err
 	}
 
+	cfg.GoogleSheetID = sheetID
+	cfg.GoogleSpreadsheetID = spreadSheetID
+	cfg.HasSheetID = true
+	return cfg, nil
+}
+
+// TokenSource returns the token source for the configured auth mode, used to build the Sheets client.
+// In OAuth mode with a token store, the refreshed tokens are persisted to the store
+// and the token source is shared with the other connectors using the same store.
+func (c Config) TokenSource(ctx context.Context) oauth2.TokenSource {
+	if c.AuthMode == AuthModeServiceAccount {
+		return c.JWTConfig.TokenSource(ctx)
+	}
+	if c.TokenStore != nil {
+		return sharedTokenSource(c.OAuthConfig, c.OAuthToken, c.TokenStore)
+	}
+	return c.OAuthConfig.TokenSource(ctx, c.OAuthToken)
+}
+
+// detectAuthMode returns AuthModeServiceAccount if the credentials are a service account JSON key,
+// AuthModeOAuth otherwise
+func detectAuthMode(credBytes []byte) string {
+	var cred struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(credBytes, &cred); err == nil && cred.Type == serviceAccountType {
+		return AuthModeServiceAccount
+	}
+	return AuthModeOAuth
+}
+
+func parseOAuth(credBytes []byte, config map[string]string) (*oauth2.Config, *oauth2.Token, error) {
 	// parse tokens file
-	var token *oauth2.Token
-	tokenBytes, err := ioutil.ReadFile(tokenFile)
+	tokenBytes, err := readSecret(config, "tokens", KeyTokensFile, KeyTokenJSON, KeyTokenJSONBase64)
 	if err != nil {
-		return Config{}, fmt.Errorf("unable to read tokens file: %w", err)
+		return nil, nil, err
 	}
 
-	if err := json.Unmarshal(tokenBytes, &token); err != nil {
-		return Config{}, fmt.Errorf("unable to unmarshal tokens file: %w", err)
+	if config[KeySubject] != "" {
+		return nil, nil, fmt.Errorf("%q config is only supported with service account credentials", KeySubject)
 	}
 
-	// parse sheets url
-	spreadSheetID, sheetID, err := parseSheetURL(sheetURL)
+	// validate if the credentials are google credentials
+	oauthConfig, err := google.ConfigFromJSON(credBytes, scopes...)
 	if err != nil {
-		// skip wrapping error, getting wrapped error from parseSheetURL function
-		return Config{}, err
+		return nil, nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+
+	var token *oauth2.Token
+	if err := json.Unmarshal(tokenBytes, &token); err != nil {
+		return nil, nil, fmt.Errorf("unable to unmarshal tokens file: %w", err)
 	}
+	return oauthConfig, token, nil
+}
 
-	cfg := Config{
-		OAuthConfig:         oauthConfig,
-		OAuthToken:          token,
-		GoogleSheetID:       sheetID,
-		GoogleSpreadsheetID: spreadSheetID,
+func parseServiceAccount(credBytes []byte, subject string) (*jwt.Config, error) {
+	jwtConfig, err := google.JWTConfigFromJSON(credBytes, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse service account key: %w", err)
 	}
-	return cfg, nil
+	if jwtConfig.Email == "" || len(jwtConfig.PrivateKey) == 0 {
+		return nil, fmt.Errorf("service account key must contain client_email and private_key")
+	}
+	// impersonate the subject user, requires domain-wide delegation to be enabled for the service account
+	jwtConfig.Subject = subject
+	return jwtConfig, nil
 }
 
-func requiredConfigErr(name string) error {
-	return fmt.Errorf("%q config value must be set", name)
+// readSecret returns the value of the secret passed either as a file path, as inline JSON or as base64 encoded JSON.
+// Exactly one of the three config keys is expected to be set.
+func readSecret(config map[string]string, name, fileKey, jsonKey, base64Key string) ([]byte, error) {
+	var keys []string
+	for _, key := range []string{fileKey, jsonKey, base64Key} {
+		if config[key] != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("one of %q, %q, %q config value must be set", fileKey, jsonKey, base64Key)
+	}
+	if len(keys) > 1 {
+		return nil, fmt.Errorf("only one of %q, %q, %q config value can be set, got: %q", fileKey, jsonKey, base64Key, keys)
+	}
+
+	switch keys[0] {
+	case jsonKey:
+		return []byte(config[jsonKey]), nil
+	case base64Key:
+		data, err := base64.StdEncoding.DecodeString(config[base64Key])
+		if err != nil {
+			return nil, fmt.Errorf("unable to decode %q config value: %w", base64Key, err)
+		}
+		return data, nil
+	default:
+		data, err := ioutil.ReadFile(config[fileKey])
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s file: %w", name, err)
+		}
+		return data, nil
+	}
 }
 
 func parseSheetURL(url string) (string, int64, error) {
@@ -130,3 +278,14 @@ func parseSheetURL(url string) (string, int64, error) {
 	}
 	return stringMatches[1], sheetID, nil // spreadsheetID, sheetID, error
 }
+
+// SplitList splits the comma separated list, trimming the items and dropping the empty ones
+func SplitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -15,6 +15,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,6 +29,9 @@ func TestParse(t *testing.T) {
 	filePath := getFilePath("conduit-connector-google-sheets") // Get project root path
 	validCredFile := fmt.Sprintf("%s/testdata/dummy_cred.json", filePath)
 	invalidCredFile := fmt.Sprintf("%s/testdata/dummy_invalid_cred.json", filePath)
+	serviceAccountFile := fmt.Sprintf("%s/testdata/dummy_service_account.json", filePath)
+	credJSON, _ := os.ReadFile(validCredFile)
+	tokenJSON, _ := os.ReadFile(fmt.Sprintf("%s/testdata/dummy_token.json", filePath))
 	tests := []struct {
 		name   string
 		config map[string]string
@@ -36,7 +40,7 @@ func TestParse(t *testing.T) {
 	}{{
 		name:   "missing required params",
 		config: map[string]string{},
-		err:    fmt.Errorf(`"credentialsFile" config value must be set`),
+		err:    fmt.Errorf(`one of "credentialsFile", "credentialsJSON", "credentialsJSONBase64" config value must be set`),
 		want:   Config{},
 	}, {
 		name: "config succeeds",
@@ -47,8 +51,11 @@ func TestParse(t *testing.T) {
 		},
 		err: nil,
 		want: Config{
+			AuthMode:            AuthModeOAuth,
+			TokenStore:          FileTokenStore{Path: validCredFile},
 			GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 			GoogleSheetID:       158080911,
+			HasSheetID:          true,
 		},
 	}, {
 		name: "missing required token file params",
@@ -56,7 +63,7 @@ func TestParse(t *testing.T) {
 			KeyCredentialsFile: validCredFile,
 			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
 		},
-		err:  fmt.Errorf(`"tokensFile" config value must be set`),
+		err:  fmt.Errorf(`auth mode "oauth" (detected from credentials): one of "tokensFile", "tokenJSON", "tokenJSONBase64" config value must be set`),
 		want: Config{},
 	}, {
 		name: "missing required sheets url params",
@@ -64,7 +71,7 @@ func TestParse(t *testing.T) {
 			KeyTokensFile:      validCredFile,
 			KeyCredentialsFile: validCredFile,
 		},
-		err:  fmt.Errorf(`"sheetsURL" config value must be set`),
+		err:  fmt.Errorf(`one of "sheetsURL", "spreadsheetId" config value must be set`),
 		want: Config{},
 	}, {
 		name: "missing gid in sheets url",
@@ -82,7 +89,7 @@ func TestParse(t *testing.T) {
 			KeyCredentialsFile: validCredFile,
 			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit",
 		},
-		err:  fmt.Errorf("unable to read tokens file: open invalid_file.json: no such file or directory"),
+		err:  fmt.Errorf(`auth mode "oauth" (detected from credentials): unable to read tokens file: open invalid_file.json: no such file or directory`),
 		want: Config{},
 	}, {
 		name: "invalid creds",
@@ -91,7 +98,78 @@ func TestParse(t *testing.T) {
 			KeyCredentialsFile: invalidCredFile,
 			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit",
 		},
-		err:  fmt.Errorf("unable to parse client secret file to config: oauth2/google: no credentials found"),
+		err:  fmt.Errorf(`auth mode "oauth" (detected from credentials): unable to parse client secret file to config: oauth2/google: no credentials found`),
+		want: Config{},
+	}, {
+		name: "service account detected",
+		config: map[string]string{
+			KeyCredentialsFile: serviceAccountFile,
+			KeySubject:         "user@example.com",
+			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+		},
+		err: nil,
+		want: Config{
+			AuthMode:            AuthModeServiceAccount,
+			GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+			GoogleSheetID:       158080911,
+			HasSheetID:          true,
+		},
+	}, {
+		name: "service account mode with oauth credentials",
+		config: map[string]string{
+			KeyAuthMode:        AuthModeServiceAccount,
+			KeyCredentialsFile: validCredFile,
+			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+		},
+		err:  fmt.Errorf(`auth mode "serviceAccount" (set in config): unable to parse service account key: google: read JWT from JSON credentials: 'type' field is "" (expected "service_account")`),
+		want: Config{},
+	}, {
+		name: "subject with oauth credentials",
+		config: map[string]string{
+			KeyTokensFile:      validCredFile,
+			KeyCredentialsFile: validCredFile,
+			KeySubject:         "user@example.com",
+			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+		},
+		err:  fmt.Errorf(`auth mode "oauth" (detected from credentials): "subject" config is only supported with service account credentials`),
+		want: Config{},
+	}, {
+		name: "inline credentials and base64 token",
+		config: map[string]string{
+			KeyCredentialsJSON: string(credJSON),
+			KeyTokenJSONBase64: base64.StdEncoding.EncodeToString(tokenJSON),
+			KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+		},
+		err: nil,
+		want: Config{
+			AuthMode:            AuthModeOAuth,
+			GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+			GoogleSheetID:       158080911,
+			HasSheetID:          true,
+		},
+	}, {
+		name: "credentials file and inline credentials",
+		config: map[string]string{
+			KeyCredentialsFile: validCredFile,
+			KeyCredentialsJSON: string(credJSON),
+		},
+		err:  fmt.Errorf(`only one of "credentialsFile", "credentialsJSON", "credentialsJSONBase64" config value can be set, got: ["credentialsFile" "credentialsJSON"]`),
+		want: Config{},
+	}, {
+		name: "invalid base64 token",
+		config: map[string]string{
+			KeyCredentialsJSON: string(credJSON),
+			KeyTokenJSONBase64: "not base64",
+		},
+		err:  fmt.Errorf(`auth mode "oauth" (detected from credentials): unable to decode "tokenJSONBase64" config value: illegal base64 data at input byte 3`),
+		want: Config{},
+	}, {
+		name: "invalid auth mode",
+		config: map[string]string{
+			KeyAuthMode:        "apiKey",
+			KeyCredentialsFile: validCredFile,
+		},
+		err:  fmt.Errorf("invalid value (apiKey) for `authMode` config received, valid values: `oauth`, `serviceAccount`"),
 		want: Config{},
 	},
 	}
@@ -104,6 +182,10 @@ func TestParse(t *testing.T) {
 				assert.NoError(t, err)
 				tt.want.OAuthConfig = cfg.OAuthConfig
 				tt.want.OAuthToken = cfg.OAuthToken
+				if tt.want.AuthMode == AuthModeServiceAccount {
+					assert.Equal(t, tt.config[KeySubject], cfg.JWTConfig.Subject)
+				}
+				tt.want.JWTConfig = cfg.JWTConfig
 				assert.Equal(t, tt.want, cfg)
 			}
 		})
@@ -0,0 +1,124 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/conduitio/conduit-connector-google-sheets/internal/jsonfile"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"golang.org/x/oauth2"
+)
+
+var (
+	// sharedTokenSources holds the persisting token sources created in the process, keyed by the token store ID
+	// and the OAuth client ID, so that the source and destination connectors using the same token store and client
+	// share the refreshed token
+	sharedTokenSources    = make(map[string]*PersistingTokenSource)
+	sharedTokenSourcesMux sync.Mutex
+)
+
+// TokenStore persists the OAuth token, whenever the token is refreshed
+type TokenStore interface {
+	// ID uniquely identifies the store, e.g. the token file path
+	ID() string
+	// Save persists the token
+	Save(token *oauth2.Token) error
+}
+
+// FileTokenStore stores the token as JSON in the file at Path
+type FileTokenStore struct {
+	Path string
+}
+
+func (f FileTokenStore) ID() string {
+	if abs, err := filepath.Abs(f.Path); err == nil {
+		return abs
+	}
+	return f.Path
+}
+
+// Save atomically rewrites the token file, so that the token file is never left partially written
+func (f FileTokenStore) Save(token *oauth2.Token) error {
+	if err := jsonfile.Save(f.Path, token); err != nil {
+		return fmt.Errorf("error saving token: %w", err)
+	}
+	return nil
+}
+
+// PersistingTokenSource is an oauth2.TokenSource which saves the token to the TokenStore
+// every time the access token is refreshed or the refresh token is rotated
+type PersistingTokenSource struct {
+	ctx   context.Context
+	base  oauth2.TokenSource
+	store TokenStore
+	// last is the last token returned by base
+	last *oauth2.Token
+	mux  sync.Mutex
+}
+
+// NewPersistingTokenSource returns a token source refreshing the token using the oauth config,
+// persisting the refreshed tokens to the store
+func NewPersistingTokenSource(ctx context.Context, cfg *oauth2.Config, token *oauth2.Token, store TokenStore) *PersistingTokenSource {
+	return &PersistingTokenSource{
+		ctx:   ctx,
+		base:  cfg.TokenSource(ctx, token),
+		store: store,
+		last:  token,
+	}
+}
+
+// Token returns the cached token or refreshes it if expired. Failing to persist the token is logged
+// and does not fail the call, as the refreshed token is still valid for the current process.
+func (p *PersistingTokenSource) Token() (*oauth2.Token, error) {
+	p.mux.Lock()
+	defer p.mux.Unlock()
+
+	token, err := p.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	if p.last != nil && token.AccessToken == p.last.AccessToken && token.RefreshToken == p.last.RefreshToken {
+		return token, nil
+	}
+	if err := p.store.Save(token); err != nil {
+		sdk.Logger(p.ctx).Error().Err(err).Str("token_store", p.store.ID()).Msg("unable to persist refreshed token")
+		return token, nil
+	}
+	p.last = token
+	return token, nil
+}
+
+// sharedTokenSource returns the persisting token source for the store and the OAuth client, creating it if it doesn't
+// exist yet. A token refreshed by another client can't be used, so the token sources of the clients aren't shared.
+// The token source outlives the connector which created it, so it isn't bound to the connector's context.
+func sharedTokenSource(cfg *oauth2.Config, token *oauth2.Token, store TokenStore) *PersistingTokenSource {
+	sharedTokenSourcesMux.Lock()
+	defer sharedTokenSourcesMux.Unlock()
+
+	key := store.ID() + "|" + cfg.ClientID
+	if ts, ok := sharedTokenSources[key]; ok {
+		return ts
+	}
+	ts := NewPersistingTokenSource(context.Background(), cfg, token, store)
+	sharedTokenSources[key] = ts
+	return ts
+}
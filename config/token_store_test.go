@@ -0,0 +1,116 @@
+// Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+type staticTokenSource struct {
+	token *oauth2.Token
+}
+
+func (s *staticTokenSource) Token() (*oauth2.Token, error) {
+	return s.token, nil
+}
+
+type memoryTokenStore struct {
+	saved []*oauth2.Token
+}
+
+func (m *memoryTokenStore) ID() string {
+	return "memory"
+}
+
+func (m *memoryTokenStore) Save(token *oauth2.Token) error {
+	m.saved = append(m.saved, token)
+	return nil
+}
+
+func TestFileTokenStore_Save(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"refresh_token":"old"}`), 0600))
+
+	store := FileTokenStore{Path: path}
+	err := store.Save(&oauth2.Token{AccessToken: "access", RefreshToken: "new"})
+	assert.NoError(t, err)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var got oauth2.Token
+	assert.NoError(t, json.Unmarshal(data, &got))
+	assert.Equal(t, "access", got.AccessToken)
+	assert.Equal(t, "new", got.RefreshToken)
+
+	// temp files are renamed, nothing else should be left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestPersistingTokenSource_Token(t *testing.T) {
+	initial := &oauth2.Token{AccessToken: "access-1", RefreshToken: "refresh-1"}
+	base := &staticTokenSource{token: initial}
+	store := &memoryTokenStore{}
+	ts := &PersistingTokenSource{ctx: context.Background(), base: base, store: store, last: initial}
+
+	// unchanged token is not persisted
+	token, err := ts.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, initial, token)
+	assert.Len(t, store.saved, 0)
+
+	// refreshed access token is persisted
+	base.token = &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-1"}
+	_, err = ts.Token()
+	assert.NoError(t, err)
+	assert.Equal(t, []*oauth2.Token{base.token}, store.saved)
+
+	// rotated refresh token is persisted
+	base.token = &oauth2.Token{AccessToken: "access-2", RefreshToken: "refresh-2"}
+	_, err = ts.Token()
+	assert.NoError(t, err)
+	assert.Len(t, store.saved, 2)
+	assert.Equal(t, "refresh-2", store.saved[1].RefreshToken)
+}
+
+func TestConfig_TokenSource_Shared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token.json")
+	cfg := Config{
+		AuthMode:    AuthModeOAuth,
+		OAuthConfig: &oauth2.Config{},
+		OAuthToken:  &oauth2.Token{RefreshToken: "refresh"},
+		TokenStore:  FileTokenStore{Path: path},
+	}
+	ctx := context.Background()
+	assert.Same(t, cfg.TokenSource(ctx), cfg.TokenSource(ctx))
+
+	// the token source isn't shared with another client using the same token file
+	other := cfg
+	other.OAuthConfig = &oauth2.Config{ClientID: "other"}
+	assert.NotSame(t, cfg.TokenSource(ctx), other.TokenSource(ctx))
+	assert.Same(t, other.TokenSource(ctx), other.TokenSource(ctx))
+
+	cfg.TokenStore = nil
+	_, ok := cfg.TokenSource(ctx).(*PersistingTokenSource)
+	assert.False(t, ok)
+}
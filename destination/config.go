@@ -18,11 +18,19 @@ package destination
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/conduitio/conduit-connector-google-sheets/config"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
 )
 
+// columnLettersRegexp matches the column letters. e.g. A, AB
+var columnLettersRegexp = regexp.MustCompile(`^[A-Za-z]+$`)
+
 const (
 	// KeySheetName is the name of the sheet needed to fetch data.
 	KeySheetName = "sheetName"
@@ -30,6 +38,12 @@ const (
 	// KeyBufferSize is the config name for buffer size.
 	KeyBufferSize = "bufferSize"
 
+	// KeyFlushInterval is the config name for the interval the partially filled buffers are written at
+	KeyFlushInterval = "flushInterval"
+
+	// KeyMaxBufferBytes is the config name for the maximum size in bytes of the payloads of a buffer
+	KeyMaxBufferBytes = "maxBufferBytes"
+
 	// KeyValueInputOption is the config name for how the input data
 	// should be inserted.
 	KeyValueInputOption = "valueInputOption"
@@ -37,6 +51,60 @@ const (
 	// KeyMaxRetries is the config key for max retry
 	KeyMaxRetries = "maxRetries"
 
+	// KeyHeaderRow is the config name for the row number of the sheet holding the column names
+	KeyHeaderRow = "headerRow"
+
+	// KeyAppendHeaders is the config name for appending the record fields missing in the header row as new columns
+	KeyAppendHeaders = "appendHeaders"
+
+	// KeyWriteMode is the config name for the write mode, append or upsert
+	KeyWriteMode = "writeMode"
+
+	// KeyKeyColumn is the config name for the column holding the row keys in upsert mode
+	KeyKeyColumn = "keyColumn"
+
+	// KeyDeleteMode is the config name for how the deleted rows are handled in upsert mode, clear or remove
+	KeyDeleteMode = "deleteMode"
+
+	// KeyRouteByMetadata is the config name for routing the records by the spreadsheet and sheet metadata
+	KeyRouteByMetadata = "routeByMetadata"
+
+	// KeySheetNameTemplate is the config name for the Go template rendering the sheet name of each record
+	KeySheetNameTemplate = "sheetNameTemplate"
+
+	// KeySpreadsheetIDTemplate is the config name for the Go template rendering the spreadsheet ID of each record
+	KeySpreadsheetIDTemplate = "spreadsheetIdTemplate"
+
+	// KeySchema is the config name for the declared schema the records are validated against
+	KeySchema = "schema"
+
+	// KeyCreateSheet is the config name for creating the sheet, if it's not found in the spreadsheet
+	KeyCreateSheet = "createSheet"
+
+	// KeyFrozenRows is the config name for the number of rows frozen at the top of the sheet created
+	KeyFrozenRows = "frozenRows"
+
+	// KeyColumnWidths is the config name for the comma separated widths in pixels of the columns of the sheet created
+	KeyColumnWidths = "columnWidths"
+
+	// KeyGridRows is the config name for the number of rows of the grid of the sheet created
+	KeyGridRows = "gridRows"
+
+	// KeyGridColumns is the config name for the number of columns of the grid of the sheet created
+	KeyGridColumns = "gridColumns"
+
+	// KeyHeaders is the config name for the comma separated column names written to the empty header row
+	KeyHeaders = "headers"
+
+	// KeyRolloverCells is the config name for the number of cells the destination rolls over to a new sheet or spreadsheet at
+	KeyRolloverCells = "rolloverCells"
+
+	// KeyRolloverTo is the config name for what the destination rolls over to, sheet or spreadsheet
+	KeyRolloverTo = "rolloverTo"
+
+	// KeyStateFile is the config name for the path of the file storing the sheets and spreadsheets rolled over to
+	KeyStateFile = "stateFile"
+
 	// defaultValueInputOption is the value ValueInputOption assumes when the config omits
 	// the ValueInputOption parameter
 	defaultValueInputOption = "USER_ENTERED"
@@ -55,7 +123,44 @@ type Config struct {
 	// In RAW, the data is inserted without any parsing
 	ValueInputOption string
 	BufferSize       uint64
-	MaxRetries       uint64
+	// FlushInterval is the interval the partially filled buffers are written at, 0 if they are written once full only
+	FlushInterval time.Duration
+	// MaxBufferBytes is the maximum size in bytes of the payloads of a buffer, the buffer is written before it
+	// exceeds it. 0 if the buffers are written once they hold BufferSize records only.
+	MaxBufferBytes uint64
+	MaxRetries     uint64
+	// HeaderRow is the row number holding the column names, the fields of the structured and JSON object
+	// payloads are written to the matching columns. 0 if the sheet has no header row.
+	HeaderRow int64
+	// AppendHeaders enables appending the fields missing in the header row as new columns
+	AppendHeaders bool
+	// WriteMode is append to append the records, upsert to update the rows with the record keys, overwrite to rewrite
+	// the sheet at every snapshot, or replace-on-snapshot to replace the sheet with every snapshot once completed
+	WriteMode string
+	// KeyColumn is the header name, or the column letters without a header row, of the column holding the row keys
+	KeyColumn string
+	// DeleteMode is clear to clear the deleted rows, or remove to remove them from the sheet
+	DeleteMode string
+	// RouteByMetadata enables writing the records to the spreadsheet and the sheet of their
+	// google.sheets.spreadsheetId and google.sheets.sheetTitle metadata, if set
+	RouteByMetadata bool
+	// SheetNameTemplate and SpreadsheetIDTemplate render the sheet name and the spreadsheet ID each record is written to,
+	// the configured sheet and spreadsheet are used if not set or if the template renders an empty string
+	SheetNameTemplate     *template.Template
+	SpreadsheetIDTemplate *template.Template
+	// Schema is the declared schema the records are validated against before they are written, nil if not set
+	Schema sheets.Schema
+	// Format is the format of the record payloads, the format of every payload is detected if not set
+	Format sheets.Format
+	// CreateSheet enables creating the sheet with the Layout, if it's not found in the spreadsheet
+	CreateSheet bool
+	// Layout is the layout of the sheet created, the rows up to the header row are frozen by default
+	Layout sheets.SheetLayout
+	// Headers are the column names written to the header row if it is empty, the header row is written
+	// from the fields of the first record if not set
+	Headers []string
+	// Rollover configures rolling over to a new sheet or spreadsheet in append mode, disabled if Rollover.Cells is 0
+	Rollover sheets.Rollover
 }
 
 // Parse attempts to parse the configurations into a Config struct that Destination could utilize
@@ -94,6 +199,22 @@ func Parse(cfg map[string]string) (Config, error) {
 		)
 	}
 
+	var flushInterval time.Duration
+	if val := strings.TrimSpace(cfg[KeyFlushInterval]); val != "" {
+		flushInterval, err = time.ParseDuration(val)
+		if err != nil || flushInterval <= 0 {
+			return Config{}, fmt.Errorf("%q config value must be a positive duration, got: %q", KeyFlushInterval, val)
+		}
+	}
+
+	var maxBufferBytes uint64
+	if val := strings.TrimSpace(cfg[KeyMaxBufferBytes]); val != "" {
+		maxBufferBytes, err = strconv.ParseUint(val, 10, 64)
+		if err != nil || maxBufferBytes == 0 {
+			return Config{}, fmt.Errorf("%q config value must be a positive integer, got: %q", KeyMaxBufferBytes, val)
+		}
+	}
+
 	retriesString := cfg[KeyMaxRetries]
 	if retriesString == "" {
 		retriesString = defaultMaxRetries
@@ -107,17 +228,256 @@ func Parse(cfg map[string]string) (Config, error) {
 		)
 	}
 
+	var headerRow int64
+	if val := strings.TrimSpace(cfg[KeyHeaderRow]); val != "" {
+		headerRow, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || headerRow < 0 {
+			return Config{}, fmt.Errorf("%q config value must be a non-negative integer, got: %q", KeyHeaderRow, val)
+		}
+	}
+
+	var appendHeaders bool
+	if val := strings.TrimSpace(cfg[KeyAppendHeaders]); val != "" {
+		appendHeaders, err = strconv.ParseBool(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a boolean, got: %q", KeyAppendHeaders, val)
+		}
+	}
+	if appendHeaders && headerRow == 0 {
+		return Config{}, fmt.Errorf("%q config value must be set, if %q is enabled", KeyHeaderRow, KeyAppendHeaders)
+	}
+
+	writeMode, keyColumn, deleteMode, err := parseUpsertConfig(cfg, headerRow)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var routeByMetadata bool
+	if val := strings.TrimSpace(cfg[KeyRouteByMetadata]); val != "" {
+		routeByMetadata, err = strconv.ParseBool(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a boolean, got: %q", KeyRouteByMetadata, val)
+		}
+	}
+
+	sheetNameTemplate, spreadsheetIDTemplate, err := parseRouteConfig(cfg, routeByMetadata)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var schema sheets.Schema
+	if val := strings.TrimSpace(cfg[KeySchema]); val != "" {
+		schema, err = sheets.ParseSchema(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a JSON array of the schema columns: %w", KeySchema, err)
+		}
+	}
+
+	format, err := sheets.ParseFormat(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// the sheets routed to by the template are created by default, as they can't be known in advance
+	createSheet, layout, err := parseSheetConfig(cfg, headerRow, sheetNameTemplate != nil)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var headers []string
+	if val := strings.TrimSpace(cfg[KeyHeaders]); val != "" {
+		if headerRow == 0 {
+			return Config{}, fmt.Errorf("%q config value must be set, if %q is set", KeyHeaderRow, KeyHeaders)
+		}
+		headers = config.SplitList(val)
+	}
+
+	rollover, err := parseRolloverConfig(cfg, writeMode)
+	if err != nil {
+		return Config{}, err
+	}
+
 	destinationConfig := Config{
-		Config:           sharedConfig,
-		SheetName:        sheetName,
-		ValueInputOption: sheetValueOption,
-		BufferSize:       bufferSize,
-		MaxRetries:       retries,
+		Config:                sharedConfig,
+		SheetName:             sheetName,
+		ValueInputOption:      sheetValueOption,
+		BufferSize:            bufferSize,
+		FlushInterval:         flushInterval,
+		MaxBufferBytes:        maxBufferBytes,
+		MaxRetries:            retries,
+		HeaderRow:             headerRow,
+		AppendHeaders:         appendHeaders,
+		WriteMode:             writeMode,
+		KeyColumn:             keyColumn,
+		DeleteMode:            deleteMode,
+		RouteByMetadata:       routeByMetadata,
+		Schema:                schema,
+		SheetNameTemplate:     sheetNameTemplate,
+		SpreadsheetIDTemplate: spreadsheetIDTemplate,
+		Format:                format,
+		CreateSheet:           createSheet,
+		Layout:                layout,
+		Headers:               headers,
+		Rollover:              rollover,
 	}
 
 	return destinationConfig, nil
 }
 
+// parseRouteConfig parses the templates routing the records to their sheet and spreadsheet,
+// which can't be set if routing by metadata is enabled
+func parseRouteConfig(cfg map[string]string, routeByMetadata bool) (*template.Template, *template.Template, error) {
+	templates := make([]*template.Template, 2)
+	for i, key := range []string{KeySheetNameTemplate, KeySpreadsheetIDTemplate} {
+		val := strings.TrimSpace(cfg[key])
+		if val == "" {
+			continue
+		}
+		if routeByMetadata {
+			return nil, nil, fmt.Errorf("%q config value can't be set, if %q is enabled", key, KeyRouteByMetadata)
+		}
+		tmpl, err := newRouteTemplate(key, val)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%q config value must be a Go template: %w", key, err)
+		}
+		templates[i] = tmpl
+	}
+	return templates[0], templates[1], nil
+}
+
+// parseRolloverConfig parses the number of cells the writer rolls over at in append mode, what it rolls over to,
+// and the state file recording the sheets and spreadsheets rolled over to
+func parseRolloverConfig(cfg map[string]string, writeMode string) (sheets.Rollover, error) {
+	val := strings.TrimSpace(cfg[KeyRolloverCells])
+	rolloverTo := strings.TrimSpace(cfg[KeyRolloverTo])
+	stateFile := strings.TrimSpace(cfg[KeyStateFile])
+	if val == "" {
+		if rolloverTo != "" || stateFile != "" {
+			return sheets.Rollover{}, fmt.Errorf("%q and %q config values can only be set, if %q is set", KeyRolloverTo, KeyStateFile, KeyRolloverCells)
+		}
+		return sheets.Rollover{}, nil
+	}
+
+	cells, err := strconv.ParseInt(val, 10, 64)
+	if err != nil || cells <= 0 || cells > sheets.MaxSpreadsheetCells {
+		return sheets.Rollover{}, fmt.Errorf("%q config value must be a positive integer up to %d, got: %q",
+			KeyRolloverCells, sheets.MaxSpreadsheetCells, val)
+	}
+	if writeMode != sheets.WriteModeAppend {
+		return sheets.Rollover{}, fmt.Errorf("%q config value can only be set, if %q is %q", KeyRolloverCells, KeyWriteMode, sheets.WriteModeAppend)
+	}
+	switch rolloverTo {
+	case "":
+		rolloverTo = sheets.RolloverToSheet
+	case sheets.RolloverToSheet, sheets.RolloverToSpreadsheet:
+	default:
+		return sheets.Rollover{}, fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `sheet`, `spreadsheet`",
+			rolloverTo, KeyRolloverTo,
+		)
+	}
+	if stateFile == "" {
+		return sheets.Rollover{}, fmt.Errorf("%q config value must be set, if %q is set", KeyStateFile, KeyRolloverCells)
+	}
+	return sheets.Rollover{Cells: cells, To: rolloverTo, State: sheets.RolloverStateFile{Path: stateFile}}, nil
+}
+
+// parseSheetConfig parses whether the sheet is created if it's not found, createSheet being the default,
+// and the layout of the sheet created, which can only be set if creating the sheet is enabled
+func parseSheetConfig(cfg map[string]string, headerRow int64, createSheet bool) (bool, sheets.SheetLayout, error) {
+	var err error
+	if val := strings.TrimSpace(cfg[KeyCreateSheet]); val != "" {
+		createSheet, err = strconv.ParseBool(val)
+		if err != nil {
+			return false, sheets.SheetLayout{}, fmt.Errorf("%q config value must be a boolean, got: %q", KeyCreateSheet, val)
+		}
+	}
+	layoutKeys := []string{KeyFrozenRows, KeyColumnWidths, KeyGridRows, KeyGridColumns}
+	if !createSheet {
+		for _, key := range layoutKeys {
+			if strings.TrimSpace(cfg[key]) != "" {
+				return false, sheets.SheetLayout{}, fmt.Errorf("%q config value can only be set, if %q is enabled", key, KeyCreateSheet)
+			}
+		}
+		return false, sheets.SheetLayout{}, nil
+	}
+
+	layout := sheets.SheetLayout{FrozenRows: headerRow}
+	if val := strings.TrimSpace(cfg[KeyFrozenRows]); val != "" {
+		layout.FrozenRows, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || layout.FrozenRows < 0 {
+			return false, sheets.SheetLayout{}, fmt.Errorf("%q config value must be a non-negative integer, got: %q", KeyFrozenRows, val)
+		}
+	}
+	for _, size := range []struct {
+		key   string
+		value *int64
+	}{{KeyGridRows, &layout.RowCount}, {KeyGridColumns, &layout.ColumnCount}} {
+		val := strings.TrimSpace(cfg[size.key])
+		if val == "" {
+			continue
+		}
+		*size.value, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || *size.value <= 0 {
+			return false, sheets.SheetLayout{}, fmt.Errorf("%q config value must be a positive integer, got: %q", size.key, val)
+		}
+	}
+	if val := strings.TrimSpace(cfg[KeyColumnWidths]); val != "" {
+		for _, width := range config.SplitList(val) {
+			pixels, err := strconv.ParseInt(width, 10, 64)
+			if err != nil || pixels <= 0 {
+				return false, sheets.SheetLayout{}, fmt.Errorf("%q config value must be comma separated positive integers, got: %q", KeyColumnWidths, val)
+			}
+			layout.ColumnWidths = append(layout.ColumnWidths, pixels)
+		}
+	}
+	return true, layout, nil
+}
+
+// parseUpsertConfig parses the write mode, the key column and the delete mode, which are valid in upsert mode only
+func parseUpsertConfig(cfg map[string]string, headerRow int64) (string, string, string, error) {
+	writeMode := strings.TrimSpace(cfg[KeyWriteMode])
+	if writeMode == "" {
+		writeMode = sheets.WriteModeAppend
+	}
+	switch writeMode {
+	case sheets.WriteModeAppend, sheets.WriteModeUpsert, sheets.WriteModeOverwrite, sheets.WriteModeReplaceOnSnapshot:
+	default:
+		return "", "", "", fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `%s`, `%s`, `%s`, `%s`",
+			writeMode, KeyWriteMode, sheets.WriteModeAppend, sheets.WriteModeUpsert,
+			sheets.WriteModeOverwrite, sheets.WriteModeReplaceOnSnapshot,
+		)
+	}
+
+	keyColumn := strings.TrimSpace(cfg[KeyKeyColumn])
+	deleteMode := strings.TrimSpace(cfg[KeyDeleteMode])
+	if writeMode != sheets.WriteModeUpsert {
+		if keyColumn != "" || deleteMode != "" {
+			return "", "", "", fmt.Errorf("%q and %q config values can only be set, if %q is %q",
+				KeyKeyColumn, KeyDeleteMode, KeyWriteMode, sheets.WriteModeUpsert)
+		}
+		return writeMode, "", "", nil
+	}
+
+	if keyColumn == "" {
+		return "", "", "", fmt.Errorf("%q config value must be set, if %q is %q", KeyKeyColumn, KeyWriteMode, sheets.WriteModeUpsert)
+	}
+	if headerRow == 0 && !columnLettersRegexp.MatchString(keyColumn) {
+		return "", "", "", fmt.Errorf("%q config value must be the column letters, if %q is not set, got: %q", KeyKeyColumn, KeyHeaderRow, keyColumn)
+	}
+	if deleteMode == "" {
+		deleteMode = sheets.DeleteModeClear
+	}
+	if deleteMode != sheets.DeleteModeClear && deleteMode != sheets.DeleteModeRemove {
+		return "", "", "", fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `%s`, `%s`",
+			deleteMode, KeyDeleteMode, sheets.DeleteModeClear, sheets.DeleteModeRemove,
+		)
+	}
+	return writeMode, keyColumn, deleteMode, nil
+}
+
 func requiredConfigErr(name string) error {
 	return fmt.Errorf("%q config value must be set", name)
 }
@@ -20,8 +20,11 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
 
 	"github.com/conduitio/conduit-connector-google-sheets/config"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,7 +43,7 @@ func TestParse(t *testing.T) {
 		{
 			testCase: "Checking against default values",
 			params:   map[string]string{},
-			err:      fmt.Errorf("error parsing shared config, \"credentialsFile\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, one of \"credentialsFile\", \"credentialsJSON\", \"credentialsJSONBase64\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -53,7 +56,7 @@ func TestParse(t *testing.T) {
 				KeyValueInputOption:       "",
 				KeyBufferSize:             "",
 			},
-			err:      fmt.Errorf("error parsing shared config, \"sheetsURL\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, one of \"sheetsURL\", \"spreadsheetId\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -82,13 +85,17 @@ func TestParse(t *testing.T) {
 			err: nil,
 			expected: Config{
 				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
 					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 					GoogleSheetID:       158080911,
+					HasSheetID:          true,
 				},
 				SheetName:        "Sheet",
 				ValueInputOption: defaultValueInputOption,
 				BufferSize:       100,
 				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
 			},
 		},
 		{
@@ -104,13 +111,17 @@ func TestParse(t *testing.T) {
 			err: nil,
 			expected: Config{
 				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
 					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 					GoogleSheetID:       158080911,
+					HasSheetID:          true,
 				},
 				SheetName:        "Sheet",
 				ValueInputOption: "RAW",
 				BufferSize:       100,
 				MaxRetries:       5,
+				WriteMode:        sheets.WriteModeAppend,
 			},
 		},
 		{
@@ -126,15 +137,491 @@ func TestParse(t *testing.T) {
 			err: nil,
 			expected: Config{
 				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
 					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 					GoogleSheetID:       158080911,
+					HasSheetID:          true,
 				},
 				SheetName:        "Sheet",
 				ValueInputOption: defaultValueInputOption,
 				BufferSize:       10,
 				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
 			},
 		},
+		{
+			testCase: "Checking for header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyHeaderRow:              "1",
+				KeyAppendHeaders:          "true",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
+				HeaderRow:        1,
+				AppendHeaders:    true,
+			},
+		},
+		{
+			testCase: "Checking for append headers without header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyAppendHeaders:          "true",
+			},
+			err:      fmt.Errorf("\"headerRow\" config value must be set, if \"appendHeaders\" is enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for routing by metadata",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRouteByMetadata:        "true",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
+				RouteByMetadata:  true,
+			},
+		},
+		{
+			testCase: "Checking for schema",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeySchema:                 `[{"name":"id","type":"integer"},{"name":"ordered","type":"date"}]`,
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
+				Schema:           sheets.Schema{{Name: "id", Type: sheets.ColumnInteger}, {Name: "ordered", Type: sheets.ColumnDate}},
+			},
+		},
+		{
+			testCase: "Checking for invalid schema",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeySchema:                 `[{"name":"id","type":"uuid"}]`,
+			},
+			err:      fmt.Errorf("\"schema\" config value must be a JSON array of the schema columns: schema column \"id\" has an invalid type \"uuid\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for upsert mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyHeaderRow:              "1",
+				KeyWriteMode:              "upsert",
+				KeyKeyColumn:              "order_id",
+				KeyDeleteMode:             "remove",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				HeaderRow:        1,
+				WriteMode:        sheets.WriteModeUpsert,
+				KeyColumn:        "order_id",
+				DeleteMode:       sheets.DeleteModeRemove,
+			},
+		},
+		{
+			testCase: "Checking for upsert mode without key column",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyWriteMode:              "upsert",
+			},
+			err:      fmt.Errorf("\"keyColumn\" config value must be set, if \"writeMode\" is \"upsert\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for upsert mode with key column name without header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyWriteMode:              "upsert",
+				KeyKeyColumn:              "order_id",
+			},
+			err:      fmt.Errorf("\"keyColumn\" config value must be the column letters, if \"headerRow\" is not set, got: \"order_id\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for replace-on-snapshot mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyWriteMode:              "replace-on-snapshot",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeReplaceOnSnapshot,
+			},
+		},
+		{
+			testCase: "Checking for invalid write mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyWriteMode:              "truncate",
+			},
+			err:      fmt.Errorf("invalid value (truncate) for `writeMode` config received, valid values: `append`, `upsert`, `overwrite`, `replace-on-snapshot`"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for sheet name template",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyHeaderRow:              "1",
+				KeySheetNameTemplate:      `orders_{{ date .CreatedAt "2006_01" }}`,
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:         "Sheet",
+				ValueInputOption:  defaultValueInputOption,
+				BufferSize:        100,
+				MaxRetries:        3,
+				HeaderRow:         1,
+				WriteMode:         sheets.WriteModeAppend,
+				SheetNameTemplate: &template.Template{},
+				CreateSheet:       true,
+				Layout:            sheets.SheetLayout{FrozenRows: 1},
+			},
+		},
+		{
+			testCase: "Checking for invalid sheet name template",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeySheetNameTemplate:      "{{ .Metadata.table",
+			},
+			err:      fmt.Errorf("\"sheetNameTemplate\" config value must be a Go template: template: sheetNameTemplate:1: unclosed action"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for spreadsheet ID template with routing by metadata",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRouteByMetadata:        "true",
+				KeySpreadsheetIDTemplate:  "{{ .Metadata.spreadsheet }}",
+			},
+			err:      fmt.Errorf("\"spreadsheetIdTemplate\" config value can't be set, if \"routeByMetadata\" is enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for rollover",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRolloverCells:          "9000000",
+				KeyStateFile:              "/tmp/rollover.json",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
+				Rollover: sheets.Rollover{
+					Cells: 9000000,
+					To:    sheets.RolloverToSheet,
+					State: sheets.RolloverStateFile{Path: "/tmp/rollover.json"},
+				},
+			},
+		},
+		{
+			testCase: "Checking for rollover above the cell limit",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRolloverCells:          "20000000",
+				KeyStateFile:              "/tmp/rollover.json",
+			},
+			err:      fmt.Errorf("\"rolloverCells\" config value must be a positive integer up to 10000000, got: \"20000000\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for rollover in upsert mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyWriteMode:              "upsert",
+				KeyKeyColumn:              "A",
+				KeyRolloverCells:          "9000000",
+				KeyStateFile:              "/tmp/rollover.json",
+			},
+			err:      fmt.Errorf("\"rolloverCells\" config value can only be set, if \"writeMode\" is \"append\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for invalid rollover target",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRolloverCells:          "9000000",
+				KeyRolloverTo:             "drive",
+				KeyStateFile:              "/tmp/rollover.json",
+			},
+			err:      fmt.Errorf("invalid value (drive) for `rolloverTo` config received, valid values: `sheet`, `spreadsheet`"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for rollover without state file",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRolloverCells:          "9000000",
+				KeyRolloverTo:             "spreadsheet",
+			},
+			err:      fmt.Errorf("\"stateFile\" config value must be set, if \"rolloverCells\" is set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for rollover target without rollover",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyRolloverTo:             "spreadsheet",
+			},
+			err:      fmt.Errorf("\"rolloverTo\" and \"stateFile\" config values can only be set, if \"rolloverCells\" is set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for flush interval and max buffer bytes",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyFlushInterval:          "5s",
+				KeyMaxBufferBytes:         "2000000",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				FlushInterval:    5 * time.Second,
+				MaxBufferBytes:   2000000,
+				MaxRetries:       3,
+				WriteMode:        sheets.WriteModeAppend,
+			},
+		},
+		{
+			testCase: "Checking for invalid flush interval",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyFlushInterval:          "5",
+			},
+			err:      fmt.Errorf("\"flushInterval\" config value must be a positive duration, got: \"5\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for invalid max buffer bytes",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyMaxBufferBytes:         "0",
+			},
+			err:      fmt.Errorf("\"maxBufferBytes\" config value must be a positive integer, got: \"0\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for sheet creation",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyHeaderRow:              "1",
+				KeyCreateSheet:            "true",
+				KeyColumnWidths:           "120, 200",
+				KeyGridColumns:            "10",
+				KeyHeaders:                "id, name",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+					GoogleSheetID:       158080911,
+					HasSheetID:          true,
+				},
+				SheetName:        "Sheet",
+				ValueInputOption: defaultValueInputOption,
+				BufferSize:       100,
+				MaxRetries:       3,
+				HeaderRow:        1,
+				WriteMode:        sheets.WriteModeAppend,
+				CreateSheet:      true,
+				Layout:           sheets.SheetLayout{FrozenRows: 1, ColumnWidths: []int64{120, 200}, ColumnCount: 10},
+				Headers:          []string{"id", "name"},
+			},
+		},
+		{
+			testCase: "Checking for sheet layout without sheet creation",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyFrozenRows:             "1",
+			},
+			err:      fmt.Errorf("\"frozenRows\" config value can only be set, if \"createSheet\" is enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for invalid column widths",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyCreateSheet:            "true",
+				KeyColumnWidths:           "120,wide",
+			},
+			err:      fmt.Errorf("\"columnWidths\" config value must be comma separated positive integers, got: \"120,wide\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for headers without header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Sheet",
+				KeyHeaders:                "id,name",
+			},
+			err:      fmt.Errorf("\"headerRow\" config value must be set, if \"headers\" is set"),
+			expected: Config{},
+		},
 	}
 
 	for _, tc := range cases {
@@ -146,6 +633,9 @@ func TestParse(t *testing.T) {
 				assert.NoError(t, err)
 				tc.expected.OAuthConfig = cfg.OAuthConfig
 				tc.expected.OAuthToken = cfg.OAuthToken
+				// the parsed templates are checked by the router tests
+				assert.Equal(t, tc.expected.SheetNameTemplate != nil, cfg.SheetNameTemplate != nil)
+				tc.expected.SheetNameTemplate = cfg.SheetNameTemplate
 				assert.EqualValues(t, tc.expected, cfg)
 			}
 		})
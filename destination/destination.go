@@ -23,24 +23,45 @@ import (
 
 	"github.com/conduitio/conduit-connector-google-sheets/sheets"
 	sdk "github.com/conduitio/conduit-connector-sdk"
+	"golang.org/x/oauth2"
 )
 
 // Destination connector
 type Destination struct {
 	sdk.UnimplementedDestination
-	// buffer holds the records for asynchronous write to google sheets
-	buffer []sdk.Record
-	// ackCache holds the ack functions for the records currently buffered
-	// i-th index of ackCache is the ack function of record buffered at i-th index
-	ackCache []sdk.AckFunc
+	// buffers hold the records of each target for asynchronous write to google sheets
+	buffers map[target]*buffer
+	// targets are the targets of the buffers, in the order of their first record. At most maxTargets are kept.
+	targets []target
+	// used counts the records buffered, the buffer of a target is marked with the count of its last record
+	used uint64
 	// err holds the last error encountered by the connector
 	err error
 	// config holds the destination config
 	config Config
-	// writer is the instance of sheets writer, which is a wrapper over sheets write API
-	writer *sheets.Writer
+	// writers are the instances of sheets writer, which is a wrapper over sheets write API, keyed by target.
+	// The writer of the configured sheet is created on Open, the writers of the other targets on their first record.
+	writers map[target]*sheets.Writer
+	// tokenSource provides the tokens used by the writers
+	tokenSource oauth2.TokenSource
+	// rolloverState holds the sheets and spreadsheets rolled over to by all the writers, nil if rolling over is disabled
+	rolloverState *sheets.RolloverState
 
 	mux *sync.Mutex
+	// stopTimer stops the timer flushing the buffers every flush interval, timerDone is closed once it's stopped
+	stopTimer context.CancelFunc
+	timerDone chan struct{}
+}
+
+// buffer holds the records of a target along with their ack functions,
+// i-th index of acks is the ack function of record buffered at i-th index
+type buffer struct {
+	records []sdk.Record
+	acks    []sdk.AckFunc
+	// bytes is the size of the payloads of the records
+	bytes uint64
+	// used is the count of the last record buffered, the buffer used least recently is evicted first
+	used uint64
 }
 
 func NewDestination() sdk.Destination {
@@ -56,10 +77,26 @@ func (d *Destination) Configure(ctx context.Context,
 	}
 
 	d.config = Config{
-		Config:           sheetsConfig.Config,
-		SheetName:        sheetsConfig.SheetName,
-		BufferSize:       sheetsConfig.BufferSize,
-		ValueInputOption: sheetsConfig.ValueInputOption,
+		Config:                sheetsConfig.Config,
+		SheetName:             sheetsConfig.SheetName,
+		BufferSize:            sheetsConfig.BufferSize,
+		FlushInterval:         sheetsConfig.FlushInterval,
+		MaxBufferBytes:        sheetsConfig.MaxBufferBytes,
+		ValueInputOption:      sheetsConfig.ValueInputOption,
+		HeaderRow:             sheetsConfig.HeaderRow,
+		AppendHeaders:         sheetsConfig.AppendHeaders,
+		WriteMode:             sheetsConfig.WriteMode,
+		KeyColumn:             sheetsConfig.KeyColumn,
+		DeleteMode:            sheetsConfig.DeleteMode,
+		RouteByMetadata:       sheetsConfig.RouteByMetadata,
+		SheetNameTemplate:     sheetsConfig.SheetNameTemplate,
+		SpreadsheetIDTemplate: sheetsConfig.SpreadsheetIDTemplate,
+		Schema:                sheetsConfig.Schema,
+		Format:                sheetsConfig.Format,
+		CreateSheet:           sheetsConfig.CreateSheet,
+		Layout:                sheetsConfig.Layout,
+		Headers:               sheetsConfig.Headers,
+		Rollover:              sheetsConfig.Rollover,
 	}
 	d.mux = &sync.Mutex{}
 	return nil
@@ -67,23 +104,21 @@ func (d *Destination) Configure(ctx context.Context,
 
 // Open makes sure everything is prepared to receive records.
 func (d *Destination) Open(ctx context.Context) error {
-	// initializing the buffer
-	d.buffer = make([]sdk.Record, 0, d.config.BufferSize)
-	d.ackCache = make([]sdk.AckFunc, 0, d.config.BufferSize)
-
-	writer, err := sheets.NewWriter(
-		ctx,
-		d.config.OAuthConfig,
-		d.config.OAuthToken,
-		d.config.GoogleSpreadsheetID,
-		d.config.SheetName,
-		d.config.ValueInputOption,
-		d.config.MaxRetries,
-	)
-	if err != nil {
+	// initializing the buffers, the buffer of a target is created on its first record
+	d.buffers = make(map[target]*buffer)
+	d.targets = nil
+
+	d.tokenSource = d.config.TokenSource(ctx)
+	d.writers = make(map[target]*sheets.Writer)
+	if d.config.Rollover.Cells > 0 {
+		d.rolloverState = sheets.NewRolloverState(d.config.Rollover.State)
+	}
+	if _, err := d.writer(ctx, d.defaultTarget()); err != nil {
 		return fmt.Errorf("unable to init writer: %w", err)
 	}
-	d.writer = writer
+	if d.config.FlushInterval > 0 {
+		d.startFlushTimer(ctx)
+	}
 	return nil
 }
 
@@ -92,6 +127,10 @@ func (d *Destination) Open(ctx context.Context) error {
 // records in it. This is done for performance reasons.
 func (d *Destination) WriteAsync(ctx context.Context,
 	r sdk.Record, ack sdk.AckFunc) error {
+	// the lock is held from the start, as the error is set by the flush timer as well
+	d.mux.Lock()
+	defer d.mux.Unlock()
+
 	// If either Destination or Writer have encountered an error, there's no point in
 	// accepting more records. We better signal the error up the stack and force
 	// the server to maybe re-instantiate plugin or do something else about it.
@@ -99,18 +138,57 @@ func (d *Destination) WriteAsync(ctx context.Context,
 		return d.err
 	}
 
-	if len(r.Payload.Bytes()) == 0 {
+	// records without payload are skipped, except the deletes in upsert mode, which are found by the record key
+	if (r.Payload == nil || len(r.Payload.Bytes()) == 0) &&
+		(d.config.WriteMode != sheets.WriteModeUpsert || !sheets.IsDeleteRecord(r)) {
 		return nil
 	}
 
-	d.mux.Lock()
-	defer d.mux.Unlock()
+	// the records not matching the declared schema are rejected before they are buffered
+	if d.config.Schema != nil && !sheets.IsDeleteRecord(r) {
+		if err := d.config.Schema.Validate(d.config.Format, r); err != nil {
+			return fmt.Errorf("record does not match the schema: %w", err)
+		}
+	}
+
+	t, err := d.recordTarget(r)
+	if err != nil {
+		return fmt.Errorf("unable to route the record: %w", err)
+	}
 
-	d.buffer = append(d.buffer, r)
-	d.ackCache = append(d.ackCache, ack)
+	b, ok := d.buffers[t]
+	if !ok {
+		b = &buffer{
+			records: make([]sdk.Record, 0, d.config.BufferSize),
+			acks:    make([]sdk.AckFunc, 0, d.config.BufferSize),
+		}
+		d.buffers[t] = b
+		d.targets = append(d.targets, t)
+		if len(d.targets) > maxTargets {
+			if err := d.evictTarget(ctx, t); err != nil {
+				return fmt.Errorf("failed flushing the records: %w", err)
+			}
+		}
+	}
+	d.used++
+	b.used = d.used
+	// the buffer is written before the record would make it exceed the max bytes
+	var size uint64
+	if r.Payload != nil {
+		size = uint64(len(r.Payload.Bytes()))
+	}
+	if d.config.MaxBufferBytes > 0 && len(b.records) > 0 && b.bytes+size > d.config.MaxBufferBytes {
+		err := d.flushTarget(ctx, t)
+		if err != nil {
+			return fmt.Errorf("failed flushing the records: %w", err)
+		}
+	}
+	b.records = append(b.records, r)
+	b.acks = append(b.acks, ack)
+	b.bytes += size
 
-	if len(d.buffer) >= int(d.config.BufferSize) {
-		err := d.Flush(ctx)
+	if len(b.records) >= int(d.config.BufferSize) {
+		err := d.flushTarget(ctx, t)
 		if err != nil {
 			return fmt.Errorf("failed flushing the records: %w", err)
 		}
@@ -119,25 +197,44 @@ func (d *Destination) WriteAsync(ctx context.Context,
 	return d.err
 }
 
-// Flush writes the records when the buffer threshold is hit and after successful pushing the data
-// empties the record buffer and acknowledgment buffer for new records.
+// Flush writes the buffered records of every target, in the order of the first record of each target.
 func (d *Destination) Flush(ctx context.Context) error {
-	bufferedRecords := d.buffer
-	d.buffer = d.buffer[:0]
+	for _, t := range d.targets {
+		if err := d.flushTarget(ctx, t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	err := d.writer.Write(ctx, bufferedRecords)
-	if err != nil {
-		d.err = err
+// flushTarget writes the records of the target when its buffer threshold is hit and after successful pushing the data
+// empties the record buffer and acknowledgment buffer of the target for new records.
+// The records are not written once an error is encountered, they are acknowledged with the error.
+func (d *Destination) flushTarget(ctx context.Context, t target) error {
+	b := d.buffers[t]
+	if b == nil || len(b.records) == 0 {
+		return nil
+	}
+	bufferedRecords, acks := b.records, b.acks
+	b.records, b.acks, b.bytes = b.records[:0], b.acks[:0], 0
+
+	if d.err == nil {
+		writer, err := d.writer(ctx, t)
+		if err == nil {
+			err = writer.Write(ctx, bufferedRecords)
+		}
+		if err != nil {
+			d.err = err
+		}
 	}
 
 	// call all the written records ackFunctions
-	for _, ack := range d.ackCache {
+	for _, ack := range acks {
 		err := ack(d.err)
 		if err != nil {
 			return fmt.Errorf("failed acknowledgement: %w", err)
 		}
 	}
-	d.ackCache = d.ackCache[:0]
 
 	return nil
 }
@@ -145,8 +242,10 @@ func (d *Destination) Flush(ctx context.Context) error {
 // Teardown writes all the pending records to sheets and gracefully disconnects the client
 func (d *Destination) Teardown(ctx context.Context) error {
 	defer func() {
-		d.writer = nil
+		d.writers = nil
 	}()
+	// the timer is stopped before the buffers are flushed, it would wait for the lock otherwise
+	d.stopFlushTimer()
 	if d.mux != nil {
 		d.mux.Lock()
 		defer d.mux.Unlock()
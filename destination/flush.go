@@ -0,0 +1,78 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"context"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// startFlushTimer writes the partially filled buffers every flush interval, until the timer is stopped
+// or the context is canceled. The buffers are flushed holding the lock, so a flush never runs
+// concurrently with WriteAsync or Teardown. Stopping the timer doesn't cancel the flush in progress.
+func (d *Destination) startFlushTimer(ctx context.Context) {
+	timerCtx, stopTimer := context.WithCancel(ctx)
+	d.stopTimer = stopTimer
+	d.timerDone = make(chan struct{})
+	go func() {
+		defer close(d.timerDone)
+		ticker := time.NewTicker(d.config.FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-timerCtx.Done():
+				return
+			case <-ticker.C:
+				d.flushOnTimer(ctx, timerCtx.Done())
+			}
+		}
+	}()
+}
+
+// flushOnTimer writes the buffered records of every target, the errors are returned by the next WriteAsync call
+func (d *Destination) flushOnTimer(ctx context.Context, stopped <-chan struct{}) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	select {
+	case <-stopped:
+		// the timer was stopped while waiting for the lock, the buffers are flushed by Teardown
+		return
+	default:
+	}
+	failed := d.err != nil
+	if err := d.Flush(ctx); err != nil && d.err == nil {
+		d.err = err
+	}
+	if d.err != nil && !failed {
+		sdk.Logger(ctx).Error().
+			Err(d.err).
+			Dur("flush_interval", d.config.FlushInterval).
+			Msg("failed flushing the records on timer")
+	}
+}
+
+// stopFlushTimer stops the timer and waits for the flush in progress, if any
+func (d *Destination) stopFlushTimer() {
+	if d.stopTimer == nil {
+		return
+	}
+	d.stopTimer()
+	<-d.timerDone
+	d.stopTimer = nil
+}
@@ -0,0 +1,254 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/config"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+)
+
+// fakeSheets serves the metadata of the spreadsheet and counts the rows appended to its sheet,
+// the appends fail once limit rows are appended if the limit is set
+type fakeSheets struct {
+	t     *testing.T
+	mux   sync.Mutex
+	rows  int
+	limit int
+}
+
+func (f *fakeSheets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy_spreadsheet":
+		_, _ = w.Write([]byte(`{"sheets": [{"properties": {"sheetId": 0, "title": "Sheet1"}}]}`))
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, ":append"):
+		var values struct {
+			Values [][]interface{} `json:"values"`
+		}
+		assert.NoError(f.t, json.NewDecoder(r.Body).Decode(&values))
+		f.mux.Lock()
+		defer f.mux.Unlock()
+		if f.limit > 0 && f.rows >= f.limit {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": {"code": 400, "message": "limit exceeded"}}`))
+			return
+		}
+		f.rows += len(values.Values)
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeSheets) appended() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.rows
+}
+
+// redirectTransport sends the requests to the test server instead of the Sheets API
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = rt.target.Scheme, rt.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// newFakeSheetsDestination returns the destination writing to the fake sheets, along with the context
+// the writers are to be created with, whose HTTP client sends the requests to the fake sheets
+func newFakeSheetsDestination(t *testing.T, fake *fakeSheets, cfg Config) (*Destination, context.Context) {
+	testServer := httptest.NewServer(fake)
+	t.Cleanup(testServer.Close)
+	serverURL, err := url.Parse(testServer.URL)
+	assert.NoError(t, err)
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: redirectTransport{target: serverURL}})
+
+	cfg.GoogleSpreadsheetID = "dummy_spreadsheet"
+	cfg.SheetName = "Sheet1"
+	return &Destination{
+		config:      cfg,
+		buffers:     make(map[target]*buffer),
+		writers:     make(map[target]*sheets.Writer),
+		tokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "dummy"}),
+		mux:         &sync.Mutex{},
+	}, ctx
+}
+
+func TestDestination_FlushTimer(t *testing.T) {
+	d := &Destination{
+		config: Config{
+			Config:        config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+			SheetName:     "Sheet1",
+			BufferSize:    100,
+			FlushInterval: 10 * time.Millisecond,
+		},
+		buffers: make(map[target]*buffer),
+		mux:     &sync.Mutex{},
+	}
+	acked := make(chan error, 2)
+	ack := func(err error) error {
+		acked <- err
+		return nil
+	}
+	for _, payload := range []string{`["1"]`, `["2"]`} {
+		err := d.WriteAsync(context.Background(), sdk.Record{Payload: sdk.RawData(payload)}, ack)
+		assert.NoError(t, err)
+	}
+	assert.Len(t, acked, 0)
+
+	// the records are acknowledged with the error encountered, without being written
+	d.err = fmt.Errorf("write error")
+	d.startFlushTimer(context.Background())
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-acked:
+			assert.Equal(t, d.err, err)
+		case <-time.After(time.Second):
+			t.Fatal("the records were not flushed by the timer")
+		}
+	}
+
+	assert.NoError(t, d.Teardown(context.Background()))
+	assert.Nil(t, d.stopTimer)
+}
+
+func TestDestination_WriteAsync_BufferBytes(t *testing.T) {
+	d := &Destination{
+		config: Config{
+			Config:         config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+			SheetName:      "Sheet1",
+			BufferSize:     100,
+			MaxBufferBytes: 10,
+		},
+		buffers: make(map[target]*buffer),
+		mux:     &sync.Mutex{},
+	}
+	ack := func(err error) error { return nil }
+
+	// the records fit in the max bytes, so they are not flushed
+	for _, payload := range []string{`["1"]`, `["2"]`} {
+		err := d.WriteAsync(context.Background(), sdk.Record{Payload: sdk.RawData(payload)}, ack)
+		assert.NoError(t, err)
+	}
+	b := d.buffers[d.defaultTarget()]
+	assert.Len(t, b.records, 2)
+	assert.Equal(t, uint64(10), b.bytes)
+
+	d.err = fmt.Errorf("write error")
+	assert.NoError(t, d.Flush(context.Background()))
+	assert.Empty(t, b.records)
+	assert.Equal(t, uint64(0), b.bytes)
+}
+
+func TestDestination_WriteAsync_Writer(t *testing.T) {
+	fake := &fakeSheets{t: t}
+	d, ctx := newFakeSheetsDestination(t, fake, Config{BufferSize: 2, ValueInputOption: "RAW"})
+	var acked []error
+	ack := func(err error) error {
+		acked = append(acked, err)
+		return nil
+	}
+
+	for _, payload := range []string{`["1"]`, `["2"]`, `["3"]`} {
+		err := d.WriteAsync(ctx, sdk.Record{Payload: sdk.RawData(payload)}, ack)
+		assert.NoError(t, err)
+	}
+	// the full buffer is written, the last record is written on teardown
+	assert.Equal(t, 2, fake.appended())
+	assert.Equal(t, []error{nil, nil}, acked)
+
+	assert.NoError(t, d.Teardown(ctx))
+	assert.Equal(t, 3, fake.appended())
+	assert.Equal(t, []error{nil, nil, nil}, acked)
+}
+
+func TestDestination_FlushTimer_Concurrent(t *testing.T) {
+	fake := &fakeSheets{t: t}
+	d, ctx := newFakeSheetsDestination(t, fake, Config{BufferSize: 1000, FlushInterval: time.Millisecond, ValueInputOption: "RAW"})
+	d.startFlushTimer(ctx)
+
+	const writers, records = 4, 50
+	var acks sync.WaitGroup
+	acks.Add(writers * records)
+	ack := func(err error) error {
+		assert.NoError(t, err)
+		acks.Done()
+		return nil
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < records; j++ {
+				payload := fmt.Sprintf(`["%d-%d"]`, i, j)
+				assert.NoError(t, d.WriteAsync(ctx, sdk.Record{Payload: sdk.RawData(payload)}, ack))
+				time.Sleep(100 * time.Microsecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the records are written by the timer running alongside WriteAsync, and by teardown
+	assert.NoError(t, d.Teardown(ctx))
+	acks.Wait()
+	assert.Equal(t, writers*records, fake.appended())
+}
+
+func TestDestination_FlushTimer_ConcurrentError(t *testing.T) {
+	fake := &fakeSheets{t: t, limit: 20}
+	d, ctx := newFakeSheetsDestination(t, fake, Config{BufferSize: 1000, FlushInterval: time.Millisecond, ValueInputOption: "RAW"})
+	d.startFlushTimer(ctx)
+
+	// the error of the write made by the timer is returned by the next WriteAsync call
+	var failed sync.WaitGroup
+	ack := func(err error) error { return nil }
+	for i := 0; i < 4; i++ {
+		failed.Add(1)
+		go func(i int) {
+			defer failed.Done()
+			for j := 0; ; j++ {
+				payload := fmt.Sprintf(`["%d-%d"]`, i, j)
+				if err := d.WriteAsync(ctx, sdk.Record{Payload: sdk.RawData(payload)}, ack); err != nil {
+					assert.Contains(t, err.Error(), "limit exceeded")
+					return
+				}
+				time.Sleep(100 * time.Microsecond)
+			}
+		}(i)
+	}
+	failed.Wait()
+	assert.NoError(t, d.Teardown(ctx))
+}
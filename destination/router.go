@@ -0,0 +1,168 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// target is the spreadsheet and the sheet the records are written to
+type target struct {
+	spreadsheetID string
+	sheetName     string
+}
+
+// maxTargets is the number of targets whose buffers and writers are kept. When a new target exceeds it,
+// the least recently used target is flushed and evicted, its writer is created again on its next record.
+const maxTargets = 64
+
+// defaultTarget returns the configured spreadsheet and sheet
+func (d *Destination) defaultTarget() target {
+	return target{spreadsheetID: d.config.GoogleSpreadsheetID, sheetName: d.config.SheetName}
+}
+
+// routeFuncs are the functions available to the route templates, in addition to the builtin functions
+var routeFuncs = template.FuncMap{
+	// date formats the time with the layout, e.g. {{ date .CreatedAt "2006_01" }}
+	"date":  func(t time.Time, layout string) string { return t.Format(layout) },
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// newRouteTemplate parses the route template, the missing metadata are rendered as empty strings
+func newRouteTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(routeFuncs).Option("missingkey=zero").Parse(text)
+}
+
+// routeData is the data the route templates are executed with
+type routeData struct {
+	Metadata map[string]string
+	Key      string
+	// Payload holds the fields of the structured and JSON object payloads, nil for the other payloads
+	Payload   map[string]interface{}
+	CreatedAt time.Time
+}
+
+// recordTarget returns the target of the record. If routing by metadata is enabled, the spreadsheet ID
+// and the sheet title metadata set by the source override the configured spreadsheet and sheet.
+// If route templates are set, the non-empty spreadsheet ID and sheet name rendered override them.
+func (d *Destination) recordTarget(r sdk.Record) (target, error) {
+	t := d.defaultTarget()
+	if d.config.RouteByMetadata {
+		if id := strings.TrimSpace(r.Metadata[sheets.MetadataSpreadsheetID]); id != "" {
+			t.spreadsheetID = id
+		}
+		if title := strings.TrimSpace(r.Metadata[sheets.MetadataSheetTitle]); title != "" {
+			t.sheetName = title
+		}
+		return t, nil
+	}
+	if d.config.SheetNameTemplate == nil && d.config.SpreadsheetIDTemplate == nil {
+		return t, nil
+	}
+
+	data := routeData{Metadata: r.Metadata, CreatedAt: r.CreatedAt}
+	if data.Metadata == nil {
+		data.Metadata = map[string]string{}
+	}
+	if r.Key != nil {
+		data.Key = string(r.Key.Bytes())
+	}
+	if r.Payload != nil {
+		// the payloads which can't be decoded are reported by the writer
+		_, data.Payload, _ = d.config.Format.Decode(r.Payload)
+	}
+	for _, route := range []struct {
+		tmpl  *template.Template
+		value *string
+	}{{d.config.SpreadsheetIDTemplate, &t.spreadsheetID}, {d.config.SheetNameTemplate, &t.sheetName}} {
+		if route.tmpl == nil {
+			continue
+		}
+		var sb strings.Builder
+		if err := route.tmpl.Execute(&sb, data); err != nil {
+			return target{}, fmt.Errorf("error executing %q template: %w", route.tmpl.Name(), err)
+		}
+		if value := strings.TrimSpace(sb.String()); value != "" {
+			*route.value = value
+		}
+	}
+	return t, nil
+}
+
+// writer returns the writer of the target, the writer is created on the first use
+func (d *Destination) writer(ctx context.Context, t target) (*sheets.Writer, error) {
+	if w, ok := d.writers[t]; ok {
+		return w, nil
+	}
+	w, err := sheets.NewWriter(ctx, sheets.WriterArgs{
+		TokenSource:      d.tokenSource,
+		SpreadsheetID:    t.spreadsheetID,
+		SheetName:        t.sheetName,
+		ValueInputOption: d.config.ValueInputOption,
+		MaxRetries:       d.config.MaxRetries,
+		HeaderRow:        d.config.HeaderRow,
+		AppendHeaders:    d.config.AppendHeaders,
+		WriteMode:        d.config.WriteMode,
+		KeyColumn:        d.config.KeyColumn,
+		DeleteMode:       d.config.DeleteMode,
+		Format:           d.config.Format,
+		CreateSheet:      d.config.CreateSheet,
+		Layout:           d.config.Layout,
+		Headers:          d.config.Headers,
+		Rollover:         d.config.Rollover,
+		RolloverState:    d.rolloverState,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to init writer of sheet(%s) in spreadsheet(%s): %w", t.sheetName, t.spreadsheetID, err)
+	}
+	d.writers[t] = w
+	return w, nil
+}
+
+// evictTarget flushes the records of the least recently used target, other than the configured one and the kept one,
+// and drops its buffer and writer
+func (d *Destination) evictTarget(ctx context.Context, keep target) error {
+	evicted := -1
+	for i, t := range d.targets {
+		if t == keep || t == d.defaultTarget() {
+			continue
+		}
+		if evicted == -1 || d.buffers[t].used < d.buffers[d.targets[evicted]].used {
+			evicted = i
+		}
+	}
+	if evicted == -1 {
+		return nil
+	}
+	t := d.targets[evicted]
+	if err := d.flushTarget(ctx, t); err != nil {
+		return err
+	}
+	delete(d.buffers, t)
+	delete(d.writers, t)
+	d.targets = append(d.targets[:evicted], d.targets[evicted+1:]...)
+	return nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package destination
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/config"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDestination_recordTarget(t *testing.T) {
+	records := []sdk.Record{
+		{Key: sdk.RawData("1"), Metadata: map[string]string{sheets.MetadataSheetTitle: "Feb"}},
+		{Key: sdk.RawData("2")},
+		{Key: sdk.RawData("3"), Metadata: map[string]string{sheets.MetadataSheetTitle: "Feb", sheets.MetadataSpreadsheetID: "other"}},
+	}
+	d := &Destination{config: Config{
+		Config:          config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+		SheetName:       "Sheet1",
+		RouteByMetadata: true,
+	}}
+	feb := target{spreadsheetID: "dummy_spreadsheet", sheetName: "Feb"}
+	sheet1 := target{spreadsheetID: "dummy_spreadsheet", sheetName: "Sheet1"}
+	other := target{spreadsheetID: "other", sheetName: "Feb"}
+
+	for i, expected := range []target{feb, sheet1, other} {
+		actual, err := d.recordTarget(records[i])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// the metadata is ignored if routing is disabled
+	d.config.RouteByMetadata = false
+	for _, r := range records {
+		actual, err := d.recordTarget(r)
+		assert.NoError(t, err)
+		assert.Equal(t, sheet1, actual)
+	}
+}
+
+func TestDestination_recordTarget_Template(t *testing.T) {
+	createdAt := time.Date(2022, time.March, 4, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name          string
+		sheetName     string
+		spreadsheetID string
+		record        sdk.Record
+		expected      target
+		err           error
+	}{
+		{
+			name:      "metadata",
+			sheetName: "{{ .Metadata.table }}",
+			record:    sdk.Record{Metadata: map[string]string{"table": "orders"}},
+			expected:  target{spreadsheetID: "dummy_spreadsheet", sheetName: "orders"},
+		},
+		{
+			name:      "date of the record",
+			sheetName: `orders_{{ date .CreatedAt "2006_01" }}`,
+			record:    sdk.Record{CreatedAt: createdAt},
+			expected:  target{spreadsheetID: "dummy_spreadsheet", sheetName: "orders_2022_03"},
+		},
+		{
+			name:          "payload field and spreadsheet",
+			sheetName:     "{{ upper .Payload.region }}",
+			spreadsheetID: "{{ .Metadata.spreadsheet }}",
+			record: sdk.Record{
+				Metadata: map[string]string{"spreadsheet": "other"},
+				Payload:  sdk.RawData(`{"region":"eu"}`),
+			},
+			expected: target{spreadsheetID: "other", sheetName: "EU"},
+		},
+		{
+			name:      "key",
+			sheetName: "{{ .Key }}",
+			record:    sdk.Record{Key: sdk.StructuredData{"id": "1"}},
+			expected:  target{spreadsheetID: "dummy_spreadsheet", sheetName: `{"id":"1"}`},
+		},
+		{
+			name:      "empty result",
+			sheetName: "{{ .Metadata.table }}",
+			record:    sdk.Record{},
+			expected:  target{spreadsheetID: "dummy_spreadsheet", sheetName: "Sheet1"},
+		},
+		{
+			name:      "template error",
+			sheetName: "{{ date .Metadata.table \"2006\" }}",
+			record:    sdk.Record{Metadata: map[string]string{"table": "orders"}},
+			err: fmt.Errorf("error executing \"sheetNameTemplate\" template: template: sheetNameTemplate:1:17: executing " +
+				"\"sheetNameTemplate\" at <.Metadata.table>: wrong type for value; expected time.Time; got string"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &Destination{config: Config{
+				Config:    config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+				SheetName: "Sheet1",
+			}}
+			if tc.sheetName != "" {
+				d.config.SheetNameTemplate = template.Must(newRouteTemplate(KeySheetNameTemplate, tc.sheetName))
+			}
+			if tc.spreadsheetID != "" {
+				d.config.SpreadsheetIDTemplate = template.Must(newRouteTemplate(KeySpreadsheetIDTemplate, tc.spreadsheetID))
+			}
+
+			actual, err := d.recordTarget(tc.record)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestDestination_WriteAsync_BufferPerTarget(t *testing.T) {
+	d := &Destination{
+		config: Config{
+			Config:            config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+			SheetName:         "Sheet1",
+			BufferSize:        2,
+			SheetNameTemplate: template.Must(newRouteTemplate(KeySheetNameTemplate, "{{ .Metadata.table }}")),
+		},
+		buffers: make(map[target]*buffer),
+		mux:     &sync.Mutex{},
+	}
+	var acked []error
+	ack := func(err error) error {
+		acked = append(acked, err)
+		return nil
+	}
+
+	// the buffer of each target holds a single record, so none is flushed
+	for _, table := range []string{"orders", "users"} {
+		err := d.WriteAsync(context.Background(), sdk.Record{
+			Metadata: map[string]string{"table": table},
+			Payload:  sdk.RawData(`["1"]`),
+		}, ack)
+		assert.NoError(t, err)
+	}
+	orders := target{spreadsheetID: "dummy_spreadsheet", sheetName: "orders"}
+	users := target{spreadsheetID: "dummy_spreadsheet", sheetName: "users"}
+	assert.Equal(t, []target{orders, users}, d.targets)
+	assert.Len(t, d.buffers[orders].records, 1)
+	assert.Len(t, d.buffers[users].records, 1)
+	assert.Empty(t, acked)
+
+	// the records of every target are acknowledged with the error encountered, without being written
+	d.err = fmt.Errorf("write error")
+	assert.NoError(t, d.Flush(context.Background()))
+	assert.Equal(t, []error{d.err, d.err}, acked)
+	assert.Empty(t, d.buffers[orders].records)
+	assert.Empty(t, d.buffers[users].records)
+}
+
+func TestDestination_WriteAsync_EvictTarget(t *testing.T) {
+	d := &Destination{
+		config: Config{
+			Config:            config.Config{GoogleSpreadsheetID: "dummy_spreadsheet"},
+			SheetName:         "Sheet1",
+			BufferSize:        2,
+			SheetNameTemplate: template.Must(newRouteTemplate(KeySheetNameTemplate, "{{ .Metadata.table }}")),
+		},
+		buffers: make(map[target]*buffer),
+		writers: make(map[target]*sheets.Writer),
+		mux:     &sync.Mutex{},
+	}
+	// the buffers of the targets are flushed, the configured sheet is used least recently
+	for i := 0; i < maxTargets; i++ {
+		tt := target{spreadsheetID: "dummy_spreadsheet", sheetName: fmt.Sprintf("sheet_%d", i)}
+		if i == 0 {
+			tt = d.defaultTarget()
+		}
+		d.used++
+		d.buffers[tt] = &buffer{used: d.used}
+		d.writers[tt] = nil
+		d.targets = append(d.targets, tt)
+	}
+	// sheet_1 received a record after the other targets, sheet_2 is evicted instead
+	d.used++
+	d.buffers[target{spreadsheetID: "dummy_spreadsheet", sheetName: "sheet_1"}].used = d.used
+
+	err := d.WriteAsync(context.Background(), sdk.Record{
+		Metadata: map[string]string{"table": "orders"},
+		Payload:  sdk.RawData(`["1"]`),
+	}, func(error) error { return nil })
+	assert.NoError(t, err)
+
+	evicted := target{spreadsheetID: "dummy_spreadsheet", sheetName: "sheet_2"}
+	assert.Len(t, d.targets, maxTargets)
+	assert.NotContains(t, d.targets, evicted)
+	assert.NotContains(t, d.buffers, evicted)
+	assert.NotContains(t, d.writers, evicted)
+	assert.Contains(t, d.buffers, d.defaultTarget())
+	assert.Equal(t, target{spreadsheetID: "dummy_spreadsheet", sheetName: "orders"}, d.targets[len(d.targets)-1])
+}
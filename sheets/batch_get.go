@@ -18,15 +18,18 @@ package sheets
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/conduitio/conduit-connector-google-sheets/source/position"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
@@ -37,8 +40,13 @@ const majorDimension = "ROWS"
 type BatchReader struct {
 	// spreadsheet ID of the Google sheet
 	spreadsheetID string
-	// gid of the sheet extracted from the sheet URL <url>#gid=<gid>
-	sheetID int64
+	// spreadsheetTitle is the title of the spreadsheet, empty if the spreadsheet metadata couldn't be read
+	spreadsheetTitle string
+	// readAt is the time the values of the last read were fetched at
+	readAt time.Time
+	// ranges are the ranges of the sheets to be read, resolved from the sheet gid extracted from
+	// the sheet URL <url>#gid=<gid> or from the range selector
+	ranges []SheetRange
 	// instance of sheets service, used to interact with Google Sheets APIs
 	sheetSvc *sheets.Service
 	// If rate limit is exceeded, nextRun is used to skip hitting API till the specified time.
@@ -55,111 +63,638 @@ type BatchReader struct {
 	// valueRenderOption Determines how values in the response should be rendered.
 	// The default render option is FORMATTED_VALUE.
 	valueRenderOption string
+	// headerRow is the row number, relative to the start of the range, holding the field names.
+	// The rows up to the header row are not part of the records, 0 if the sheet has no header row.
+	headerRow int64
+	// headers are the last header names read per sheet gid, used to log the renamed headers
+	headers map[int64][]string
+
+	// cdc enables reading the changes of the rows, the whole ranges are read on every call
+	// and diffed against the snapshot of the rows read by the previous call
+	cdc bool
+	// keyColumn identifies the rows in cdc mode, the row number is used if not set
+	keyColumn string
+	// stateFile stores the snapshot of the rows in cdc mode, or the ids of the rows read if the rows are identified
+	// by their hash, it is required then
+	stateFile *StateFile
+	// snapshot is the state of the rows read in cdc mode keyed by the row id, loaded on the first read
+	snapshot map[string]rowState
+	// seq is the sequence number of the last change read in cdc mode
+	seq int64
+	// stateMux guards the acknowledged state, which is updated by Ack concurrently to the reads
+	stateMux sync.Mutex
+	// pending are the changes read, which are not acknowledged yet
+	pending []pendingChange
+	// acked is the snapshot of the rows after the last acknowledged change, ackedSeq is its sequence number
+	acked    map[string]rowState
+	ackedSeq int64
+	// savedSeq is the sequence number of the last change saved to the state file
+	savedSeq int64
+
+	// pageSize is the maximum number of rows read from a sheet by a request, 0 to read all the rows at once
+	pageSize int64
+	// caughtUp is set once a page with less rows than the page size is read, the rows are then read without a page limit
+	caughtUp bool
+	// hasMore is set if the last read returned a full page of rows
+	hasMore bool
+	// pagePosition is the position after all the rows of the last page read, including the rows yielding no records
+	pagePosition position.SheetPosition
+
+	// instance of drive service, used to check the version of the spreadsheet before reading the values.
+	// The values are read on every call if nil.
+	driveSvc *drive.Service
+	// version is the Drive version of the spreadsheet, whose rows have all been read
+	version int64
+	// driveRetryCount is the count of unsuccessful retries of the Drive API calls, made after getting 429 http error status.
+	// The Drive and the Sheets API calls are rate limited separately.
+	driveRetryCount int64
+
+	// incrementalColumn is the column whose values are the cursor of the rows read, the whole ranges are read
+	// on every call and the rows after the cursor are returned, the row offsets are used if not set
+	incrementalColumn string
+
+	// snapshotEnabled enables the snapshot phase, the rows existing when the source is started are read as snapshots
+	snapshotEnabled bool
+	// phase is position.PhaseSnapshot till all the rows have been read once, position.PhaseCDC afterwards.
+	// It is loaded from the position on the first read.
+	phase string
+
+	// typedValues enables converting the values read using the types of the columns, the values are read unformatted
+	typedValues bool
+	// location is the time zone of the spreadsheet, the serial numbers of the dates are converted in
+	location *time.Location
+	// columnTypes are the types of the columns per sheet gid, inferred from the first row after the header row.
+	// They are reloaded on every read of the values, as the Drive version changes along with the formats.
+	columnTypes map[int64][]string
+	// schemas are the schemas of the columns in JSON per sheet gid, cleared when the types or the headers change
+	schemas map[int64]string
+	// widths are the numbers of values of the widest row read per sheet gid, the columns of the schemas
+	// without a header row
+	widths map[int64]int
+
+	// schemaFormat is the format of the schema definitions set in the record metadata, no definition is set if empty
+	schemaFormat string
+	// schemaVersions are the versions of the schemas per sheet gid, loaded from the position on the first read
+	schemaVersions map[int64]position.SchemaVersion
+	// definitions are the schema definitions per sheet gid, built by trackSchema
+	definitions map[int64]string
+
+	// format is the format of the record payloads
+	format Format
+
+	// hashRows enables identifying the rows read in append mode by the hash of their values instead of the row offsets,
+	// the ids of the rows read are stored in the state file
+	hashRows bool
+	// rowNumbers are the row numbers of the rows found by the previous read keyed by the row ids, used to log
+	// the structural edits
+	rowNumbers map[string]int64
+
+	// keyColumns are the columns whose values compose the record keys, the row numbers, or the cdc keys, are used if not set
+	keyColumns []string
+	// keySeparator joins the values of the key columns in the raw record keys
+	keySeparator string
+	// structuredKey enables the record keys keyed by the key columns
+	structuredKey bool
+	// skipEmptyKeys enables skipping the rows with an empty record key, the read fails on these rows otherwise
+	skipEmptyKeys bool
 }
 
 type BatchReaderArgs struct {
-	OAuthConfig          *oauth2.Config
-	OAuthToken           *oauth2.Token
-	SpreadsheetID        string
-	SheetID              int64
+	// TokenSource provides the tokens used to authenticate the Sheets API calls
+	TokenSource   oauth2.TokenSource
+	SpreadsheetID string
+	SheetID       int64
+	// SheetName, SheetNames, Range and NamedRange select the range to be read, resolved using the spreadsheet metadata.
+	// At most one of them is expected to be set, the whole sheet SheetID is read if none is set.
+	SheetName            string
+	SheetNames           []string
+	Range                string
+	NamedRange           string
 	DateTimeRenderOption string
 	ValueRenderOption    string
 	PollingPeriod        time.Duration
+	// HeaderRow is the row number holding the field names, relative to the start of the range.
+	// The rows are read as structured data keyed by the header names if set, as JSON arrays otherwise.
+	HeaderRow int64
+	// CDC enables reading the created, updated and deleted rows, instead of the appended rows only
+	CDC bool
+	// KeyColumn is the header name, or the column letters without a header row, of the column identifying
+	// the rows in cdc mode. The rows are identified by the row number if not set.
+	KeyColumn string
+	// StateFile is the path of the file storing the state of the rows in cdc mode, or the ids of the rows read with
+	// HashRows.
+	// The fingerprints of the rows are stored in the position if not set.
+	StateFile string
+	// PageSize is the maximum number of rows read from a sheet by a request, while catching up with the sheet.
+	// All the rows are read at once if 0.
+	PageSize int64
+	// IncrementalColumn is the header name, or the column letters without a header row, of the column
+	// holding a timestamp or a sequence, used as the cursor of the rows read instead of the row offsets
+	IncrementalColumn string
+	// Snapshot enables the snapshot phase, the rows existing when the source is started are read as snapshots,
+	// before the rows created afterwards, or the row changes in cdc mode, are read
+	Snapshot bool
+	// TypedValues enables converting the values to numbers, booleans and timestamps using the number formats
+	// of the columns, the render options are expected to be UNFORMATTED_VALUE and SERIAL_NUMBER
+	TypedValues bool
+	// SchemaFormat is SchemaFormatAvro or SchemaFormatJSON to set the schema definition of the sheet in the record
+	// metadata, derived from the header row and the column types. No definition is set if empty.
+	SchemaFormat string
+	// Format is the format of the record payloads, structured data with a header row and JSON arrays otherwise if not set
+	Format Format
+	// HashRows enables identifying the rows read in append mode by the hash of their values instead of the row
+	// offsets, which requires the StateFile
+	HashRows bool
+	// KeyColumns are the header names, or the column letters without a header row, of the columns whose values
+	// compose the record keys. The record keys are the row numbers, or the cdc keys in cdc mode, if not set.
+	KeyColumns []string
+	// KeySeparator joins the values of the key columns, unless StructuredKey is set
+	KeySeparator string
+	// StructuredKey enables the record keys as structured data keyed by the key columns
+	StructuredKey bool
+	// SkipEmptyKeys enables skipping the rows whose key columns are all blank, the read fails on these rows otherwise
+	SkipEmptyKeys bool
 }
 
 func NewBatchReader(ctx context.Context, args BatchReaderArgs) (*BatchReader, error) {
-	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(args.OAuthConfig.Client(ctx, args.OAuthToken)))
+	client := oauth2.NewClient(ctx, args.TokenSource)
+	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(client))
 	if err != nil {
 		return nil, fmt.Errorf("error creating sheets service client: %w", err)
 	}
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(client))
+	if err != nil {
+		return nil, fmt.Errorf("error creating drive service client: %w", err)
+	}
+	ranges, err := resolveSheetRanges(ctx, sheetService, args.SpreadsheetID, RangeSelector{
+		SheetID:    args.SheetID,
+		SheetName:  args.SheetName,
+		SheetNames: args.SheetNames,
+		Range:      args.Range,
+		NamedRange: args.NamedRange,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error resolving sheet range: %w", err)
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no sheets found to be read in spreadsheet(%s)", args.SpreadsheetID)
+	}
+	spreadsheetTitle := loadTitles(ctx, sheetService, args.SpreadsheetID, ranges)
+	var stateFile *StateFile
+	if args.StateFile != "" {
+		stateFile = &StateFile{Path: args.StateFile}
+	} else if args.CDC {
+		return nil, fmt.Errorf("a state file is required to read spreadsheet(%s) in cdc mode", args.SpreadsheetID)
+	}
 	return &BatchReader{
 		spreadsheetID:        args.SpreadsheetID,
-		sheetID:              args.SheetID,
+		spreadsheetTitle:     spreadsheetTitle,
+		ranges:               ranges,
 		pollingPeriod:        args.PollingPeriod,
 		sheetSvc:             sheetService,
+		driveSvc:             driveService,
 		dateTimeRenderOption: args.DateTimeRenderOption,
 		valueRenderOption:    args.ValueRenderOption,
+		headerRow:            args.HeaderRow,
+		cdc:                  args.CDC,
+		keyColumn:            args.KeyColumn,
+		stateFile:            stateFile,
+		pageSize:             args.PageSize,
+		incrementalColumn:    args.IncrementalColumn,
+		snapshotEnabled:      args.Snapshot,
+		typedValues:          args.TypedValues,
+		schemaFormat:         args.SchemaFormat,
+		format:               args.Format,
+		hashRows:             args.HashRows,
+		keyColumns:           args.KeyColumns,
+		keySeparator:         args.KeySeparator,
+		structuredKey:        args.StructuredKey,
+		skipEmptyKeys:        args.SkipEmptyKeys,
 	}, nil
 }
 
-// GetSheetRecords returns the list of records up to a maximum of 1000 rows(default limit)
-// added after the row offset of last successfully read record
-func (b *BatchReader) GetSheetRecords(ctx context.Context, offset int64) ([]sdk.Record, error) {
+// GetSheetRecords returns the list of records up to a maximum of page size rows per sheet
+// added after the row offset of last successfully read record of the sheet, received in the position.
+// The offsets are relative to the start of the range of the sheet, or to the header row if set.
+// In cdc mode, the whole ranges are read and the records of the created, updated and deleted rows are returned.
+// The values are not read if the Drive version of the spreadsheet is the version of the rows read last.
+func (b *BatchReader) GetSheetRecords(ctx context.Context, pos position.SheetPosition) ([]sdk.Record, error) {
 	if b.nextRun.After(time.Now()) {
 		return nil, nil
 	}
+	b.loadPhase(pos)
+	b.loadSchemaVersions(pos)
+	version, changed := b.checkVersion(ctx, pos)
+	if !changed && !b.hasMore {
+		return nil, nil
+	}
+	if b.typedValues && (changed || b.columnTypes == nil) {
+		b.loadColumnTypes(ctx)
+	}
+	if b.cdc || b.incrementalColumn != "" || b.hashRows {
+		return b.getAll(ctx, pos, version)
+	}
+
+	// the rows are read in pages while catching up, once the end of the sheets is reached
+	// all the rows added after the offsets are read by the next calls
+	bounded := !b.caughtUp
+	req, requested := b.getDataFilter(b.ranges, pos, bounded)
+	if len(requested) == 0 {
+		// all the rows of the bounded ranges have been read
+		b.hasMore = false
+		return b.completeRead(nil, version)
+	}
 
-	res, err := b.sheetSvc.Spreadsheets.Values.BatchGetByDataFilter(b.spreadsheetID, b.getDataFilter(offset)).Context(ctx).Do()
+	res, err := b.batchGet(ctx, req)
+	if err != nil || res == nil {
+		return nil, err
+	}
+	b.hasMore = bounded && b.fullPage(res.ValueRanges, requested)
+	b.caughtUp = !b.hasMore
+	records, err := b.valueRangesToRecords(ctx, res.ValueRanges, requested, pos)
+	if err != nil {
+		return records, err
+	}
+	b.setOperations(records)
+	if b.hasMore {
+		return records, nil
+	}
+	return b.completeRead(records, version)
+}
+
+// HasMore reports whether the last call of GetSheetRecords read a full page of rows from any sheet,
+// in which case the next page is expected to be read right away, without waiting for the polling period
+func (b *BatchReader) HasMore() bool {
+	return b.hasMore
+}
+
+// PagePosition returns the position after all the rows of the page read by the last call of GetSheetRecords.
+// It is used to read the next page if the page yields no records, as no record position moves past its rows then.
+func (b *BatchReader) PagePosition() position.SheetPosition {
+	return b.pagePosition
+}
+
+// getAll reads all the rows of the ranges, and returns the records of the row changes in cdc mode,
+// or the records of the rows after the cursors of the incremental column, or the records of the rows not read yet
+func (b *BatchReader) getAll(ctx context.Context, pos position.SheetPosition, version int64) ([]sdk.Record, error) {
+	valueRanges, requested, err := b.readAll(ctx)
+	if err != nil || valueRanges == nil {
+		return nil, err
+	}
+
+	var records []sdk.Record
+	switch {
+	case b.cdc:
+		records, err = b.valueRangesToChanges(ctx, valueRanges, requested, pos)
+	case b.hashRows:
+		records, err = b.valueRangesToHashed(ctx, valueRanges, requested, pos)
+	default:
+		records, err = b.valueRangesToIncremental(ctx, valueRanges, requested, pos)
+	}
+	if err != nil {
+		return nil, err
+	}
+	b.setOperations(records)
+	return b.completeRead(records, version)
+}
+
+// readAll reads all the pages of the ranges, the rows of the pages are merged into a value range per requested range.
+// nil is returned without an error if the values are not modified or the rate limit is exceeded.
+func (b *BatchReader) readAll(ctx context.Context) ([]*sheets.MatchedValueRange, []SheetRange, error) {
+	pageOffsets := position.SheetPosition{Offsets: make(map[int64]int64, len(b.ranges))}
+	ranges := b.ranges
+	var requested []SheetRange
+	values := make(map[int64]*sheets.MatchedValueRange)
+	headers := make(map[int64]*sheets.MatchedValueRange)
+
+	for len(ranges) > 0 {
+		req, pageRanges := b.getDataFilter(ranges, pageOffsets, b.pageSize > 0)
+		if len(pageRanges) == 0 {
+			break
+		}
+		res, err := b.batchGet(ctx, req)
+		if err != nil || res == nil {
+			return nil, nil, err
+		}
+
+		ranges = ranges[:0:0]
+		for _, page := range b.splitValueRanges(res.ValueRanges, pageRanges) {
+			sheetID := page.sheetRange.GridRange.SheetId
+			if _, ok := values[sheetID]; !ok {
+				requested = append(requested, page.sheetRange)
+				values[sheetID] = &sheets.MatchedValueRange{ValueRange: &sheets.ValueRange{}}
+				headers[sheetID] = page.headerRange
+			}
+			values[sheetID].ValueRange.Values = append(values[sheetID].ValueRange.Values, page.rows...)
+			if b.pageSize > 0 && int64(len(page.rows)) >= b.pageSize {
+				pageOffsets.Offsets[sheetID] += b.pageSize
+				ranges = append(ranges, page.sheetRange)
+			}
+		}
+	}
+
+	valueRanges := make([]*sheets.MatchedValueRange, 0, len(requested)*2)
+	for _, sheetRange := range requested {
+		if b.headerRow > 0 {
+			valueRanges = append(valueRanges, headers[sheetRange.GridRange.SheetId])
+		}
+		valueRanges = append(valueRanges, values[sheetRange.GridRange.SheetId])
+	}
+	return valueRanges, requested, nil
+}
+
+// batchGet fetches the values of the data filters, nil is returned without an error if the values are not modified
+// or the rate limit is exceeded, in which case the next calls are skipped till the back off duration is over
+func (b *BatchReader) batchGet(
+	ctx context.Context,
+	req *sheets.BatchGetValuesByDataFilterRequest,
+) (*sheets.BatchGetValuesByDataFilterResponse, error) {
+	b.readAt = time.Now()
+	res, err := b.sheetSvc.Spreadsheets.Values.BatchGetByDataFilter(b.spreadsheetID, req).Context(ctx).Do()
 	if err != nil {
 		if googleapi.IsNotModified(err) {
 			return nil, nil
 		}
 		if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusTooManyRequests {
-			b.retryCount++
-			duration := time.Duration(b.retryCount * int64(b.pollingPeriod)) // exponential back off
-			b.nextRun = time.Now().Add(duration)
-			sdk.Logger(ctx).Error().Err(gerr).
-				Int64("retry_count", b.retryCount).
-				Float64("wait_duration", duration.Seconds()).
-				Msg("exponential back off, rate limit exceeded")
+			b.backOff(ctx, &b.retryCount, gerr)
 			return nil, nil
 		}
-		return nil, fmt.Errorf("error getting sheet(gid:%v) values, %w", b.sheetID, err)
+		return nil, fmt.Errorf("error getting sheet(gid:%v) values, %w", b.sheetIDs(), err)
 	}
-
 	b.retryCount = 0
-	return b.valueRangesToRecords(res.ValueRanges, offset)
+	return res, nil
 }
 
-func (b *BatchReader) getDataFilter(offset int64) *sheets.BatchGetValuesByDataFilterRequest {
-	dataFilters := make([]*sheets.DataFilter, 0)
-	dataFilters = append(dataFilters, &sheets.DataFilter{
-		GridRange: &sheets.GridRange{
-			SheetId:       b.sheetID,
-			StartRowIndex: offset,
-		},
-	})
+// backOff skips the next calls till the back off duration is over, after the rate limit of the API whose
+// retry count is received is exceeded
+func (b *BatchReader) backOff(ctx context.Context, retryCount *int64, err error) {
+	*retryCount++
+	duration := time.Duration(*retryCount * int64(b.pollingPeriod)) // exponential back off
+	b.nextRun = time.Now().Add(duration)
+	b.hasMore = false
+	sdk.Logger(ctx).Error().Err(err).
+		Int64("retry_count", *retryCount).
+		Float64("wait_duration", duration.Seconds()).
+		Msg("exponential back off, rate limit exceeded")
+}
+
+// fullPage reports whether a page of rows was read from any sheet
+func (b *BatchReader) fullPage(valueRanges []*sheets.MatchedValueRange, requested []SheetRange) bool {
+	if b.pageSize <= 0 {
+		return false
+	}
+	for _, values := range b.splitValueRanges(valueRanges, requested) {
+		if int64(len(values.rows)) >= b.pageSize {
+			return true
+		}
+	}
+	return false
+}
+
+// getDataFilter returns the request to fetch the rows after the offset of every sheet of the ranges,
+// along with the ranges requested, the i-th data filter is for the i-th requested range.
+// If the header row is set, the header row of each requested range is fetched as well,
+// the data filters are then pairs of header row and rows for each requested range.
+// If bounded is set, a page of rows of the page size at most is requested from each range.
+func (b *BatchReader) getDataFilter(
+	ranges []SheetRange,
+	pos position.SheetPosition,
+	bounded bool,
+) (*sheets.BatchGetValuesByDataFilterRequest, []SheetRange) {
+	dataFilters := make([]*sheets.DataFilter, 0, len(ranges))
+	requested := make([]SheetRange, 0, len(ranges))
+	for _, sheetRange := range ranges {
+		gridRange := *sheetRange.GridRange
+		gridRange.StartRowIndex += b.headerRow + pos.Offset(gridRange.SheetId)
+		if gridRange.EndRowIndex > 0 && gridRange.StartRowIndex >= gridRange.EndRowIndex {
+			continue
+		}
+		if bounded && b.pageSize > 0 {
+			if end := gridRange.StartRowIndex + b.pageSize; gridRange.EndRowIndex == 0 || end < gridRange.EndRowIndex {
+				gridRange.EndRowIndex = end
+			}
+		}
+		if b.headerRow > 0 {
+			headerRange := *sheetRange.GridRange
+			headerRange.StartRowIndex += b.headerRow - 1
+			headerRange.EndRowIndex = headerRange.StartRowIndex + 1
+			dataFilters = append(dataFilters, &sheets.DataFilter{
+				GridRange: &headerRange,
+			})
+		}
+		dataFilters = append(dataFilters, &sheets.DataFilter{
+			GridRange: &gridRange,
+		})
+		requested = append(requested, sheetRange)
+	}
 	return &sheets.BatchGetValuesByDataFilterRequest{
 		DataFilters:          dataFilters,
 		DateTimeRenderOption: b.dateTimeRenderOption,
 		MajorDimension:       majorDimension,
 		ValueRenderOption:    b.valueRenderOption,
+	}, requested
+}
+
+// sheetValues are the values read from a requested range
+type sheetValues struct {
+	sheetRange SheetRange
+	// headerRange is the value range of the header row, nil if the header row is not set
+	headerRange *sheets.MatchedValueRange
+	// header is the header row read, nil if the header row is not set or empty
+	header []interface{}
+	// rows are the rows read after the header row
+	rows [][]interface{}
+}
+
+// splitValueRanges pairs the value ranges fetched with the requested ranges, the i-th requested range is read
+// by the i-th value range, or by the i-th pair of header row and rows if the header row is set, see getDataFilter
+func (b *BatchReader) splitValueRanges(valueRanges []*sheets.MatchedValueRange, requested []SheetRange) []sheetValues {
+	stride := 1
+	if b.headerRow > 0 {
+		stride = 2
+	}
+	out := make([]sheetValues, 0, len(requested))
+	for i := 0; i+stride <= len(valueRanges) && i/stride < len(requested); i += stride {
+		values := sheetValues{sheetRange: requested[i/stride]}
+		if b.headerRow > 0 {
+			values.headerRange = valueRanges[i]
+			if headerValues := valueRanges[i].ValueRange.Values; len(headerValues) > 0 {
+				values.header = headerValues[0]
+			}
+		}
+		values.rows = valueRanges[i+stride-1].ValueRange.Values
+		out = append(out, values)
+	}
+	return out
+}
+
+// readSheets splits the value ranges read into the values of the requested ranges, tracking the header rows
+// and the schemas of the sheets read
+func (b *BatchReader) readSheets(
+	ctx context.Context,
+	valueRanges []*sheets.MatchedValueRange,
+	requested []SheetRange,
+) []sheetValues {
+	out := b.splitValueRanges(valueRanges, requested)
+	for _, values := range out {
+		if b.headerRow > 0 {
+			b.trackHeaders(ctx, values.sheetRange, values.header)
+		} else {
+			b.trackWidth(values.sheetRange, values.rows)
+		}
+		b.trackSchema(ctx, values.sheetRange)
 	}
+	return out
 }
 
-func (b *BatchReader) valueRangesToRecords(valueRanges []*sheets.MatchedValueRange, offset int64) ([]sdk.Record, error) {
+func (b *BatchReader) valueRangesToRecords(
+	ctx context.Context,
+	valueRanges []*sheets.MatchedValueRange,
+	requested []SheetRange,
+	pos position.SheetPosition,
+) ([]sdk.Record, error) {
 	records := make([]sdk.Record, 0)
 
+	// offsets are tracked per sheet only when multiple sheets are read
+	var offsets map[int64]int64
+	if len(b.ranges) > 1 {
+		offsets = make(map[int64]int64, len(b.ranges))
+		for _, sheetRange := range b.ranges {
+			offsets[sheetRange.GridRange.SheetId] = pos.Offset(sheetRange.GridRange.SheetId)
+		}
+	}
+
 	// As we can fetch multiple ranges in one BatchGetByDataFilter request
 	// iterate over all the value ranges fetched from the Google sheet BatchGet API request
 	// https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/batchGetByDataFilter#response-body
-	for _, valueRange := range valueRanges {
-		rowValues := valueRange.ValueRange.Values
+	for _, values := range b.readSheets(ctx, valueRanges, requested) {
+		sheetRange, header := values.sheetRange, values.header
+		sheetID := sheetRange.GridRange.SheetId
+		offset := pos.Offset(sheetID)
+
+		keys, err := b.recordKeys(sheetRange, header)
+		if err != nil {
+			return records, err
+		}
+
+		rowValues := values.rows
 		// Iterate over the Rows of the value range
 		// Data is of format: [][]interface{} => ([ [ROW1 => A1,B1,C1..], [ROW2 => A2, B2, C2,...],...])
 		for index, rowValue := range rowValues {
 			if len(rowValue) == 0 {
 				continue
 			}
-			rawData, err := json.Marshal(rowValue)
+			rowOffset := offset + int64(index) + 1
+			// row number in the sheet, differs from row offset if the range doesn't start from the first row
+			rowNumber := sheetRange.GridRange.StartRowIndex + b.headerRow + rowOffset
+			key, err := b.key(keys, rowValue, strconv.FormatInt(rowNumber, 10), rowNumber)
 			if err != nil {
-				return records, fmt.Errorf("error marshaling the map: %w", err)
+				return records, err
+			}
+			if key == nil {
+				continue
+			}
+			payload, err := b.rowPayload(sheetRange, header, rowValue)
+			if err != nil {
+				return records, err
 			}
-			rowOffset := offset + int64(index) + 1
 			lastRowPosition := position.SheetPosition{
 				RowOffset:     rowOffset,
 				SpreadsheetID: b.spreadsheetID,
-				SheetID:       b.sheetID,
+				SheetID:       sheetID,
+				Phase:         b.phase,
+				Schemas:       b.schemaVersions,
+			}
+			if offsets != nil {
+				offsets[sheetID] = rowOffset
+				lastRowPosition.Offsets = copyOffsets(offsets)
 			}
 
 			records = append(records, sdk.Record{
 				Position:  lastRowPosition.RecordPosition(),
-				Metadata:  nil,
+				Metadata:  b.recordMetadata(sheetRange, rowNumber, len(rowValue)),
 				CreatedAt: time.Now(),
-				Key:       sdk.RawData(fmt.Sprintf("%d", rowOffset)),
-				Payload:   sdk.RawData(rawData),
+				Key:       key,
+				Payload:   payload,
 			})
 		}
+		keys.logSkipped(ctx, b.keyColumns)
+
+		// the rows read are skipped by the next page, even if they yield no records, e.g. if they are blank
+		b.pagePosition = position.SheetPosition{
+			RowOffset:     offset + int64(len(rowValues)),
+			SpreadsheetID: b.spreadsheetID,
+			SheetID:       sheetID,
+			Phase:         b.phase,
+			Schemas:       b.schemaVersions,
+		}
+		if offsets != nil {
+			offsets[sheetID] = b.pagePosition.RowOffset
+			b.pagePosition.Offsets = copyOffsets(offsets)
+		}
 	}
 	return records, nil
 }
+
+// rowPayload returns the payload of the row in the configured format. By default, the row is read as structured data
+// keyed by the header names if the header row is set, as raw data of the JSON array of the row values otherwise.
+// Without a header row, the row values are keyed by the column letters.
+func (b *BatchReader) rowPayload(sheetRange SheetRange, header, row []interface{}) (sdk.Data, error) {
+	if b.typedValues {
+		row = b.typedRow(sheetRange.GridRange.SheetId, row)
+	}
+	return b.payloadFormat().Encode(headerNames(header, sheetRange.GridRange.StartColumnIndex, len(row)), row)
+}
+
+// payloadFormat returns the format of the record payloads, structured data with a header row
+// and JSON arrays otherwise if the format is not set
+func (b *BatchReader) payloadFormat() Format {
+	format := b.format
+	if format.Type == "" {
+		format.Type = FormatJSONArray
+		if b.headerRow > 0 {
+			format.Type = FormatStructured
+		}
+	}
+	return format
+}
+
+// trackHeaders stores the header names of the sheet, logging the headers if they changed since the last read.
+// The records are always keyed by the headers read along with the rows, so a renamed header applies
+// to the rows read after the rename only.
+func (b *BatchReader) trackHeaders(ctx context.Context, sheetRange SheetRange, header []interface{}) {
+	names := headerNames(header, sheetRange.GridRange.StartColumnIndex, len(header))
+	sheetID := sheetRange.GridRange.SheetId
+	last, ok := b.headers[sheetID]
+	if ok && sameHeaders(last, names) {
+		return
+	}
+	if b.headers == nil {
+		b.headers = make(map[int64][]string)
+	}
+	b.headers[sheetID] = names
+	delete(b.schemas, sheetID)
+	if ok {
+		sdk.Logger(ctx).Info().
+			Int64("sheet_id", sheetID).
+			Strs("old_headers", last).
+			Strs("new_headers", names).
+			Msg("sheet headers changed")
+	}
+}
+
+func (b *BatchReader) sheetIDs() string {
+	ids := make([]string, 0, len(b.ranges))
+	for _, sheetRange := range b.ranges {
+		ids = append(ids, strconv.FormatInt(sheetRange.GridRange.SheetId, 10))
+	}
+	return strings.Join(ids, ",")
+}
+
+func copyOffsets(offsets map[int64]int64) map[int64]int64 {
+	out := make(map[int64]int64, len(offsets))
+	for k, v := range offsets {
+		out[k] = v
+	}
+	return out
+}
@@ -18,12 +18,15 @@ package sheets
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/oauth2"
@@ -33,8 +36,7 @@ import (
 
 func TestNewBatchReader(t *testing.T) {
 	got, err := NewBatchReader(context.Background(), BatchReaderArgs{
-		OAuthToken:           &oauth2.Token{},
-		OAuthConfig:          &oauth2.Config{},
+		TokenSource:          oauth2.StaticTokenSource(&oauth2.Token{}),
 		SpreadsheetID:        "dummy_spreadsheet",
 		SheetID:              1234,
 		DateTimeRenderOption: "SOME_VALUE",
@@ -44,18 +46,19 @@ func TestNewBatchReader(t *testing.T) {
 	assert.NoError(t, err)
 	want := &BatchReader{
 		spreadsheetID:        "dummy_spreadsheet",
-		sheetID:              1234,
+		ranges:               []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		dateTimeRenderOption: "SOME_VALUE",
 		valueRenderOption:    "SOME_OTHER_VALUE",
 		pollingPeriod:        3 * time.Second,
 	}
 	want.sheetSvc = got.sheetSvc
+	want.driveSvc = got.driveSvc
 	assert.Equal(t, want, got)
 }
 
 func TestBatchReader_getDataFilter(t *testing.T) {
 	br := &BatchReader{
-		sheetID:              1234,
+		ranges:               []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		dateTimeRenderOption: "DATE_TIME_OPTION",
 		valueRenderOption:    "VALUE_OPTION",
 	}
@@ -70,7 +73,9 @@ func TestBatchReader_getDataFilter(t *testing.T) {
 		ValueRenderOption:    "VALUE_OPTION",
 	}
 
-	assert.Equal(t, want, br.getDataFilter(10))
+	got, requested := br.getDataFilter(br.ranges, position.SheetPosition{RowOffset: 10, SheetID: 1234}, false)
+	assert.Equal(t, want, got)
+	assert.Equal(t, br.ranges, requested)
 }
 
 func TestBatchReader_valueRangesToRecords(t *testing.T) {
@@ -83,12 +88,12 @@ func TestBatchReader_valueRangesToRecords(t *testing.T) {
 		}}}}
 
 	br := &BatchReader{
-		sheetID:              1234,
+		ranges:               []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		spreadsheetID:        "dummy_spreadsheet",
 		dateTimeRenderOption: "DATE_TIME_OPTION",
 		valueRenderOption:    "VALUE_OPTION",
 	}
-	out, err := br.valueRangesToRecords(in, 10)
+	out, err := br.valueRangesToRecords(context.Background(), in, br.ranges, position.SheetPosition{RowOffset: 10, SheetID: 1234})
 	assert.NoError(t, err)
 	want := []sdk.Record{
 		{
@@ -108,6 +113,123 @@ func TestBatchReader_valueRangesToRecords(t *testing.T) {
 	assert.Equal(t, want, out)
 }
 
+func TestBatchReader_valueRangesToRecords_MultipleSheets(t *testing.T) {
+	in := []*sheets.MatchedValueRange{
+		{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{"jan-1"}, {"jan-2"}}}},
+		{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{"feb-1"}}}},
+	}
+	br := &BatchReader{
+		ranges: []SheetRange{
+			{Title: "Jan", GridRange: &sheets.GridRange{SheetId: 1}},
+			{Title: "Feb", GridRange: &sheets.GridRange{SheetId: 2}},
+		},
+		spreadsheetID: "dummy_spreadsheet",
+	}
+	pos := position.SheetPosition{Offsets: map[int64]int64{1: 5}}
+	out, err := br.valueRangesToRecords(context.Background(), in, br.ranges, pos)
+	assert.NoError(t, err)
+	want := []sdk.Record{
+		{
+			Position: sdk.Position(`{"row_offset":6,"spreadsheet_id":"dummy_spreadsheet","sheet_id":1,"offsets":{"1":6,"2":0}}`),
+			Metadata: map[string]string{
+				MetadataSpreadsheetID: "dummy_spreadsheet", MetadataSheetTitle: "Jan", MetadataSheetID: "1",
+				MetadataRowNumber: "6", MetadataRange: "'Jan'!A6:A6",
+			},
+			Key:     sdk.RawData(`6`),
+			Payload: sdk.RawData(`["jan-1"]`),
+		}, {
+			Position: sdk.Position(`{"row_offset":7,"spreadsheet_id":"dummy_spreadsheet","sheet_id":1,"offsets":{"1":7,"2":0}}`),
+			Metadata: map[string]string{
+				MetadataSpreadsheetID: "dummy_spreadsheet", MetadataSheetTitle: "Jan", MetadataSheetID: "1",
+				MetadataRowNumber: "7", MetadataRange: "'Jan'!A7:A7",
+			},
+			Key:     sdk.RawData(`7`),
+			Payload: sdk.RawData(`["jan-2"]`),
+		}, {
+			Position: sdk.Position(`{"row_offset":1,"spreadsheet_id":"dummy_spreadsheet","sheet_id":2,"offsets":{"1":7,"2":1}}`),
+			Metadata: map[string]string{
+				MetadataSpreadsheetID: "dummy_spreadsheet", MetadataSheetTitle: "Feb", MetadataSheetID: "2",
+				MetadataRowNumber: "1", MetadataRange: "'Feb'!A1:A1",
+			},
+			Key:     sdk.RawData(`1`),
+			Payload: sdk.RawData(`["feb-1"]`),
+		},
+	}
+	for i := range out {
+		out[i].CreatedAt = time.Time{}
+	}
+	assert.Equal(t, want, out)
+}
+
+func TestBatchReader_getDataFilter_HeaderRow(t *testing.T) {
+	br := &BatchReader{
+		ranges:    []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234, StartRowIndex: 2, StartColumnIndex: 1}}},
+		headerRow: 1,
+	}
+	want := []*sheets.DataFilter{
+		{GridRange: &sheets.GridRange{SheetId: 1234, StartRowIndex: 2, EndRowIndex: 3, StartColumnIndex: 1}},
+		{GridRange: &sheets.GridRange{SheetId: 1234, StartRowIndex: 13, StartColumnIndex: 1}},
+	}
+
+	got, requested := br.getDataFilter(br.ranges, position.SheetPosition{RowOffset: 10, SheetID: 1234}, false)
+	assert.Equal(t, want, got.DataFilters)
+	assert.Equal(t, br.ranges, requested)
+}
+
+func TestBatchReader_getDataFilter_Bounded(t *testing.T) {
+	br := &BatchReader{
+		ranges: []SheetRange{
+			{GridRange: &sheets.GridRange{SheetId: 1234}},
+			{GridRange: &sheets.GridRange{SheetId: 5678, StartRowIndex: 1, EndRowIndex: 15}},
+			{GridRange: &sheets.GridRange{SheetId: 9012, EndRowIndex: 5}},
+		},
+		pageSize: 10,
+	}
+	want := []*sheets.DataFilter{
+		{GridRange: &sheets.GridRange{SheetId: 1234, StartRowIndex: 10, EndRowIndex: 20}},
+		{GridRange: &sheets.GridRange{SheetId: 5678, StartRowIndex: 11, EndRowIndex: 15}},
+	}
+
+	pos := position.SheetPosition{Offsets: map[int64]int64{1234: 10, 5678: 10, 9012: 5}}
+	got, requested := br.getDataFilter(br.ranges, pos, true)
+	assert.Equal(t, want, got.DataFilters)
+	assert.Equal(t, br.ranges[:2], requested)
+
+	got, _ = br.getDataFilter(br.ranges, pos, false)
+	assert.Equal(t, int64(0), got.DataFilters[0].GridRange.EndRowIndex)
+}
+
+func TestBatchReader_valueRangesToRecords_HeaderRow(t *testing.T) {
+	in := []*sheets.MatchedValueRange{
+		{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{"id", " name ", "", "id"}}}},
+		{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{"1", "foo", "x", "10", "extra"}, {"2"}}}},
+	}
+	br := &BatchReader{
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
+		spreadsheetID: "dummy_spreadsheet",
+		headerRow:     1,
+	}
+	out, err := br.valueRangesToRecords(context.Background(), in, br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	want := []sdk.Record{
+		{
+			Position: sdk.Position(`{"row_offset":1,"spreadsheet_id":"dummy_spreadsheet","sheet_id":1234}`),
+			Key:      sdk.RawData(`2`),
+			Payload:  sdk.StructuredData{"id": "1", "name": "foo", "C": "x", "id_2": "10", "E": "extra"},
+		}, {
+			Position: sdk.Position(`{"row_offset":2,"spreadsheet_id":"dummy_spreadsheet","sheet_id":1234}`),
+			Key:      sdk.RawData(`3`),
+			Payload:  sdk.StructuredData{"id": "2", "name": "", "C": "", "id_2": ""},
+		},
+	}
+	for i := range out {
+		out[i].CreatedAt = time.Time{}
+		out[i].Metadata = nil
+	}
+	assert.Equal(t, want, out)
+	assert.Equal(t, map[int64][]string{1234: {"id", "name", "C", "id_2"}}, br.headers)
+}
+
 type testHandler struct {
 	t          *testing.T
 	url        *url.URL
@@ -146,12 +268,12 @@ func TestBatchReader_GetSheetRecords_429(t *testing.T) {
 	cursor := &BatchReader{
 		nextRun:       time.Time{},
 		spreadsheetID: "dummy_spreadsheet",
-		sheetID:       1234,
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		sheetSvc:      sheetSvc,
 		pollingPeriod: 10 * time.Second,
 	}
 	ctx := context.Background()
-	recs, err := cursor.GetSheetRecords(ctx, 10)
+	recs, err := cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 10, SheetID: 1234})
 	assert.NoError(t, err)
 	assert.Len(t, recs, 0)
 	assert.GreaterOrEqual(t, cursor.nextRun.Unix(), time.Now().Add(9*time.Second).Unix())
@@ -176,12 +298,12 @@ func TestBatchReader_GetSheetRecords_500(t *testing.T) {
 	cursor := &BatchReader{
 		nextRun:       time.Time{},
 		spreadsheetID: "dummy_spreadsheet",
-		sheetID:       1234,
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		sheetSvc:      sheetSvc,
 		pollingPeriod: 10 * time.Second,
 	}
 	ctx := context.Background()
-	_, err = cursor.GetSheetRecords(ctx, 10)
+	_, err = cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 10, SheetID: 1234})
 	assert.EqualError(t, err, "error getting sheet(gid:1234) values, googleapi: got HTTP response code 500 with body: ")
 }
 
@@ -204,12 +326,104 @@ func TestBatchReader_GetSheetRecords_304(t *testing.T) {
 	cursor := &BatchReader{
 		nextRun:       time.Time{},
 		spreadsheetID: "dummy_spreadsheet",
-		sheetID:       1234,
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
 		sheetSvc:      sheetSvc,
 		pollingPeriod: 10 * time.Second,
 	}
 	ctx := context.Background()
-	recs, err := cursor.GetSheetRecords(ctx, 10)
+	recs, err := cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 10, SheetID: 1234})
 	assert.NoError(t, err)
 	assert.Nil(t, recs)
 }
+
+func TestBatchReader_GetSheetRecords_Pages(t *testing.T) {
+	var requests []*sheets.BatchGetValuesByDataFilterRequest
+	pages := [][][]interface{}{{{"a"}, {"b"}}, {{"c"}}, {}}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &sheets.BatchGetValuesByDataFilterRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		requests = append(requests, req)
+		res := &sheets.BatchGetValuesByDataFilterResponse{ValueRanges: []*sheets.MatchedValueRange{
+			{ValueRange: &sheets.ValueRange{Values: pages[len(requests)-1]}},
+		}}
+		assert.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(
+		context.Background(),
+		option.WithEndpoint(testServer.URL),
+		option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	cursor := &BatchReader{
+		spreadsheetID: "dummy_spreadsheet",
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
+		sheetSvc:      sheetSvc,
+		pollingPeriod: 10 * time.Second,
+		pageSize:      2,
+	}
+	ctx := context.Background()
+
+	recs, err := cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 10, SheetID: 1234})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+	assert.True(t, cursor.HasMore())
+	assert.Equal(t, int64(12), requests[0].DataFilters[0].GridRange.EndRowIndex)
+
+	recs, err = cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 12, SheetID: 1234})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.False(t, cursor.HasMore())
+	assert.Equal(t, int64(14), requests[1].DataFilters[0].GridRange.EndRowIndex)
+
+	// the end of the sheet is reached, the rows are read without a page limit
+	recs, err = cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 13, SheetID: 1234})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.False(t, cursor.HasMore())
+	assert.Equal(t, int64(0), requests[2].DataFilters[0].GridRange.EndRowIndex)
+}
+
+func TestBatchReader_GetSheetRecords_BlankPage(t *testing.T) {
+	var requests []*sheets.BatchGetValuesByDataFilterRequest
+	pages := [][][]interface{}{{{}, {}}, {{"c"}}}
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := &sheets.BatchGetValuesByDataFilterRequest{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(req))
+		requests = append(requests, req)
+		res := &sheets.BatchGetValuesByDataFilterResponse{ValueRanges: []*sheets.MatchedValueRange{
+			{ValueRange: &sheets.ValueRange{Values: pages[len(requests)-1]}},
+		}}
+		assert.NoError(t, json.NewEncoder(w).Encode(res))
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(
+		context.Background(),
+		option.WithEndpoint(testServer.URL),
+		option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	cursor := &BatchReader{
+		spreadsheetID: "dummy_spreadsheet",
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
+		sheetSvc:      sheetSvc,
+		pollingPeriod: 10 * time.Second,
+		pageSize:      2,
+	}
+	ctx := context.Background()
+
+	// the full page of blank rows yields no records, the next page is read after its rows
+	recs, err := cursor.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 10, SheetID: 1234})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 0)
+	assert.True(t, cursor.HasMore())
+	pos := cursor.PagePosition()
+	assert.Equal(t, position.SheetPosition{RowOffset: 12, SpreadsheetID: "dummy_spreadsheet", SheetID: 1234, Phase: position.PhaseCDC}, pos)
+
+	recs, err = cursor.GetSheetRecords(ctx, pos)
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.False(t, cursor.HasMore())
+	assert.Equal(t, int64(12), requests[1].DataFilters[0].GridRange.StartRowIndex)
+	pos, err = position.ParseRecordPosition(recs[0].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(13), pos.RowOffset)
+}
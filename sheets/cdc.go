@@ -0,0 +1,381 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// MetadataOperation is the metadata key for the operation of the record, OperationSnapshot for the rows read
+	// during the snapshot phase, the operation of the row change afterwards
+	MetadataOperation = "google.sheets.operation"
+	// MetadataBefore is the metadata key for the row data before the change, set for updates and deletes
+	// if the previous row values are known
+	MetadataBefore = "google.sheets.before"
+
+	OperationCreate = "create"
+	OperationUpdate = "update"
+	OperationDelete = "delete"
+)
+
+// pendingChange is a row change read in cdc mode, which is not acknowledged yet
+type pendingChange struct {
+	seq int64
+	id  string
+	// state is the state of the row after the change, nil if the row was deleted
+	state *rowState
+}
+
+// valueRangesToChanges diffs the rows read against the snapshot of the rows read by the previous calls,
+// returning a record for each created, updated and deleted row.
+// The rows are identified by the value of the key column, or by the row number if the key column is not set.
+func (b *BatchReader) valueRangesToChanges(
+	ctx context.Context,
+	valueRanges []*sheets.MatchedValueRange,
+	requested []SheetRange,
+	pos position.SheetPosition,
+) ([]sdk.Record, error) {
+	if err := b.loadSnapshot(pos); err != nil {
+		return nil, err
+	}
+
+	records := make([]sdk.Record, 0)
+	seen := make(map[string]bool)
+	readSheets := make(map[int64]SheetRange)
+	headers := make(map[int64][]interface{})
+	recordKeys := make(map[int64]*recordKeys)
+
+	for _, values := range b.readSheets(ctx, valueRanges, requested) {
+		sheetRange, header := values.sheetRange, values.header
+		sheetID := sheetRange.GridRange.SheetId
+		readSheets[sheetID] = sheetRange
+		headers[sheetID] = header
+
+		keyIndex, err := b.keyIndex(sheetRange, header)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := b.recordKeys(sheetRange, header)
+		if err != nil {
+			return nil, err
+		}
+		recordKeys[sheetID] = keys
+
+		var emptyKeys, duplicateKeys int
+		for index, row := range values.rows {
+			if len(row) == 0 {
+				continue
+			}
+			rowNumber := sheetRange.GridRange.StartRowIndex + b.headerRow + int64(index) + 1
+			key := strconv.FormatInt(rowNumber, 10)
+			if keyIndex >= 0 {
+				key = ""
+				if keyIndex < len(row) {
+					key = strings.TrimSpace(fmt.Sprint(row[keyIndex]))
+				}
+				if key == "" {
+					emptyKeys++
+					continue
+				}
+			}
+			id := rowID(sheetID, key)
+			if seen[id] {
+				duplicateKeys++
+				continue
+			}
+			seen[id] = true
+
+			state := rowState{Hash: fingerprint(row), Values: row}
+			prev, ok := b.snapshot[id]
+			if ok && prev.Hash == state.Hash {
+				continue
+			}
+			recordKey, err := b.key(keys, row, key, rowNumber)
+			if err != nil {
+				return records, err
+			}
+			if recordKey == nil {
+				// the row is kept in the snapshot as is, so the change is read once the record key is set
+				continue
+			}
+			operation := OperationCreate
+			if ok {
+				operation = OperationUpdate
+			}
+			record, err := b.changeRecord(sheetRange, header, recordKey, operation, prev.Values, row, rowNumber)
+			if err != nil {
+				return records, err
+			}
+			records = append(records, b.applyChange(record, id, &state))
+		}
+		if emptyKeys > 0 || duplicateKeys > 0 {
+			sdk.Logger(ctx).Warn().
+				Int64("sheet_id", sheetID).
+				Str("key_column", b.keyColumn).
+				Int("empty_keys", emptyKeys).
+				Int("duplicate_keys", duplicateKeys).
+				Msg("skipped rows with an empty or a duplicate key")
+		}
+		keys.logSkipped(ctx, b.keyColumns)
+	}
+
+	// the rows of the sheets read, which are not found anymore, were deleted
+	deleted := make([]string, 0)
+	for id := range b.snapshot {
+		sheetID, _, _ := splitRowID(id)
+		if _, ok := readSheets[sheetID]; ok && !seen[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		sheetID, key, _ := splitRowID(id)
+		// the record key of a deleted row is composed of the values before the delete,
+		// the key of the row in the snapshot is the record key if the values are not known or the record key is empty
+		var recordKey sdk.Data = sdk.RawData(key)
+		if values := b.snapshot[id].Values; values != nil {
+			if k, err := b.key(recordKeys[sheetID], values, key, 0); err == nil && k != nil {
+				recordKey = k
+			}
+		}
+		record, err := b.changeRecord(readSheets[sheetID], headers[sheetID], recordKey, OperationDelete, b.snapshot[id].Values, nil, 0)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, b.applyChange(record, id, nil))
+	}
+	return records, nil
+}
+
+// changeRecord returns the record of the row change, the payload is the row data after the change,
+// or before the change for deletes. The row number is 0 for deletes, as the row is not found anymore.
+// The position is set by applyChange.
+func (b *BatchReader) changeRecord(
+	sheetRange SheetRange,
+	header []interface{},
+	key sdk.Data,
+	operation string,
+	before, after []interface{},
+	rowNumber int64,
+) (sdk.Record, error) {
+	metadata := b.recordMetadata(sheetRange, rowNumber, len(after))
+	metadata[MetadataOperation] = operation
+
+	var payload sdk.Data = sdk.RawData{}
+	if before != nil {
+		beforeData, err := b.rowPayload(sheetRange, header, before)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+		if operation != OperationCreate {
+			metadata[MetadataBefore] = string(beforeData.Bytes())
+		}
+		payload = beforeData
+	}
+	if after != nil {
+		afterData, err := b.rowPayload(sheetRange, header, after)
+		if err != nil {
+			return sdk.Record{}, err
+		}
+		payload = afterData
+	}
+	return sdk.Record{
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+		Key:       key,
+		Payload:   payload,
+	}, nil
+}
+
+// applyChange applies the row change to the snapshot and sets the record position, which holds the sequence number
+// of the change only. The change is kept pending till the record is acknowledged, and then saved to the state file.
+func (b *BatchReader) applyChange(record sdk.Record, id string, state *rowState) sdk.Record {
+	if state != nil {
+		b.snapshot[id] = *state
+	} else {
+		delete(b.snapshot, id)
+	}
+
+	b.seq++
+	sheetID, _, _ := splitRowID(id)
+	pos := position.SheetPosition{
+		SpreadsheetID: b.spreadsheetID,
+		SheetID:       sheetID,
+		Mode:          position.ModeCDC,
+		Seq:           b.seq,
+		Phase:         b.phase,
+		Schemas:       b.schemaVersions,
+	}
+	b.stateMux.Lock()
+	b.pending = append(b.pending, pendingChange{seq: b.seq, id: id, state: state})
+	b.stateMux.Unlock()
+	record.Position = pos.RecordPosition()
+	return record
+}
+
+// loadSnapshot loads the snapshot of the rows from the state file on the first read
+func (b *BatchReader) loadSnapshot(pos position.SheetPosition) error {
+	if b.snapshot != nil {
+		return nil
+	}
+	b.snapshot = make(map[string]rowState)
+	if pos.Mode == position.ModeCDC {
+		b.seq = pos.Seq
+	}
+
+	content, err := b.stateFile.Load(b.spreadsheetID)
+	if err != nil {
+		return err
+	}
+	for id, state := range content.Rows {
+		b.snapshot[id] = state
+	}
+	if content.Seq > b.seq {
+		b.seq = content.Seq
+	}
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	b.acked = content.Rows
+	b.ackedSeq = content.Seq
+	b.savedSeq = content.Seq
+	return nil
+}
+
+// Ack applies the acknowledged changes to the state stored in the state file. The state file is saved
+// once all the changes read are acknowledged, so that the file is written once per read at most.
+func (b *BatchReader) Ack(pos position.SheetPosition) error {
+	if b.stateFile == nil || pos.Mode != position.ModeCDC {
+		return nil
+	}
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+
+	n := 0
+	for _, change := range b.pending {
+		if change.seq > pos.Seq {
+			break
+		}
+		if change.state != nil {
+			b.acked[change.id] = *change.state
+		} else {
+			delete(b.acked, change.id)
+		}
+		b.ackedSeq = change.seq
+		n++
+	}
+	b.pending = b.pending[n:]
+	if n == 0 || len(b.pending) > 0 {
+		return nil
+	}
+	return b.saveState()
+}
+
+// Close saves the acknowledged changes not saved yet to the state file
+func (b *BatchReader) Close() error {
+	if b.stateFile == nil {
+		return nil
+	}
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	return b.saveState()
+}
+
+// saveState saves the acknowledged state to the state file, if changed since last saved. stateMux must be held.
+func (b *BatchReader) saveState() error {
+	if b.acked == nil || b.ackedSeq == b.savedSeq {
+		return nil
+	}
+	err := b.stateFile.Save(stateFileContent{
+		SpreadsheetID: b.spreadsheetID,
+		Seq:           b.ackedSeq,
+		Rows:          b.acked,
+	})
+	if err != nil {
+		return err
+	}
+	b.savedSeq = b.ackedSeq
+	return nil
+}
+
+// keyIndex returns the index of the key column in the rows read from the sheet, -1 if the key column is not set.
+// The key column is a header name if the header row is set, the column letters otherwise.
+func (b *BatchReader) keyIndex(sheetRange SheetRange, header []interface{}) (int, error) {
+	if b.keyColumn == "" {
+		return -1, nil
+	}
+	return b.columnIndex(sheetRange, header, b.keyColumn, "key column")
+}
+
+// columnIndex returns the index of the column in the rows read from the sheet, the column is a header name
+// if the header row is set, the column letters otherwise. The kind of column is used in the error messages.
+func (b *BatchReader) columnIndex(sheetRange SheetRange, header []interface{}, column, kind string) (int, error) {
+	gridRange := sheetRange.GridRange
+	if b.headerRow > 0 {
+		for i, name := range headerNames(header, gridRange.StartColumnIndex, len(header)) {
+			if name == column {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%s %q not found in the header row of sheet(gid:%d)", kind, column, gridRange.SheetId)
+	}
+	index := ColumnIndex(column)
+	if index < gridRange.StartColumnIndex || (gridRange.EndColumnIndex > 0 && index >= gridRange.EndColumnIndex) {
+		return 0, fmt.Errorf("%s %q is outside the range read from sheet(gid:%d)", kind, column, gridRange.SheetId)
+	}
+	return int(index - gridRange.StartColumnIndex), nil
+}
+
+// fingerprint returns the hash of the row values
+func fingerprint(row []interface{}) string {
+	data, err := json.Marshal(row)
+	if err != nil {
+		data = []byte(fmt.Sprint(row))
+	}
+	h := fnv.New64a()
+	_, _ = h.Write(data)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// rowID returns the ID of the row in the snapshot, <sheet gid>:<row key>
+func rowID(sheetID int64, key string) string {
+	return strconv.FormatInt(sheetID, 10) + ":" + key
+}
+
+func splitRowID(id string) (int64, string, error) {
+	parts := strings.SplitN(id, ":", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("invalid row id %q", id)
+	}
+	sheetID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid row id %q: %w", id, err)
+	}
+	return sheetID, parts[1], nil
+}
@@ -0,0 +1,188 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+func valueRanges(rows ...[][]interface{}) []*sheets.MatchedValueRange {
+	out := make([]*sheets.MatchedValueRange, 0, len(rows))
+	for _, values := range rows {
+		out = append(out, &sheets.MatchedValueRange{ValueRange: &sheets.ValueRange{Values: values}})
+	}
+	return out
+}
+
+func TestBatchReader_valueRangesToChanges(t *testing.T) {
+	ctx := context.Background()
+	br := &BatchReader{
+		ranges:        []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 7}}},
+		spreadsheetID: "dummy_spreadsheet",
+		headerRow:     1,
+		cdc:           true,
+		keyColumn:     "id",
+		stateFile:     &StateFile{Path: filepath.Join(t.TempDir(), "state.json")},
+	}
+	header := [][]interface{}{{"id", "status"}}
+
+	out, err := br.valueRangesToChanges(ctx, valueRanges(header, [][]interface{}{{"1", "open"}, {"2", "open"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.Equal(t, OperationCreate, out[0].Metadata[MetadataOperation])
+	assert.Equal(t, sdk.RawData("1"), out[0].Key)
+	assert.Equal(t, sdk.StructuredData{"id": "1", "status": "open"}, out[0].Payload)
+	assert.NotContains(t, out[0].Metadata, MetadataBefore)
+
+	// unchanged rows are not returned
+	out, err = br.valueRangesToChanges(ctx, valueRanges(header, [][]interface{}{{"1", "open"}, {"2", "open"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 0)
+
+	// row 1 is updated and moved down, row 2 is deleted and row 3 is created
+	out, err = br.valueRangesToChanges(ctx, valueRanges(header, [][]interface{}{{"3", "open"}, {}, {"1", "closed"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+
+	assert.Equal(t, OperationCreate, out[0].Metadata[MetadataOperation])
+	assert.Equal(t, sdk.RawData("3"), out[0].Key)
+
+	assert.Equal(t, OperationUpdate, out[1].Metadata[MetadataOperation])
+	assert.Equal(t, sdk.RawData("1"), out[1].Key)
+	assert.Equal(t, sdk.StructuredData{"id": "1", "status": "closed"}, out[1].Payload)
+	assert.JSONEq(t, `{"id":"1","status":"open"}`, out[1].Metadata[MetadataBefore])
+
+	assert.Equal(t, OperationDelete, out[2].Metadata[MetadataOperation])
+	assert.Equal(t, sdk.RawData("2"), out[2].Key)
+	assert.Equal(t, sdk.StructuredData{"id": "2", "status": "open"}, out[2].Payload)
+	assert.JSONEq(t, `{"id":"2","status":"open"}`, out[2].Metadata[MetadataBefore])
+
+	pos, err := position.ParseRecordPosition(out[2].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, position.ModeCDC, pos.Mode)
+	assert.Equal(t, int64(5), pos.Seq)
+	assert.Equal(t, map[string]rowState{
+		"7:1": {Hash: fingerprint([]interface{}{"1", "closed"}), Values: []interface{}{"1", "closed"}},
+		"7:3": {Hash: fingerprint([]interface{}{"3", "open"}), Values: []interface{}{"3", "open"}},
+	}, br.snapshot)
+}
+
+func TestBatchReader_valueRangesToChanges_FromPosition(t *testing.T) {
+	stateFile := &StateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	assert.NoError(t, stateFile.Save(stateFileContent{
+		SpreadsheetID: "dummy_spreadsheet",
+		Seq:           10,
+		Rows: map[string]rowState{
+			"7:1": {Hash: fingerprint([]interface{}{"a"}), Values: []interface{}{"a"}},
+			"7:2": {Hash: fingerprint([]interface{}{"b"}), Values: []interface{}{"b"}},
+		},
+	}))
+	br := &BatchReader{
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 7}}},
+		spreadsheetID: "dummy_spreadsheet",
+		cdc:           true,
+		stateFile:     stateFile,
+	}
+	pos := position.SheetPosition{Mode: position.ModeCDC, Seq: 10}
+	out, err := br.valueRangesToChanges(context.Background(), valueRanges([][]interface{}{{"a"}, {"c"}}), br.ranges, pos)
+	assert.NoError(t, err)
+	assert.Len(t, out, 1)
+	assert.Equal(t, OperationUpdate, out[0].Metadata[MetadataOperation])
+	assert.Equal(t, sdk.RawData("2"), out[0].Key)
+	assert.Equal(t, sdk.RawData(`["c"]`), out[0].Payload)
+	assert.Equal(t, `["b"]`, out[0].Metadata[MetadataBefore])
+
+	got, err := position.ParseRecordPosition(out[0].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), got.Seq)
+}
+
+func TestBatchReader_Ack_StateFile(t *testing.T) {
+	ctx := context.Background()
+	stateFile := &StateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	newReader := func() *BatchReader {
+		return &BatchReader{
+			ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 7}}},
+			spreadsheetID: "dummy_spreadsheet",
+			cdc:           true,
+			keyColumn:     "A",
+			stateFile:     stateFile,
+		}
+	}
+
+	br := newReader()
+	out, err := br.valueRangesToChanges(ctx, valueRanges([][]interface{}{{"k1", "a"}, {"k2", "b"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	pos, err := position.ParseRecordPosition(out[0].Position)
+	assert.NoError(t, err)
+
+	// only the first change is acknowledged, the state file isn't saved till all the changes are acknowledged
+	assert.NoError(t, br.Ack(pos))
+	_, err = stateFile.Load("dummy_spreadsheet")
+	assert.NoError(t, err)
+	assert.NoFileExists(t, stateFile.Path)
+
+	// the acknowledged changes are saved on close
+	assert.NoError(t, br.Close())
+	content, err := stateFile.Load("dummy_spreadsheet")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), content.Seq)
+	assert.Equal(t, map[string]rowState{"7:k1": {Hash: fingerprint([]interface{}{"k1", "a"}), Values: []interface{}{"k1", "a"}}}, content.Rows)
+
+	// a new reader restores the state from the state file, the unacknowledged row is read again
+	br = newReader()
+	out, err = br.valueRangesToChanges(ctx, valueRanges([][]interface{}{{"k1", "a2"}, {"k2", "b"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 2)
+	assert.Equal(t, OperationUpdate, out[0].Metadata[MetadataOperation])
+	assert.Equal(t, `["k1","a"]`, out[0].Metadata[MetadataBefore])
+	assert.Equal(t, OperationCreate, out[1].Metadata[MetadataOperation])
+
+	last, err := position.ParseRecordPosition(out[1].Position)
+	assert.NoError(t, err)
+	assert.NoError(t, br.Ack(last))
+	content, err = stateFile.Load("dummy_spreadsheet")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), content.Seq)
+	assert.Len(t, content.Rows, 2)
+
+	_, err = stateFile.Load("other_spreadsheet")
+	assert.EqualError(t, err, "state file "+stateFile.Path+" belongs to spreadsheet(dummy_spreadsheet), expected spreadsheet(other_spreadsheet)")
+}
+
+func TestBatchReader_keyIndex(t *testing.T) {
+	br := &BatchReader{keyColumn: "C"}
+	index, err := br.keyIndex(SheetRange{GridRange: &sheets.GridRange{SheetId: 7, StartColumnIndex: 1, EndColumnIndex: 4}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+
+	_, err = br.keyIndex(SheetRange{GridRange: &sheets.GridRange{SheetId: 7, StartColumnIndex: 3}}, nil)
+	assert.EqualError(t, err, `key column "C" is outside the range read from sheet(gid:7)`)
+
+	br = &BatchReader{keyColumn: "email", headerRow: 1}
+	_, err = br.keyIndex(SheetRange{GridRange: &sheets.GridRange{SheetId: 7}}, []interface{}{"id", "name"})
+	assert.EqualError(t, err, `key column "email" not found in the header row of sheet(gid:7)`)
+}
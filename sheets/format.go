@@ -0,0 +1,245 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// The payload formats of the records
+const (
+	// FormatJSONArray is the format of the payloads holding the JSON array of the row values
+	FormatJSONArray = "jsonArray"
+	// FormatJSONObject is the format of the payloads holding the JSON object of the row values keyed by the column names
+	FormatJSONObject = "jsonObject"
+	// FormatCSV is the format of the payloads holding the row values as a CSV line
+	FormatCSV = "csv"
+	// FormatStructured is the format of the structured data payloads keyed by the column names
+	FormatStructured = "structured"
+)
+
+// Format is the format of the record payloads, shared by the source and the destination
+// so that the records read from a sheet are written back the same way
+type Format struct {
+	// Type is one of the Format* constants. If empty, the source reads structured data with a header row
+	// and JSON arrays otherwise, and the destination detects the format of every payload.
+	Type string
+	// Delimiter separates the fields of the CSV lines, ',' if 0
+	Delimiter rune
+	// QuoteAll quotes all the fields of the CSV lines, the fields are quoted only if needed otherwise
+	QuoteAll bool
+}
+
+const (
+	// KeyFormat is the config name for the format of the record payloads, shared by the source and the destination
+	KeyFormat = "format"
+
+	// KeyCSVDelimiter is the config name for the delimiter of the fields of the CSV payloads
+	KeyCSVDelimiter = "csvDelimiter"
+
+	// KeyCSVQuote is the config name for the quoting of the fields of the CSV payloads, CSVQuoteMinimal or CSVQuoteAll
+	KeyCSVQuote = "csvQuote"
+)
+
+const (
+	// CSVQuoteMinimal quotes the CSV fields holding the delimiter, a quote, a line break or a leading space
+	CSVQuoteMinimal = "minimal"
+	// CSVQuoteAll quotes all the CSV fields
+	CSVQuoteAll = "all"
+)
+
+// ParseFormat parses the format of the record payloads, the CSV options can only be set in the csv format.
+// The format type is empty if not set, the connectors then use their default formats.
+func ParseFormat(config map[string]string) (Format, error) {
+	format := Format{Type: strings.TrimSpace(config[KeyFormat])}
+	switch format.Type {
+	case "", FormatJSONArray, FormatJSONObject, FormatStructured:
+	case FormatCSV:
+		format.Delimiter = ','
+	default:
+		return Format{}, fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `%s`, `%s`, `%s`, `%s`",
+			format.Type, KeyFormat, FormatJSONArray, FormatJSONObject, FormatCSV, FormatStructured,
+		)
+	}
+
+	delimiter, quote := config[KeyCSVDelimiter], strings.TrimSpace(config[KeyCSVQuote])
+	if format.Type != FormatCSV {
+		if delimiter != "" || quote != "" {
+			return Format{}, fmt.Errorf("%q and %q config values can only be set, if %q is %q",
+				KeyCSVDelimiter, KeyCSVQuote, KeyFormat, FormatCSV)
+		}
+		return format, nil
+	}
+
+	if delimiter != "" {
+		r, size := utf8.DecodeRuneInString(delimiter)
+		if size != len(delimiter) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return Format{}, fmt.Errorf("%q config value must be a single character other than a quote or a line break, got: %q",
+				KeyCSVDelimiter, delimiter)
+		}
+		format.Delimiter = r
+	}
+	switch quote {
+	case "", CSVQuoteMinimal:
+	case CSVQuoteAll:
+		format.QuoteAll = true
+	default:
+		return Format{}, fmt.Errorf(
+			"invalid value (%s) for `%s` config received, valid values: `%s`, `%s`",
+			quote, KeyCSVQuote, CSVQuoteMinimal, CSVQuoteAll,
+		)
+	}
+	return format, nil
+}
+
+// Encode returns the payload of the row values, the names are the column names of the values
+func (f Format) Encode(names []string, row []interface{}) (sdk.Data, error) {
+	switch f.Type {
+	case FormatStructured:
+		return sdk.StructuredData(rowToMap(names, row)), nil
+	case FormatJSONObject:
+		data, err := json.Marshal(rowToMap(names, row))
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling the map: %w", err)
+		}
+		return sdk.RawData(data), nil
+	case FormatCSV:
+		return sdk.RawData(f.encodeCSV(row)), nil
+	default:
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, fmt.Errorf("error marshaling the row: %w", err)
+		}
+		return sdk.RawData(data), nil
+	}
+}
+
+// Decode parses the payload, returning the row values of JSON array and CSV payloads,
+// or the fields of structured and JSON object payloads
+func (f Format) Decode(payload sdk.Data) ([]interface{}, map[string]interface{}, error) {
+	switch f.Type {
+	case "":
+		return parsePayload(payload)
+	case FormatStructured:
+		structured, ok := payload.(sdk.StructuredData)
+		if !ok {
+			return nil, nil, fmt.Errorf("payload must be structured data, got: %T", payload)
+		}
+		return nil, structured, nil
+	case FormatCSV:
+		row, err := f.decodeCSV(payload.Bytes())
+		return row, nil, err
+	}
+
+	row, fields, err := parsePayload(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.Type == FormatJSONObject && fields == nil {
+		return nil, nil, fmt.Errorf("payload must be a JSON object")
+	}
+	if f.Type == FormatJSONArray && row == nil {
+		return nil, nil, fmt.Errorf("payload must be a JSON array")
+	}
+	return row, fields, nil
+}
+
+func (f Format) delimiter() rune {
+	if f.Delimiter == 0 {
+		return ','
+	}
+	return f.Delimiter
+}
+
+// encodeCSV returns the CSV line of the row values without the line terminator
+func (f Format) encodeCSV(row []interface{}) []byte {
+	delimiter := string(f.delimiter())
+	var sb strings.Builder
+	for i, value := range row {
+		if i > 0 {
+			sb.WriteString(delimiter)
+		}
+		field := csvField(value)
+		if f.QuoteAll || needsQuotes(field, delimiter) {
+			sb.WriteString(`"` + strings.ReplaceAll(field, `"`, `""`) + `"`)
+			continue
+		}
+		sb.WriteString(field)
+	}
+	return []byte(sb.String())
+}
+
+// decodeCSV parses the CSV line of the row values, the values are strings
+func (f Format) decodeCSV(data []byte) ([]interface{}, error) {
+	reader := csv.NewReader(strings.NewReader(string(data)))
+	reader.Comma = f.delimiter()
+	reader.FieldsPerRecord = -1
+	fields, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		// a blank line is a row without values
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the CSV payload: %w", err)
+	}
+	row := make([]interface{}, len(fields))
+	for i, field := range fields {
+		row[i] = field
+	}
+	return row, nil
+}
+
+// csvField returns the text of the value, nested values are written as JSON
+func csvField(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return fmt.Sprint(cellValue(v))
+	}
+}
+
+// needsQuotes reports whether the field must be quoted to be parsed back, the same rules as encoding/csv
+func needsQuotes(field, delimiter string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` || strings.Contains(field, delimiter) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
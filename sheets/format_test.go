@@ -0,0 +1,203 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+// TestFormat_RoundTrip reads a row with the source payload encoding, and parses the payload with the destination
+// payload decoding, the row written is expected to be the row read for every format
+func TestFormat_RoundTrip(t *testing.T) {
+	header := []interface{}{"id", "name", "note", "blank"}
+	row := []interface{}{"1", "Widget, large", `say "hi"`, ""}
+	sheetRange := SheetRange{GridRange: &sheets.GridRange{SheetId: 1}}
+
+	testCases := []struct {
+		name    string
+		format  Format
+		payload sdk.Data
+	}{
+		{
+			name:    "JSON array",
+			format:  Format{Type: FormatJSONArray},
+			payload: sdk.RawData(`["1","Widget, large","say \"hi\"",""]`),
+		},
+		{
+			name:    "JSON object",
+			format:  Format{Type: FormatJSONObject},
+			payload: sdk.RawData(`{"blank":"","id":"1","name":"Widget, large","note":"say \"hi\""}`),
+		},
+		{
+			name:    "CSV",
+			format:  Format{Type: FormatCSV, Delimiter: ','},
+			payload: sdk.RawData(`1,"Widget, large","say ""hi""",`),
+		},
+		{
+			name:    "CSV with delimiter and all fields quoted",
+			format:  Format{Type: FormatCSV, Delimiter: ';', QuoteAll: true},
+			payload: sdk.RawData(`"1";"Widget, large";"say ""hi""";""`),
+		},
+		{
+			name:    "structured data",
+			format:  Format{Type: FormatStructured},
+			payload: sdk.StructuredData{"id": "1", "name": "Widget, large", "note": `say "hi"`, "blank": ""},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			br := &BatchReader{headerRow: 1, format: tc.format}
+			payload, err := br.rowPayload(sheetRange, header, row)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.payload, payload)
+
+			w := &Writer{headerRow: 1, format: tc.format}
+			rows, fields, err := w.parseRecords([]sdk.Record{{Payload: payload}}, false)
+			assert.NoError(t, err)
+			if hasFields(fields) {
+				w.mapFields(context.Background(), rows, fields, []string{"id", "name", "note", "blank"})
+			}
+			assert.Equal(t, [][]interface{}{row}, rows)
+		})
+	}
+}
+
+func TestFormat_Decode(t *testing.T) {
+	testCases := []struct {
+		name    string
+		format  Format
+		payload sdk.Data
+		row     []interface{}
+		fields  map[string]interface{}
+		err     error
+	}{
+		{
+			name:    "detected JSON object",
+			payload: sdk.RawData(`{"id":1}`),
+			fields:  map[string]interface{}{"id": float64(1)},
+		},
+		{
+			name:    "JSON object in JSON array format",
+			format:  Format{Type: FormatJSONArray},
+			payload: sdk.RawData(`{"id":1}`),
+			err:     fmt.Errorf("payload must be a JSON array"),
+		},
+		{
+			name:    "JSON array in JSON object format",
+			format:  Format{Type: FormatJSONObject},
+			payload: sdk.RawData(`[1]`),
+			err:     fmt.Errorf("payload must be a JSON object"),
+		},
+		{
+			name:    "raw data in structured format",
+			format:  Format{Type: FormatStructured},
+			payload: sdk.RawData(`{"id":1}`),
+			err:     fmt.Errorf("payload must be structured data, got: sdk.RawData"),
+		},
+		{
+			name:    "CSV with multi-line field",
+			format:  Format{Type: FormatCSV},
+			payload: sdk.RawData("a,\"line 1\nline 2\""),
+			row:     []interface{}{"a", "line 1\nline 2"},
+		},
+		{
+			name:    "invalid CSV",
+			format:  Format{Type: FormatCSV},
+			payload: sdk.RawData(`a,"b`),
+			err:     fmt.Errorf("error parsing the CSV payload: parse error on line 1, column 5: extraneous or missing \" in quoted-field"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			row, fields, err := tc.format.Decode(tc.payload)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.row, row)
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	testCases := []struct {
+		name   string
+		params map[string]string
+		want   Format
+		err    error
+	}{
+		{
+			name:   "default",
+			params: map[string]string{},
+			want:   Format{},
+		},
+		{
+			name:   "JSON object",
+			params: map[string]string{KeyFormat: "jsonObject"},
+			want:   Format{Type: FormatJSONObject},
+		},
+		{
+			name:   "CSV with default options",
+			params: map[string]string{KeyFormat: "csv"},
+			want:   Format{Type: FormatCSV, Delimiter: ','},
+		},
+		{
+			name:   "CSV with options",
+			params: map[string]string{KeyFormat: "csv", KeyCSVDelimiter: ";", KeyCSVQuote: "all"},
+			want:   Format{Type: FormatCSV, Delimiter: ';', QuoteAll: true},
+		},
+		{
+			name:   "invalid format",
+			params: map[string]string{KeyFormat: "xml"},
+			err:    fmt.Errorf("invalid value (xml) for `format` config received, valid values: `jsonArray`, `jsonObject`, `csv`, `structured`"),
+		},
+		{
+			name:   "CSV options without CSV format",
+			params: map[string]string{KeyFormat: "jsonArray", KeyCSVDelimiter: ";"},
+			err:    fmt.Errorf("\"csvDelimiter\" and \"csvQuote\" config values can only be set, if \"format\" is \"csv\""),
+		},
+		{
+			name:   "invalid delimiter",
+			params: map[string]string{KeyFormat: "csv", KeyCSVDelimiter: "||"},
+			err:    fmt.Errorf("\"csvDelimiter\" config value must be a single character other than a quote or a line break, got: \"||\""),
+		},
+		{
+			name:   "invalid quote",
+			params: map[string]string{KeyFormat: "csv", KeyCSVQuote: "none"},
+			err:    fmt.Errorf("invalid value (none) for `csvQuote` config received, valid values: `minimal`, `all`"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseFormat(tc.params)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// headerNames returns the field names of the columns read from a sheet, given the values of the header row.
+// The names are resolved deterministically, from the leftmost column to the rightmost column:
+//   - the header cell value is trimmed of the leading and trailing spaces
+//   - blank headers, and columns to the right of the last header, are named by the column letters. e.g. C
+//   - a name already used by a column on the left is suffixed by _2, _3 and so on. e.g. name, name_2
+//
+// startColumn is the zero based index of the first column read, width is the number of columns to be named.
+func headerNames(header []interface{}, startColumn int64, width int) []string {
+	if len(header) > width {
+		width = len(header)
+	}
+
+	names := make([]string, width)
+	used := make(map[string]bool, width)
+	for i := 0; i < width; i++ {
+		name := ""
+		if i < len(header) && header[i] != nil {
+			name = strings.TrimSpace(fmt.Sprint(header[i]))
+		}
+		if name == "" {
+			name = ColumnLetters(startColumn + int64(i))
+		}
+		unique := name
+		for n := 2; used[unique]; n++ {
+			unique = name + "_" + strconv.Itoa(n)
+		}
+		used[unique] = true
+		names[i] = unique
+	}
+	return names
+}
+
+// rowToMap maps the row values to the header names, the cells omitted by the API at the end of the row are set to "",
+// the same as the empty cells in the middle of the row.
+func rowToMap(names []string, row []interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(names))
+	for i, name := range names {
+		if i < len(row) {
+			out[name] = row[i]
+			continue
+		}
+		out[name] = ""
+	}
+	return out
+}
+
+// sameHeaders reports whether the header names are equal
+func sameHeaders(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderNames(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      []interface{}
+		startColumn int64
+		width       int
+		want        []string
+	}{{
+		name:   "plain headers",
+		header: []interface{}{"id", "name"},
+		width:  2,
+		want:   []string{"id", "name"},
+	}, {
+		name:        "blank headers and extra columns",
+		header:      []interface{}{"id", "  ", nil},
+		startColumn: 1,
+		width:       4,
+		want:        []string{"id", "C", "D", "E"},
+	}, {
+		name:   "duplicate headers",
+		header: []interface{}{"id", "id", "id_2", "id"},
+		width:  4,
+		want:   []string{"id", "id_2", "id_2_2", "id_3"},
+	}, {
+		name:   "blank header colliding with a column letter header",
+		header: []interface{}{"B", ""},
+		width:  2,
+		want:   []string{"B", "B_2"},
+	}, {
+		name:   "no header row",
+		header: nil,
+		width:  2,
+		want:   []string{"A", "B"},
+	}, {
+		name:   "non string headers",
+		header: []interface{}{float64(2022), true},
+		width:  1,
+		want:   []string{"2022", "true"},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, headerNames(tt.header, tt.startColumn, tt.width))
+		})
+	}
+}
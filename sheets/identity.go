@@ -0,0 +1,151 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+// valueRangesToHashed returns the records of the rows not read yet, identified by the hash of their values. The ids
+// of the rows read, <sheet gid>:<hash>/<occurrence>, are stored in the state file once the records are acknowledged,
+// so the rows inserted above the rows read are read, and the rows read are not read again once rows are inserted,
+// deleted or moved. The rows read but not acknowledged before a restart are read again. A row whose values are
+// updated after it is read is read again, as a new row. The sheet is not written to.
+func (b *BatchReader) valueRangesToHashed(
+	ctx context.Context,
+	valueRanges []*sheets.MatchedValueRange,
+	requested []SheetRange,
+	pos position.SheetPosition,
+) ([]sdk.Record, error) {
+	if err := b.loadSnapshot(pos); err != nil {
+		return nil, err
+	}
+
+	records := make([]sdk.Record, 0)
+	readSheets := make(map[int64]bool)
+	seen := make(map[string]bool)
+	numbers := make(map[string]int64)
+	for _, values := range b.readSheets(ctx, valueRanges, requested) {
+		sheetRange, header := values.sheetRange, values.header
+		sheetID := sheetRange.GridRange.SheetId
+		readSheets[sheetID] = true
+
+		keys, err := b.recordKeys(sheetRange, header)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastRead int64
+		var inserted int
+		occurrences := make(map[string]int)
+		for j, rowValue := range values.rows {
+			if len(rowValue) == 0 {
+				continue
+			}
+			number := sheetRange.GridRange.StartRowIndex + b.headerRow + int64(j) + 1
+			hash := fingerprint(rowValue)
+			id := rowID(sheetID, hash+"/"+strconv.Itoa(occurrences[hash]))
+			occurrences[hash]++
+			seen[id] = true
+			numbers[id] = number
+			if _, ok := b.snapshot[id]; ok {
+				lastRead = number
+				continue
+			}
+
+			key, err := b.key(keys, rowValue, strconv.FormatInt(number, 10), number)
+			if err != nil {
+				return records, err
+			}
+			if key == nil {
+				// the row is not stored as read, so it is read once its key is set
+				continue
+			}
+			if number < lastRead {
+				inserted++
+			}
+			payload, err := b.rowPayload(sheetRange, header, rowValue)
+			if err != nil {
+				return records, err
+			}
+			records = append(records, b.applyChange(sdk.Record{
+				Metadata:  b.recordMetadata(sheetRange, number, len(rowValue)),
+				CreatedAt: time.Now(),
+				Key:       key,
+				Payload:   payload,
+			}, id, &rowState{Hash: hash}))
+		}
+		b.logReconciled(ctx, sheetID, inserted, numbers)
+		keys.logSkipped(ctx, b.keyColumns)
+	}
+
+	// the rows of the sheets read, which are not found anymore, were deleted or updated
+	deleted := make(map[int64]int)
+	for id := range b.snapshot {
+		sheetID, _, _ := splitRowID(id)
+		if readSheets[sheetID] && !seen[id] {
+			b.forget(id)
+			deleted[sheetID]++
+		}
+	}
+	for sheetID, n := range deleted {
+		sdk.Logger(ctx).Info().
+			Int64("sheet_id", sheetID).
+			Int("deleted_rows", n).
+			Msg("reconciled the rows read with the rows deleted or updated since the previous read")
+	}
+	b.rowNumbers = numbers
+	return records, nil
+}
+
+// forget removes the row from the rows read, the removal is saved to the state file along with the next change
+// acknowledged
+func (b *BatchReader) forget(id string) {
+	delete(b.snapshot, id)
+	b.stateMux.Lock()
+	defer b.stateMux.Unlock()
+	b.pending = append(b.pending, pendingChange{seq: b.seq, id: id})
+}
+
+// logReconciled logs the structural edits made to the sheet since the previous read: the rows inserted above the rows
+// read, and the rows read which were moved
+func (b *BatchReader) logReconciled(ctx context.Context, sheetID int64, inserted int, numbers map[string]int64) {
+	var moved int
+	for id, number := range numbers {
+		if idSheet, _, _ := splitRowID(id); idSheet != sheetID {
+			continue
+		}
+		if previous, ok := b.rowNumbers[id]; ok && previous != number {
+			moved++
+		}
+	}
+	if inserted == 0 && moved == 0 {
+		return
+	}
+	sdk.Logger(ctx).Info().
+		Int64("sheet_id", sheetID).
+		Int("inserted_rows", inserted).
+		Int("moved_rows", moved).
+		Msg("reconciled the rows read with the rows inserted or moved since the previous read")
+}
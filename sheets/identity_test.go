@@ -0,0 +1,112 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// testHashedSheet serves the rows of a sheet, any other request fails the test, as the sheet is not written to
+type testHashedSheet struct {
+	t    *testing.T
+	rows [][]interface{}
+}
+
+func (s *testHashedSheet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v4/spreadsheets/dummy_spreadsheet/values:batchGetByDataFilter" {
+		s.t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	assert.NoError(s.t, json.NewEncoder(w).Encode(&sheets.BatchGetValuesByDataFilterResponse{
+		ValueRanges: []*sheets.MatchedValueRange{{ValueRange: &sheets.ValueRange{Values: s.rows}}},
+	}))
+}
+
+func TestBatchReader_GetSheetRecords_HashRows(t *testing.T) {
+	sheet := &testHashedSheet{t: t, rows: [][]interface{}{{"a"}, {"b"}}}
+	testServer := httptest.NewServer(sheet)
+	defer testServer.Close()
+
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	stateFile := &StateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	newReader := func() *BatchReader {
+		return &BatchReader{
+			spreadsheetID: "dummy_spreadsheet",
+			ranges:        []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 1234}}},
+			sheetSvc:      sheetSvc,
+			pollingPeriod: 10 * time.Second,
+			stateFile:     stateFile,
+			hashRows:      true,
+		}
+	}
+	read := func(br *BatchReader, pos position.SheetPosition) ([]sdk.Data, position.SheetPosition) {
+		records, err := br.GetSheetRecords(context.Background(), pos)
+		assert.NoError(t, err)
+		var payloads []sdk.Data
+		for _, record := range records {
+			payloads = append(payloads, record.Payload)
+			pos, err = position.ParseRecordPosition(record.Position)
+			assert.NoError(t, err)
+		}
+		return payloads, pos
+	}
+
+	br := newReader()
+	payloads, pos := read(br, position.SheetPosition{})
+	assert.Equal(t, []sdk.Data{sdk.RawData(`["a"]`), sdk.RawData(`["b"]`)}, payloads)
+	assert.NoError(t, br.Ack(pos))
+	content, err := stateFile.Load("dummy_spreadsheet")
+	assert.NoError(t, err)
+	assert.Len(t, content.Rows, 2)
+
+	// a row inserted above the rows read is read, the rows read are not read again
+	sheet.rows = [][]interface{}{{"c"}, {"a"}, {"b"}}
+	payloads, pos = read(br, pos)
+	assert.Equal(t, []sdk.Data{sdk.RawData(`["c"]`)}, payloads)
+	assert.NoError(t, br.Ack(pos))
+	acked := pos
+
+	// a row read is deleted, the row appended and the second row with the values of a row read are read
+	sheet.rows = [][]interface{}{{"c"}, {"b"}, {"d"}, {"b"}}
+	payloads, _ = read(br, pos)
+	assert.Equal(t, []sdk.Data{sdk.RawData(`["d"]`), sdk.RawData(`["b"]`)}, payloads)
+
+	// the rows not acknowledged are read again after a restart, the rows acknowledged are not
+	payloads, pos = read(newReader(), acked)
+	assert.Equal(t, []sdk.Data{sdk.RawData(`["d"]`), sdk.RawData(`["b"]`)}, payloads)
+	assert.Equal(t, position.ModeCDC, pos.Mode)
+
+	// the rows are moved by a sort, no row is read
+	sheet.rows = [][]interface{}{{"b"}, {"b"}, {"c"}, {"d"}}
+	payloads, _ = read(br, pos)
+	assert.Empty(t, payloads)
+}
@@ -0,0 +1,187 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+// cursorRow is a row read with the value of the incremental column
+type cursorRow struct {
+	number int64
+	cursor string
+	values []interface{}
+}
+
+// valueRangesToIncremental returns the records of the rows whose value of the incremental column is not less than
+// the cursor of the sheet, received in the position. The records are sorted by the value of the incremental column,
+// so the cursor of every record is the greatest value read up to the record. The rows with an empty value are skipped,
+// as are the rows with a value equal to the cursor which were read with the cursor, told apart by the record key,
+// or by the row values without key columns.
+func (b *BatchReader) valueRangesToIncremental(
+	ctx context.Context,
+	valueRanges []*sheets.MatchedValueRange,
+	requested []SheetRange,
+	pos position.SheetPosition,
+) ([]sdk.Record, error) {
+	cursors := copyCursors(pos.Cursors)
+	cursorKeys := copyCursorKeys(pos.CursorKeys)
+
+	records := make([]sdk.Record, 0)
+	for _, values := range b.readSheets(ctx, valueRanges, requested) {
+		sheetRange, header := values.sheetRange, values.header
+		sheetID := sheetRange.GridRange.SheetId
+
+		index, err := b.columnIndex(sheetRange, header, b.incrementalColumn, "incremental column")
+		if err != nil {
+			return nil, err
+		}
+		keys, err := b.recordKeys(sheetRange, header)
+		if err != nil {
+			return nil, err
+		}
+
+		cursor, hasCursor := cursors[sheetID]
+		var rows []cursorRow
+		for j, rowValue := range values.rows {
+			if index >= len(rowValue) {
+				continue
+			}
+			value := strings.TrimSpace(fmt.Sprint(rowValue[index]))
+			if value == "" || (hasCursor && compareCursors(value, cursor) < 0) {
+				continue
+			}
+			rows = append(rows, cursorRow{
+				number: sheetRange.GridRange.StartRowIndex + b.headerRow + int64(j) + 1,
+				cursor: value,
+				values: rowValue,
+			})
+		}
+		sort.SliceStable(rows, func(x, y int) bool {
+			return compareCursors(rows[x].cursor, rows[y].cursor) < 0
+		})
+
+		for _, row := range rows {
+			// the cursor moves past the rows skipped, so they are not read again
+			if cursor, ok := cursors[sheetID]; !ok || compareCursors(row.cursor, cursor) > 0 {
+				cursors[sheetID] = row.cursor
+				delete(cursorKeys, sheetID)
+			}
+			key, err := b.key(keys, row.values, strconv.FormatInt(row.number, 10), row.number)
+			if err != nil {
+				return records, err
+			}
+			if key == nil {
+				continue
+			}
+			// the row numbers change when the sheet is sorted, the rows are told apart by their values then
+			tieKey := fingerprint(row.values)
+			if keys.indexes != nil {
+				tieKey = string(key.Bytes())
+			}
+			if containsString(cursorKeys[sheetID], tieKey) {
+				continue
+			}
+			cursorKeys[sheetID] = append(cursorKeys[sheetID], tieKey)
+			payload, err := b.rowPayload(sheetRange, header, row.values)
+			if err != nil {
+				return records, err
+			}
+			lastRowPosition := position.SheetPosition{
+				SpreadsheetID: b.spreadsheetID,
+				SheetID:       sheetID,
+				Cursors:       copyCursors(cursors),
+				CursorKeys:    copyCursorKeys(cursorKeys),
+				Phase:         b.phase,
+				Schemas:       b.schemaVersions,
+			}
+			records = append(records, sdk.Record{
+				Position:  lastRowPosition.RecordPosition(),
+				Metadata:  b.recordMetadata(sheetRange, row.number, len(row.values)),
+				CreatedAt: time.Now(),
+				Key:       key,
+				Payload:   payload,
+			})
+		}
+		keys.logSkipped(ctx, b.keyColumns)
+	}
+	return records, nil
+}
+
+// compareCursors compares the values of the incremental column, as numbers if both values are numbers,
+// as timestamps if both values are RFC 3339 timestamps, as strings otherwise. The values are expected to be read
+// unformatted, the dates being serial numbers
+func compareCursors(a, b string) int {
+	if x, err := strconv.ParseFloat(a, 64); err == nil {
+		if y, err := strconv.ParseFloat(b, 64); err == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, err := time.Parse(time.RFC3339Nano, a); err == nil {
+		if y, err := time.Parse(time.RFC3339Nano, b); err == nil {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func copyCursors(cursors map[int64]string) map[int64]string {
+	out := make(map[int64]string, len(cursors))
+	for sheetID, cursor := range cursors {
+		out[sheetID] = cursor
+	}
+	return out
+}
+
+func copyCursorKeys(cursorKeys map[int64][]string) map[int64][]string {
+	out := make(map[int64][]string, len(cursorKeys))
+	for sheetID, keys := range cursorKeys {
+		out[sheetID] = append([]string(nil), keys...)
+	}
+	return out
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
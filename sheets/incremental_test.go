@@ -0,0 +1,141 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestBatchReader_valueRangesToIncremental(t *testing.T) {
+	ctx := context.Background()
+	br := &BatchReader{
+		ranges:            []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 7}}},
+		spreadsheetID:     "dummy_spreadsheet",
+		headerRow:         1,
+		incrementalColumn: "seq",
+	}
+	header := [][]interface{}{{"name", "seq"}}
+
+	// the rows are sorted by the incremental column, the rows without a value are skipped
+	out, err := br.valueRangesToIncremental(ctx,
+		valueRanges(header, [][]interface{}{{"b", "10"}, {"a", "9"}, {"c"}, {"d", "11"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+	var keys []string
+	var payloads []sdk.Data
+	for _, record := range out {
+		keys = append(keys, string(record.Key.Bytes()))
+		payloads = append(payloads, record.Payload)
+	}
+	assert.Equal(t, []string{"3", "2", "5"}, keys)
+	assert.Equal(t, sdk.StructuredData{"name": "a", "seq": "9"}, payloads[0])
+
+	pos, err := position.ParseRecordPosition(out[0].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{7: "9"}, pos.Cursors)
+	assert.Equal(t, int64(0), pos.RowOffset)
+	pos, err = position.ParseRecordPosition(out[2].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{7: "11"}, pos.Cursors)
+
+	// the rows sorted or inserted above are read after the cursor only
+	out, err = br.valueRangesToIncremental(ctx,
+		valueRanges(header, [][]interface{}{{"e", "12"}, {"d", "11"}, {"a", "9"}, {"b", "10"}}), br.ranges, pos)
+	assert.NoError(t, err)
+	assert.Len(t, out, 1)
+	assert.Equal(t, sdk.StructuredData{"name": "e", "seq": "12"}, out[0].Payload)
+	assert.Equal(t, "2", string(out[0].Key.Bytes()))
+}
+
+func TestBatchReader_valueRangesToIncremental_Ties(t *testing.T) {
+	ctx := context.Background()
+	br := &BatchReader{
+		ranges:            []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 7}}},
+		spreadsheetID:     "dummy_spreadsheet",
+		headerRow:         1,
+		incrementalColumn: "seq",
+		keyColumns:        []string{"name"},
+		keySeparator:      "-",
+		skipEmptyKeys:     true,
+	}
+	header := [][]interface{}{{"name", "seq"}}
+
+	out, err := br.valueRangesToIncremental(ctx,
+		valueRanges(header, [][]interface{}{{"a", "9"}, {"b", "10"}, {"c", "10"}}), br.ranges, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, out, 3)
+
+	// the connector restarts after the record of row b is acknowledged, row c shares the value of the cursor
+	pos, err := position.ParseRecordPosition(out[1].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{7: "10"}, pos.Cursors)
+	assert.Equal(t, map[int64][]string{7: {"b"}}, pos.CursorKeys)
+
+	// row d is added later with the value of the cursor
+	out, err = br.valueRangesToIncremental(ctx,
+		valueRanges(header, [][]interface{}{{"a", "9"}, {"b", "10"}, {"c", "10"}, {"d", "10"}, {"e", "11"}}), br.ranges, pos)
+	assert.NoError(t, err)
+	var keys []string
+	for _, record := range out {
+		keys = append(keys, string(record.Key.Bytes()))
+	}
+	assert.Equal(t, []string{"c", "d", "e"}, keys)
+
+	pos, err = position.ParseRecordPosition(out[1].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64][]string{7: {"b", "c", "d"}}, pos.CursorKeys)
+	pos, err = position.ParseRecordPosition(out[2].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]string{7: "11"}, pos.Cursors)
+	assert.Equal(t, map[int64][]string{7: {"e"}}, pos.CursorKeys)
+}
+
+func TestBatchReader_valueRangesToIncremental_MissingColumn(t *testing.T) {
+	br := &BatchReader{
+		ranges:            []SheetRange{{GridRange: &sheets.GridRange{SheetId: 7}}},
+		headerRow:         1,
+		incrementalColumn: "updated_at",
+	}
+	_, err := br.valueRangesToIncremental(context.Background(),
+		valueRanges([][]interface{}{{"name"}}, [][]interface{}{{"a"}}), br.ranges, position.SheetPosition{})
+	assert.EqualError(t, err, `incremental column "updated_at" not found in the header row of sheet(gid:7)`)
+}
+
+func TestCompareCursors(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "9", b: "10", want: -1},
+		{a: "10.5", b: "10.5", want: 0},
+		{a: "2022-07-01T10:00:00Z", b: "2022-07-01T11:00:00+02:00", want: 1},
+		{a: "2022-07-01", b: "2022-06-30", want: 1},
+		{a: "abc", b: "abd", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.want, compareCursors(tt.a, tt.b))
+		})
+	}
+}
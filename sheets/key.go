@@ -0,0 +1,99 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+// recordKeys composes the record keys of the rows of a sheet from the values of the key columns
+type recordKeys struct {
+	sheetID int64
+	// indexes are the indexes of the key columns in the rows, nil if the record keys are the fallback keys
+	indexes []int
+	// skipped is the number of rows skipped, as their key is empty
+	skipped int
+}
+
+// recordKeys resolves the key columns of the sheet, the record keys are the fallback keys if no key column is set
+func (b *BatchReader) recordKeys(sheetRange SheetRange, header []interface{}) (*recordKeys, error) {
+	keys := &recordKeys{sheetID: sheetRange.GridRange.SheetId}
+	for _, column := range b.keyColumns {
+		index, err := b.columnIndex(sheetRange, header, column, "record key column")
+		if err != nil {
+			return nil, err
+		}
+		keys.indexes = append(keys.indexes, index)
+	}
+	return keys, nil
+}
+
+// key returns the record key of the row, composed of the values of the key columns joined by the key separator,
+// or structured data keyed by the key columns. The fallback key is returned if no key column is set.
+// nil is returned if all the key values are blank and the rows with an empty key are skipped,
+// an error is returned if they are not.
+func (b *BatchReader) key(keys *recordKeys, row []interface{}, fallback string, rowNumber int64) (sdk.Data, error) {
+	if keys.indexes == nil {
+		return sdk.RawData(fallback), nil
+	}
+
+	values := make([]string, len(keys.indexes))
+	empty := true
+	for i, index := range keys.indexes {
+		if index < len(row) && row[index] != nil {
+			value := row[index]
+			if b.typedValues {
+				value = b.typedValue(keys.sheetID, index, value)
+			}
+			values[i] = strings.TrimSpace(fmt.Sprint(value))
+		}
+		empty = empty && values[i] == ""
+	}
+	if empty {
+		if !b.skipEmptyKeys {
+			return nil, fmt.Errorf("row %d of sheet(gid:%d) has an empty record key, key columns: %q",
+				rowNumber, keys.sheetID, b.keyColumns)
+		}
+		keys.skipped++
+		return nil, nil
+	}
+
+	if b.structuredKey {
+		key := make(sdk.StructuredData, len(values))
+		for i, value := range values {
+			key[b.keyColumns[i]] = value
+		}
+		return key, nil
+	}
+	return sdk.RawData(strings.Join(values, b.keySeparator)), nil
+}
+
+// logSkipped logs the number of rows skipped, as their key is empty
+func (keys *recordKeys) logSkipped(ctx context.Context, keyColumns []string) {
+	if keys.skipped == 0 {
+		return
+	}
+	sdk.Logger(ctx).Warn().
+		Int64("sheet_id", keys.sheetID).
+		Strs("key_columns", keyColumns).
+		Int("skipped_rows", keys.skipped).
+		Msg("skipped rows with an empty record key")
+}
@@ -0,0 +1,103 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"fmt"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestBatchReader_Key(t *testing.T) {
+	header := []interface{}{"region", "order id", "amount"}
+	sheetRange := SheetRange{GridRange: &sheets.GridRange{SheetId: 1}}
+
+	testCases := []struct {
+		name    string
+		reader  *BatchReader
+		row     []interface{}
+		key     sdk.Data
+		skipped int
+		err     error
+	}{
+		{
+			name:   "row number without key columns",
+			reader: &BatchReader{headerRow: 1},
+			row:    []interface{}{"EU", "42", "10"},
+			key:    sdk.RawData("7"),
+		},
+		{
+			name:   "raw key",
+			reader: &BatchReader{headerRow: 1, keyColumns: []string{"region", "order id"}, keySeparator: "|"},
+			row:    []interface{}{" EU ", "42", "10"},
+			key:    sdk.RawData("EU|42"),
+		},
+		{
+			name:   "raw key with partially blank values",
+			reader: &BatchReader{headerRow: 1, keyColumns: []string{"region", "order id"}, keySeparator: "-"},
+			row:    []interface{}{"", "42"},
+			key:    sdk.RawData("-42"),
+		},
+		{
+			name:   "structured key",
+			reader: &BatchReader{headerRow: 1, keyColumns: []string{"order id", "region"}, structuredKey: true},
+			row:    []interface{}{"EU", "42", "10"},
+			key:    sdk.StructuredData{"order id": "42", "region": "EU"},
+		},
+		{
+			name:   "column letters without header row",
+			reader: &BatchReader{keyColumns: []string{"B"}, keySeparator: "|"},
+			row:    []interface{}{"EU", "42", "10"},
+			key:    sdk.RawData("42"),
+		},
+		{
+			name:    "empty key skipped",
+			reader:  &BatchReader{headerRow: 1, keyColumns: []string{"region", "order id"}, keySeparator: "|", skipEmptyKeys: true},
+			row:     []interface{}{" ", nil, "10"},
+			skipped: 1,
+		},
+		{
+			name:   "empty key",
+			reader: &BatchReader{headerRow: 1, keyColumns: []string{"region", "order id"}, keySeparator: "|"},
+			row:    []interface{}{""},
+			err:    fmt.Errorf("row 7 of sheet(gid:1) has an empty record key, key columns: [\"region\" \"order id\"]"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := tc.reader.recordKeys(sheetRange, header)
+			assert.NoError(t, err)
+			key, err := tc.reader.key(keys, tc.row, "7", 7)
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.key, key)
+			assert.Equal(t, tc.skipped, keys.skipped)
+		})
+	}
+}
+
+func TestBatchReader_RecordKeys_UnknownColumn(t *testing.T) {
+	br := &BatchReader{headerRow: 1, keyColumns: []string{"customer"}}
+	_, err := br.recordKeys(SheetRange{GridRange: &sheets.GridRange{SheetId: 1}}, []interface{}{"region"})
+	assert.EqualError(t, err, "record key column \"customer\" not found in the header row of sheet(gid:1)")
+}
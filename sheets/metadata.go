@@ -0,0 +1,121 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// MetadataSpreadsheetID is the metadata key for the ID of the spreadsheet the record was read from
+	MetadataSpreadsheetID = "google.sheets.spreadsheetId"
+	// MetadataSpreadsheetTitle is the metadata key for the title of the spreadsheet the record was read from
+	MetadataSpreadsheetTitle = "google.sheets.spreadsheetTitle"
+	// MetadataSheetTitle is the metadata key for the title of the sheet the record was read from
+	MetadataSheetTitle = "google.sheets.sheetTitle"
+	// MetadataSheetID is the metadata key for the gid of the sheet the record was read from
+	MetadataSheetID = "google.sheets.sheetId"
+	// MetadataRowNumber is the metadata key for the row number of the row in the sheet, starting from 1
+	MetadataRowNumber = "google.sheets.rowNumber"
+	// MetadataRange is the metadata key for the range of the row in A1 notation, e.g. 'Orders'!A5:D5
+	MetadataRange = "google.sheets.range"
+	// MetadataValueRenderOption is the metadata key for the value render option used to read the row
+	MetadataValueRenderOption = "google.sheets.valueRenderOption"
+	// MetadataDateTimeRenderOption is the metadata key for the date time render option used to read the row
+	MetadataDateTimeRenderOption = "google.sheets.dateTimeRenderOption"
+	// MetadataReadAt is the metadata key for the time the row was read at, in RFC 3339 format
+	MetadataReadAt = "google.sheets.readAt"
+)
+
+// recordMetadata returns the metadata of the record of the row read from the sheet, the row number
+// and the range of the row are not set if the row number is 0, e.g. for the deleted rows in cdc mode.
+// width is the number of cells read from the row.
+func (b *BatchReader) recordMetadata(sheetRange SheetRange, rowNumber int64, width int) map[string]string {
+	metadata := map[string]string{
+		MetadataSpreadsheetID: b.spreadsheetID,
+		MetadataSheetID:       strconv.FormatInt(sheetRange.GridRange.SheetId, 10),
+	}
+	if b.spreadsheetTitle != "" {
+		metadata[MetadataSpreadsheetTitle] = b.spreadsheetTitle
+	}
+	if sheetRange.Title != "" {
+		metadata[MetadataSheetTitle] = sheetRange.Title
+	}
+	if rowNumber > 0 {
+		metadata[MetadataRowNumber] = strconv.FormatInt(rowNumber, 10)
+		metadata[MetadataRange] = rowRange(sheetRange, rowNumber, width)
+	}
+	if b.valueRenderOption != "" {
+		metadata[MetadataValueRenderOption] = b.valueRenderOption
+	}
+	if b.dateTimeRenderOption != "" {
+		metadata[MetadataDateTimeRenderOption] = b.dateTimeRenderOption
+	}
+	if schema := b.schema(sheetRange); schema != "" {
+		metadata[MetadataSchema] = schema
+	}
+	b.setSchemaMetadata(metadata, sheetRange.GridRange.SheetId)
+	if !b.readAt.IsZero() {
+		metadata[MetadataReadAt] = b.readAt.UTC().Format(time.RFC3339Nano)
+	}
+	return metadata
+}
+
+// rowRange returns the range of the cells read from the row in A1 notation,
+// the sheet name is omitted if the title of the sheet is not known
+func rowRange(sheetRange SheetRange, rowNumber int64, width int) string {
+	start := sheetRange.GridRange.StartColumnIndex
+	end := start
+	if width > 1 {
+		end = start + int64(width) - 1
+	}
+	a1 := fmt.Sprintf("%s%d:%s%d", ColumnLetters(start), rowNumber, ColumnLetters(end), rowNumber)
+	if sheetRange.Title == "" {
+		return a1
+	}
+	return quoteSheetName(sheetRange.Title) + "!" + a1
+}
+
+// loadTitles returns the title of the spreadsheet, and sets the titles of the ranges missing them.
+// The titles are only used in the record metadata, an error is logged and the titles are left empty
+// if the spreadsheet metadata can't be read.
+func loadTitles(ctx context.Context, svc *sheets.Service, spreadsheetID string, ranges []SheetRange) string {
+	spreadsheet, err := svc.Spreadsheets.Get(spreadsheetID).
+		Fields("properties.title", "sheets.properties(sheetId,title)").
+		Context(ctx).Do()
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).
+			Str("spreadsheet_id", spreadsheetID).
+			Msg("unable to read the spreadsheet titles, the titles are not set in the record metadata")
+		return ""
+	}
+	for i := range ranges {
+		if ranges[i].Title == "" {
+			ranges[i].Title = sheetTitleByID(spreadsheet, ranges[i].GridRange.SheetId)
+		}
+	}
+	if spreadsheet.Properties == nil {
+		return ""
+	}
+	return spreadsheet.Properties.Title
+}
@@ -0,0 +1,59 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestBatchReader_recordMetadata(t *testing.T) {
+	br := &BatchReader{
+		spreadsheetID:        "dummy_spreadsheet",
+		spreadsheetTitle:     "Sales",
+		valueRenderOption:    "FORMATTED_VALUE",
+		dateTimeRenderOption: "FORMATTED_STRING",
+		readAt:               time.Date(2022, 7, 1, 10, 0, 0, 0, time.UTC),
+	}
+	sheetRange := SheetRange{Title: "Q3 Orders", GridRange: &sheets.GridRange{SheetId: 42, StartColumnIndex: 1}}
+
+	want := map[string]string{
+		MetadataSpreadsheetID:        "dummy_spreadsheet",
+		MetadataSpreadsheetTitle:     "Sales",
+		MetadataSheetTitle:           "Q3 Orders",
+		MetadataSheetID:              "42",
+		MetadataRowNumber:            "5",
+		MetadataRange:                "'Q3 Orders'!B5:D5",
+		MetadataValueRenderOption:    "FORMATTED_VALUE",
+		MetadataDateTimeRenderOption: "FORMATTED_STRING",
+		MetadataReadAt:               "2022-07-01T10:00:00Z",
+	}
+	assert.Equal(t, want, br.recordMetadata(sheetRange, 5, 3))
+
+	// the row number and the range are not known for the deleted rows
+	got := br.recordMetadata(sheetRange, 0, 0)
+	assert.NotContains(t, got, MetadataRowNumber)
+	assert.NotContains(t, got, MetadataRange)
+}
+
+func TestRowRange(t *testing.T) {
+	assert.Equal(t, "A1:A1", rowRange(SheetRange{GridRange: &sheets.GridRange{}}, 1, 0))
+	assert.Equal(t, "'It''s'!AA3:AC3", rowRange(SheetRange{Title: "It's", GridRange: &sheets.GridRange{StartColumnIndex: 26}}, 3, 3))
+}
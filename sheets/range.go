@@ -0,0 +1,299 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/api/sheets/v4"
+)
+
+// cellRegexp matches one side of an A1 range, i.e. a column, a row or a cell. e.g. A, 10, A10
+var cellRegexp = regexp.MustCompile(`^([A-Za-z]*)([0-9]*)$`)
+
+// A1Range is the parsed form of a range in A1 notation, e.g. 'Sheet 1'!A1:F
+type A1Range struct {
+	// SheetName is the sheet title, empty if the range doesn't include the sheet
+	SheetName string
+	// GridRange holds the row and column bounds of the range, SheetId is not set by ParseA1Range
+	GridRange *sheets.GridRange
+}
+
+// ParseA1Range parses a range in A1 notation, supported formats:
+// Sheet1, Sheet1!A1:F, 'My Sheet'!A:C, Sheet1!2:10, Sheet1!B2, A1:F10
+// Refer: https://developers.google.com/sheets/api/guides/concepts#expandable-1
+func ParseA1Range(a1 string) (A1Range, error) {
+	a1 = strings.TrimSpace(a1)
+	if a1 == "" {
+		return A1Range{}, fmt.Errorf("empty range")
+	}
+
+	sheetName, cells, err := splitSheetName(a1)
+	if err != nil {
+		return A1Range{}, fmt.Errorf("invalid range %q: %w", a1, err)
+	}
+	if cells == "" {
+		return A1Range{SheetName: sheetName, GridRange: &sheets.GridRange{}}, nil
+	}
+
+	start, end := cells, cells
+	if i := strings.Index(cells, ":"); i >= 0 {
+		start, end = cells[:i], cells[i+1:]
+	}
+	startCol, startRow, err := parseCell(start)
+	if err != nil {
+		return A1Range{}, fmt.Errorf("invalid range %q: %w", a1, err)
+	}
+	endCol, endRow, err := parseCell(end)
+	if err != nil {
+		return A1Range{}, fmt.Errorf("invalid range %q: %w", a1, err)
+	}
+
+	// indexes are zero based, start index is inclusive and end index is exclusive, -1 means unbounded
+	gridRange := &sheets.GridRange{}
+	if startCol >= 0 {
+		gridRange.StartColumnIndex = startCol
+	}
+	if startRow >= 0 {
+		gridRange.StartRowIndex = startRow
+	}
+	if endCol >= 0 {
+		gridRange.EndColumnIndex = endCol + 1
+	}
+	if endRow >= 0 {
+		gridRange.EndRowIndex = endRow + 1
+	}
+	if (gridRange.EndColumnIndex > 0 && gridRange.EndColumnIndex <= gridRange.StartColumnIndex) ||
+		(gridRange.EndRowIndex > 0 && gridRange.EndRowIndex <= gridRange.StartRowIndex) {
+		return A1Range{}, fmt.Errorf("invalid range %q: end must be after start", a1)
+	}
+	return A1Range{SheetName: sheetName, GridRange: gridRange}, nil
+}
+
+// splitSheetName splits the sheet name from the cells part of the range.
+// A range without "!" is considered a sheet name, unless it is a valid start:end cells reference,
+// as a single cell reference can't be distinguished from a sheet name. e.g. Sheet1
+func splitSheetName(a1 string) (string, string, error) {
+	if strings.HasPrefix(a1, "'") {
+		// quoted sheet name, single quotes are escaped by doubling them
+		var name strings.Builder
+		for i := 1; i < len(a1); i++ {
+			if a1[i] != '\'' {
+				name.WriteByte(a1[i])
+				continue
+			}
+			if i+1 < len(a1) && a1[i+1] == '\'' {
+				name.WriteByte('\'')
+				i++
+				continue
+			}
+			rest := a1[i+1:]
+			if rest == "" {
+				return name.String(), "", nil
+			}
+			if rest[0] != '!' {
+				return "", "", fmt.Errorf("expected ! after sheet name")
+			}
+			return name.String(), rest[1:], nil
+		}
+		return "", "", fmt.Errorf("unterminated quoted sheet name")
+	}
+
+	if i := strings.Index(a1, "!"); i >= 0 {
+		return a1[:i], a1[i+1:], nil
+	}
+	if isCellsReference(a1) {
+		return "", a1, nil
+	}
+	return a1, "", nil
+}
+
+func isCellsReference(cells string) bool {
+	parts := strings.SplitN(cells, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || !cellRegexp.MatchString(part) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseCell returns the zero based column and row index of a cell reference, -1 if the column or row is omitted
+func parseCell(cell string) (int64, int64, error) {
+	matches := cellRegexp.FindStringSubmatch(cell)
+	if matches == nil || cell == "" {
+		return 0, 0, fmt.Errorf("invalid cell reference %q", cell)
+	}
+
+	col, row := int64(-1), int64(-1)
+	if matches[1] != "" {
+		col = ColumnIndex(matches[1])
+	}
+	if matches[2] != "" {
+		n, err := strconv.ParseInt(matches[2], 10, 64)
+		if err != nil || n < 1 {
+			return 0, 0, fmt.Errorf("invalid row number in cell reference %q", cell)
+		}
+		row = n - 1
+	}
+	return col, row, nil
+}
+
+// ColumnIndex converts the column letters to the zero based column index. e.g. A => 0, Z => 25, AA => 26
+func ColumnIndex(letters string) int64 {
+	var index int64
+	for _, c := range strings.ToUpper(letters) {
+		index = index*26 + int64(c-'A'+1)
+	}
+	return index - 1
+}
+
+// ColumnLetters converts the zero based column index to the column letters. e.g. 0 => A, 25 => Z, 26 => AA
+func ColumnLetters(index int64) string {
+	letters := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		letters = string(rune('A'+(index-1)%26)) + letters
+	}
+	return letters
+}
+
+// AllSheets is the SheetNames value selecting all the sheets of the spreadsheet
+const AllSheets = "*"
+
+// RangeSelector selects the range of the spreadsheet to be read, at most one of the fields is expected to be set.
+// If none of the fields are set, the whole sheet SheetID is read.
+type RangeSelector struct {
+	// SheetID is the gid of the sheet, used if no other selector is set or the Range doesn't include the sheet name
+	SheetID int64
+	// SheetName is the title of the sheet
+	SheetName string
+	// SheetNames is the list of titles of the sheets, or AllSheets
+	SheetNames []string
+	// Range is a range in A1 notation
+	Range string
+	// NamedRange is the name of a named range defined in the spreadsheet
+	NamedRange string
+}
+
+// SheetRange is a range of a sheet, which is read from the spreadsheet
+type SheetRange struct {
+	// Title is the title of the sheet, empty if the sheet was selected by gid only
+	Title string
+	// GridRange is the range read from the sheet, rows are read from GridRange.StartRowIndex + row offset
+	GridRange *sheets.GridRange
+}
+
+func (r RangeSelector) needsMetadata() bool {
+	return r.SheetName != "" || len(r.SheetNames) != 0 || r.NamedRange != "" || r.Range != ""
+}
+
+// resolveSheetRanges resolves the selector to the ranges to be read, using the spreadsheet metadata if required
+func resolveSheetRanges(ctx context.Context, svc *sheets.Service, spreadsheetID string, selector RangeSelector) ([]SheetRange, error) {
+	if !selector.needsMetadata() {
+		return []SheetRange{{GridRange: &sheets.GridRange{SheetId: selector.SheetID}}}, nil
+	}
+
+	var a1 A1Range
+	if selector.Range != "" {
+		var err error
+		a1, err = ParseA1Range(selector.Range)
+		if err != nil {
+			return nil, err
+		}
+		if a1.SheetName == "" {
+			a1.GridRange.SheetId = selector.SheetID
+			return []SheetRange{{GridRange: a1.GridRange}}, nil
+		}
+	}
+
+	spreadsheet, err := svc.Spreadsheets.Get(spreadsheetID).
+		Fields("sheets.properties(sheetId,title,sheetType)", "namedRanges").
+		Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting spreadsheet(%s) metadata: %w", spreadsheetID, err)
+	}
+
+	switch {
+	case selector.NamedRange != "":
+		for _, namedRange := range spreadsheet.NamedRanges {
+			if namedRange.Name == selector.NamedRange {
+				return []SheetRange{{
+					Title:     sheetTitleByID(spreadsheet, namedRange.Range.SheetId),
+					GridRange: namedRange.Range,
+				}}, nil
+			}
+		}
+		return nil, fmt.Errorf("named range %q not found in spreadsheet(%s)", selector.NamedRange, spreadsheetID)
+	case len(selector.SheetNames) == 1 && selector.SheetNames[0] == AllSheets:
+		var ranges []SheetRange
+		for _, sheet := range spreadsheet.Sheets {
+			// charts and data source sheets don't have a grid to be read
+			if sheet.Properties == nil || (sheet.Properties.SheetType != "" && sheet.Properties.SheetType != "GRID") {
+				continue
+			}
+			ranges = append(ranges, SheetRange{
+				Title:     sheet.Properties.Title,
+				GridRange: &sheets.GridRange{SheetId: sheet.Properties.SheetId},
+			})
+		}
+		return ranges, nil
+	case len(selector.SheetNames) != 0:
+		ranges := make([]SheetRange, 0, len(selector.SheetNames))
+		for _, title := range selector.SheetNames {
+			sheetID, err := sheetIDByTitle(spreadsheet, title)
+			if err != nil {
+				return nil, fmt.Errorf("%w in spreadsheet(%s)", err, spreadsheetID)
+			}
+			ranges = append(ranges, SheetRange{Title: title, GridRange: &sheets.GridRange{SheetId: sheetID}})
+		}
+		return ranges, nil
+	case selector.SheetName != "":
+		a1 = A1Range{SheetName: selector.SheetName, GridRange: &sheets.GridRange{}}
+	}
+
+	sheetID, err := sheetIDByTitle(spreadsheet, a1.SheetName)
+	if err != nil {
+		return nil, fmt.Errorf("%w in spreadsheet(%s)", err, spreadsheetID)
+	}
+	a1.GridRange.SheetId = sheetID
+	return []SheetRange{{Title: a1.SheetName, GridRange: a1.GridRange}}, nil
+}
+
+func sheetIDByTitle(spreadsheet *sheets.Spreadsheet, title string) (int64, error) {
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.Title == title {
+			return sheet.Properties.SheetId, nil
+		}
+	}
+	return 0, fmt.Errorf("sheet %q not found", title)
+}
+
+func sheetTitleByID(spreadsheet *sheets.Spreadsheet, sheetID int64) string {
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties != nil && sheet.Properties.SheetId == sheetID {
+			return sheet.Properties.Title
+		}
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestParseA1Range(t *testing.T) {
+	tests := []struct {
+		in   string
+		want A1Range
+		err  string
+	}{{
+		in:   "Orders",
+		want: A1Range{SheetName: "Orders", GridRange: &sheets.GridRange{}},
+	}, {
+		in:   "Orders!A1:F",
+		want: A1Range{SheetName: "Orders", GridRange: &sheets.GridRange{EndColumnIndex: 6}},
+	}, {
+		in:   "'My ''Sheet'''!B2:AA10",
+		want: A1Range{SheetName: "My 'Sheet'", GridRange: &sheets.GridRange{StartColumnIndex: 1, StartRowIndex: 1, EndColumnIndex: 27, EndRowIndex: 10}},
+	}, {
+		in:   "Orders!2:10",
+		want: A1Range{SheetName: "Orders", GridRange: &sheets.GridRange{StartRowIndex: 1, EndRowIndex: 10}},
+	}, {
+		in:   "Orders!C5",
+		want: A1Range{SheetName: "Orders", GridRange: &sheets.GridRange{StartColumnIndex: 2, StartRowIndex: 4, EndColumnIndex: 3, EndRowIndex: 5}},
+	}, {
+		in:   "A5:C",
+		want: A1Range{GridRange: &sheets.GridRange{StartRowIndex: 4, EndColumnIndex: 3}},
+	}, {
+		in:  "Orders!F1:A1",
+		err: `invalid range "Orders!F1:A1": end must be after start`,
+	}, {
+		in:  "Orders!A0:B",
+		err: `invalid range "Orders!A0:B": invalid row number in cell reference "A0"`,
+	}, {
+		in:  "'Orders!A1:B",
+		err: `invalid range "'Orders!A1:B": unterminated quoted sheet name`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseA1Range(tt.in)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestColumnLetters(t *testing.T) {
+	for index, letters := range map[int64]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, letters, ColumnLetters(index))
+		assert.Equal(t, index, ColumnIndex(letters))
+	}
+}
+
+func TestResolveSheetRanges(t *testing.T) {
+	metadata := []byte(`{
+		"sheets": [
+			{"properties": {"sheetId": 0, "title": "Sheet1"}},
+			{"properties": {"sheetId": 42, "title": "Orders"}},
+			{"properties": {"sheetId": 43, "title": "Chart", "sheetType": "OBJECT"}}
+		],
+		"namedRanges": [
+			{"name": "open_tickets", "range": {"sheetId": 42, "startRowIndex": 1, "endColumnIndex": 4}}
+		]
+	}`)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v4/spreadsheets/dummy", r.URL.Path)
+		_, _ = w.Write(metadata)
+	}))
+	defer testServer.Close()
+	svc, err := sheets.NewService(
+		context.Background(),
+		option.WithEndpoint(testServer.URL),
+		option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		selector RangeSelector
+		want     []SheetRange
+		err      string
+	}{{
+		name:     "sheet id",
+		selector: RangeSelector{SheetID: 7},
+		want:     []SheetRange{{GridRange: &sheets.GridRange{SheetId: 7}}},
+	}, {
+		name:     "sheet name",
+		selector: RangeSelector{SheetName: "Orders"},
+		want:     []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42}}},
+	}, {
+		name:     "range with sheet name",
+		selector: RangeSelector{Range: "Orders!A1:F"},
+		want:     []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42, EndColumnIndex: 6}}},
+	}, {
+		name:     "range without sheet name",
+		selector: RangeSelector{SheetID: 7, Range: "B:C"},
+		want:     []SheetRange{{GridRange: &sheets.GridRange{SheetId: 7, StartColumnIndex: 1, EndColumnIndex: 3}}},
+	}, {
+		name:     "named range",
+		selector: RangeSelector{NamedRange: "open_tickets"},
+		want:     []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42, StartRowIndex: 1, EndColumnIndex: 4}}},
+	}, {
+		name:     "sheet names",
+		selector: RangeSelector{SheetNames: []string{"Orders", "Sheet1"}},
+		want: []SheetRange{
+			{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42}},
+			{Title: "Sheet1", GridRange: &sheets.GridRange{SheetId: 0}},
+		},
+	}, {
+		name:     "all sheets",
+		selector: RangeSelector{SheetNames: []string{AllSheets}},
+		want: []SheetRange{
+			{Title: "Sheet1", GridRange: &sheets.GridRange{SheetId: 0}},
+			{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42}},
+		},
+	}, {
+		name:     "missing sheet",
+		selector: RangeSelector{SheetName: "Invoices"},
+		err:      `sheet "Invoices" not found in spreadsheet(dummy)`,
+	}, {
+		name:     "missing named range",
+		selector: RangeSelector{NamedRange: "closed_tickets"},
+		err:      `named range "closed_tickets" not found in spreadsheet(dummy)`,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveSheetRanges(context.Background(), svc, "dummy", tt.selector)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,350 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// WriteModeOverwrite clears the rows below the header row at the start of every snapshot,
+	// the records are written from the first row below the header row
+	WriteModeOverwrite = "overwrite"
+	// WriteModeReplaceOnSnapshot writes the snapshot records to a hidden staging sheet, which replaces the values
+	// of the sheet once the snapshot is completed. The other records are appended to the sheet.
+	WriteModeReplaceOnSnapshot = "replace-on-snapshot"
+
+	// stagingSuffix is appended to the sheet name to name the staging sheet
+	stagingSuffix = "_staging"
+	// snapshotMarkerKey is the key of the developer metadata marking the sheet written to by a snapshot in overwrite
+	// mode, so that a restart during the snapshot resumes it rather than clearing the rows again
+	snapshotMarkerKey = "google.sheets.snapshotInProgress"
+)
+
+// IsSnapshotRecord reports whether the record was read during a snapshot, marked by the source
+// with the google.sheets.snapshot metadata or the snapshot operation
+func IsSnapshotRecord(record sdk.Record) bool {
+	return record.Metadata[MetadataSnapshot] == "true" || record.Metadata[MetadataOperation] == OperationSnapshot
+}
+
+// isSnapshotCompleted reports whether the record is the last record of a snapshot
+func isSnapshotCompleted(record sdk.Record) bool {
+	return record.Metadata[MetadataSnapshotCompleted] == "true"
+}
+
+// insertOption returns how the rows are appended, the rows cleared in overwrite mode are overwritten
+// rather than left empty above the rows appended
+func (w *Writer) insertOption() string {
+	if w.writeMode == WriteModeOverwrite {
+		return "OVERWRITE"
+	}
+	return insertDataOption
+}
+
+// writeSnapshots writes the records in overwrite and replace-on-snapshot modes. The records are written in runs
+// of snapshot records and of other records, a run of snapshot records ends with the last record of the snapshot.
+func (w *Writer) writeSnapshots(ctx context.Context, records []sdk.Record) error {
+	for start := 0; start < len(records); {
+		snapshot := IsSnapshotRecord(records[start])
+		end := start + 1
+		for end < len(records) && IsSnapshotRecord(records[end]) == snapshot && !isSnapshotCompleted(records[end-1]) {
+			end++
+		}
+		run := records[start:end]
+		completed := snapshot && isSnapshotCompleted(run[len(run)-1])
+
+		var err error
+		switch {
+		case snapshot && w.writeMode == WriteModeOverwrite:
+			err = w.overwrite(ctx, run, completed)
+		case snapshot:
+			err = w.writeStaging(ctx, run, completed)
+		default:
+			err = w.writeChanges(ctx, run)
+		}
+		if err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+// overwrite writes the snapshot records, the rows below the header row are cleared at the start of the snapshot.
+// The rows are not cleared on Open, so that the rows written are kept when the connector restarts after the snapshot.
+// The sheet is marked while the snapshot is written, so that the snapshot is resumed when the connector restarts
+// during the snapshot.
+func (w *Writer) overwrite(ctx context.Context, records []sdk.Record, completed bool) error {
+	if !w.inSnapshot {
+		if err := w.startOverwrite(ctx, !completed); err != nil {
+			return err
+		}
+	}
+	if err := w.append(ctx, records); err != nil {
+		return err
+	}
+	if completed {
+		return w.endOverwrite(ctx)
+	}
+	return nil
+}
+
+// writeStaging writes the snapshot records to the staging sheet, which is created by the first snapshot record,
+// and replaces the values of the sheet once the snapshot is completed
+func (w *Writer) writeStaging(ctx context.Context, records []sdk.Record, completed bool) error {
+	if w.staging == nil {
+		if err := w.startStaging(ctx); err != nil {
+			return err
+		}
+	}
+	if err := w.staging.append(ctx, records); err != nil {
+		return err
+	}
+	if completed {
+		return w.replaceWithStaging(ctx)
+	}
+	return nil
+}
+
+// writeChanges appends the records read after the snapshot, the snapshot is completed by the first of these records,
+// if it was not marked as completed
+func (w *Writer) writeChanges(ctx context.Context, records []sdk.Record) error {
+	if w.staging != nil {
+		if err := w.replaceWithStaging(ctx); err != nil {
+			return err
+		}
+	}
+	if w.inSnapshot {
+		if err := w.endOverwrite(ctx); err != nil {
+			return err
+		}
+	}
+	return w.append(ctx, records)
+}
+
+// startOverwrite clears the values of the rows below the header row, the formats are kept. The sheet is marked
+// along, if the snapshot is not completed by the records written next.
+func (w *Writer) startOverwrite(ctx context.Context, mark bool) error {
+	requests := []*sheets.Request{clearValuesRequest(w.sheetID, w.headerRow)}
+	if mark {
+		requests = append(requests, &sheets.Request{CreateDeveloperMetadata: &sheets.CreateDeveloperMetadataRequest{
+			DeveloperMetadata: &sheets.DeveloperMetadata{
+				MetadataKey:   snapshotMarkerKey,
+				MetadataValue: "true",
+				Location:      &sheets.DeveloperMetadataLocation{SheetId: w.sheetID},
+				Visibility:    "DOCUMENT",
+			},
+		}})
+	}
+	res, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error clearing the rows of sheet(%s): %w", w.sheetName, err)
+	}
+	if mark {
+		if len(res.Replies) < 2 || res.Replies[1].CreateDeveloperMetadata == nil ||
+			res.Replies[1].CreateDeveloperMetadata.DeveloperMetadata == nil {
+			return fmt.Errorf("error marking the snapshot of sheet(%s): no developer metadata returned", w.sheetName)
+		}
+		w.snapshotMarker = res.Replies[1].CreateDeveloperMetadata.DeveloperMetadata.MetadataId
+		w.inSnapshot = true
+	}
+	sdk.Logger(ctx).Info().
+		Str("sheet_name", w.sheetName).
+		Msg("cleared the rows below the header row")
+	return nil
+}
+
+// endOverwrite removes the mark of the snapshot written to the sheet
+func (w *Writer) endOverwrite(ctx context.Context) error {
+	if w.snapshotMarker != 0 {
+		_, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{DeleteDeveloperMetadata: &sheets.DeleteDeveloperMetadataRequest{
+				DataFilter: &sheets.DataFilter{DeveloperMetadataLookup: &sheets.DeveloperMetadataLookup{
+					MetadataId: w.snapshotMarker,
+				}},
+			}}},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error unmarking the snapshot of sheet(%s): %w", w.sheetName, err)
+		}
+	}
+	w.snapshotMarker = 0
+	w.inSnapshot = false
+	return nil
+}
+
+// loadSnapshotMarker resumes the snapshot marked on the sheet, which was interrupted by a restart of the connector
+func (w *Writer) loadSnapshotMarker(ctx context.Context) error {
+	res, err := w.sheetSvc.Spreadsheets.DeveloperMetadata.Search(w.spreadsheetID, &sheets.SearchDeveloperMetadataRequest{
+		DataFilters: []*sheets.DataFilter{{
+			DeveloperMetadataLookup: &sheets.DeveloperMetadataLookup{
+				MetadataKey:      snapshotMarkerKey,
+				MetadataLocation: &sheets.DeveloperMetadataLocation{SheetId: w.sheetID},
+			},
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error searching the snapshot marker of sheet(%s): %w", w.sheetName, err)
+	}
+	for _, matched := range res.MatchedDeveloperMetadata {
+		if matched.DeveloperMetadata == nil {
+			continue
+		}
+		w.snapshotMarker = matched.DeveloperMetadata.MetadataId
+		w.inSnapshot = true
+		sdk.Logger(ctx).Info().
+			Str("sheet_name", w.sheetName).
+			Msg("resuming the snapshot interrupted by a restart")
+		break
+	}
+	return nil
+}
+
+// startStaging creates the staging sheet as a hidden copy of the sheet without the rows below the header row.
+// A staging sheet left by a snapshot interrupted by a restart is written to as is, as the snapshot is resumed.
+func (w *Writer) startStaging(ctx context.Context) error {
+	name := w.sheetName + stagingSuffix
+	properties, _, err := w.findSheet(ctx, name)
+	if err != nil {
+		return err
+	}
+	if properties != nil {
+		sdk.Logger(ctx).Info().
+			Str("sheet_name", w.sheetName).
+			Str("staging_sheet_name", name).
+			Msg("resuming the snapshot in the existing staging sheet")
+	} else {
+		res, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{{
+				DuplicateSheet: &sheets.DuplicateSheetRequest{SourceSheetId: w.sheetID, NewSheetName: name},
+			}},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error creating staging sheet(%s): %w", name, err)
+		}
+		if len(res.Replies) == 0 || res.Replies[0].DuplicateSheet == nil || res.Replies[0].DuplicateSheet.Properties == nil {
+			return fmt.Errorf("error creating staging sheet(%s): no sheet properties returned", name)
+		}
+		properties = res.Replies[0].DuplicateSheet.Properties
+
+		_, err = w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+			Requests: []*sheets.Request{
+				clearValuesRequest(properties.SheetId, w.headerRow),
+				{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+					Properties: &sheets.SheetProperties{SheetId: properties.SheetId, Hidden: true},
+					Fields:     "hidden",
+				}},
+			},
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error clearing staging sheet(%s): %w", name, err)
+		}
+		sdk.Logger(ctx).Info().
+			Str("sheet_name", w.sheetName).
+			Str("staging_sheet_name", name).
+			Msg("writing the snapshot to the staging sheet")
+	}
+
+	w.staging = &Writer{
+		sheetSvc:         w.sheetSvc,
+		sheetName:        name,
+		spreadsheetID:    w.spreadsheetID,
+		valueInputOption: w.valueInputOption,
+		maxRetries:       w.maxRetries,
+		headerRow:        w.headerRow,
+		appendHeaders:    w.appendHeaders,
+		writeMode:        WriteModeOverwrite,
+		format:           w.format,
+	}
+	w.staging.setSheetProperties(properties)
+	return nil
+}
+
+// replaceWithStaging replaces the values of the sheet with the values of the staging sheet, and deletes the staging
+// sheet. The requests are applied atomically, so the sheet is never seen half written. The sheet keeps its gid and
+// formats, so the references to the sheet, e.g. from charts or formulas, keep working.
+func (w *Writer) replaceWithStaging(ctx context.Context) error {
+	target, _, err := w.findSheet(ctx, w.sheetName)
+	if err != nil {
+		return err
+	}
+	staging, _, err := w.findSheet(ctx, w.staging.sheetName)
+	if err != nil {
+		return err
+	}
+	if target == nil || staging == nil || target.GridProperties == nil || staging.GridProperties == nil {
+		return fmt.Errorf("error replacing sheet(%s) with staging sheet(%s): sheet not found", w.sheetName, w.staging.sheetName)
+	}
+
+	rows, columns := staging.GridProperties.RowCount, staging.GridProperties.ColumnCount
+	requests := []*sheets.Request{clearValuesRequest(w.sheetID, 0)}
+	if rows > target.GridProperties.RowCount || columns > target.GridProperties.ColumnCount {
+		requests = append(requests, &sheets.Request{UpdateSheetProperties: &sheets.UpdateSheetPropertiesRequest{
+			Properties: &sheets.SheetProperties{
+				SheetId: w.sheetID,
+				GridProperties: &sheets.GridProperties{
+					RowCount:    maxInt64(rows, target.GridProperties.RowCount),
+					ColumnCount: maxInt64(columns, target.GridProperties.ColumnCount),
+				},
+			},
+			Fields: "gridProperties.rowCount,gridProperties.columnCount",
+		}})
+	}
+	requests = append(requests,
+		&sheets.Request{CopyPaste: &sheets.CopyPasteRequest{
+			Source:      &sheets.GridRange{SheetId: staging.SheetId, EndRowIndex: rows, EndColumnIndex: columns},
+			Destination: &sheets.GridRange{SheetId: w.sheetID, EndRowIndex: rows, EndColumnIndex: columns},
+			PasteType:   "PASTE_NORMAL",
+		}},
+		&sheets.Request{DeleteSheet: &sheets.DeleteSheetRequest{SheetId: staging.SheetId}},
+	)
+	_, err = w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error replacing sheet(%s) with staging sheet(%s): %w", w.sheetName, w.staging.sheetName, err)
+	}
+	sdk.Logger(ctx).Info().
+		Str("sheet_name", w.sheetName).
+		Str("staging_sheet_name", w.staging.sheetName).
+		Msg("replaced the sheet with the snapshot written to the staging sheet")
+	w.staging = nil
+	w.headerCache = nil
+	w.rowCount = maxInt64(rows, target.GridProperties.RowCount)
+	return nil
+}
+
+// clearValuesRequest returns the request clearing the values of the rows of the sheet from the zero based row index
+func clearValuesRequest(sheetID, startRow int64) *sheets.Request {
+	return &sheets.Request{UpdateCells: &sheets.UpdateCellsRequest{
+		Range:  &sheets.GridRange{SheetId: sheetID, StartRowIndex: startRow},
+		Fields: "userEnteredValue",
+	}}
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,242 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeSpreadsheet serves the sheets and the developer metadata of a spreadsheet, applying the sheets and the
+// developer metadata added and deleted by the batch updates, and captures the requests made in order
+type fakeSpreadsheet struct {
+	t        *testing.T
+	sheets   []*sheets.Sheet
+	metadata []*sheets.DeveloperMetadata
+	requests []string
+	updates  []*sheets.Request
+}
+
+func (f *fakeSpreadsheet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy":
+		f.requests = append(f.requests, "get")
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&sheets.Spreadsheet{Sheets: f.sheets}))
+	case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/dummy:batchUpdate":
+		f.requests = append(f.requests, "batchUpdate")
+		var req sheets.BatchUpdateSpreadsheetRequest
+		assert.NoError(f.t, json.Unmarshal(body, &req))
+		res := &sheets.BatchUpdateSpreadsheetResponse{}
+		for _, request := range req.Requests {
+			f.updates = append(f.updates, request)
+			reply := &sheets.Response{}
+			switch {
+			case request.DuplicateSheet != nil:
+				properties := &sheets.SheetProperties{
+					SheetId:        9,
+					Title:          request.DuplicateSheet.NewSheetName,
+					GridProperties: &sheets.GridProperties{RowCount: 1000, ColumnCount: 26},
+				}
+				f.sheets = append(f.sheets, &sheets.Sheet{Properties: properties})
+				reply.DuplicateSheet = &sheets.DuplicateSheetResponse{Properties: properties}
+			case request.CreateDeveloperMetadata != nil:
+				metadata := *request.CreateDeveloperMetadata.DeveloperMetadata
+				metadata.MetadataId = int64(len(f.updates))
+				f.metadata = append(f.metadata, &metadata)
+				reply.CreateDeveloperMetadata = &sheets.CreateDeveloperMetadataResponse{DeveloperMetadata: &metadata}
+			case request.DeleteDeveloperMetadata != nil:
+				for i, metadata := range f.metadata {
+					if metadata.MetadataId == request.DeleteDeveloperMetadata.DataFilter.DeveloperMetadataLookup.MetadataId {
+						f.metadata = append(f.metadata[:i], f.metadata[i+1:]...)
+						break
+					}
+				}
+			case request.DeleteSheet != nil:
+				for i, sheet := range f.sheets {
+					if sheet.Properties.SheetId == request.DeleteSheet.SheetId {
+						f.sheets = append(f.sheets[:i], f.sheets[i+1:]...)
+						break
+					}
+				}
+			}
+			res.Replies = append(res.Replies, reply)
+		}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(res))
+	case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/dummy/developerMetadata:search":
+		f.requests = append(f.requests, "search")
+		res := &sheets.SearchDeveloperMetadataResponse{}
+		for _, metadata := range f.metadata {
+			res.MatchedDeveloperMetadata = append(res.MatchedDeveloperMetadata, &sheets.MatchedDeveloperMetadata{DeveloperMetadata: metadata})
+		}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(res))
+	case r.Method == http.MethodPost:
+		f.requests = append(f.requests, r.URL.Path[len("/v4/spreadsheets/dummy/values/"):]+" "+r.URL.Query().Get("insertDataOption"))
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeSpreadsheetWriter(t *testing.T, fake *fakeSpreadsheet, writeMode string) *Writer {
+	testServer := httptest.NewServer(fake)
+	t.Cleanup(testServer.Close)
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	return &Writer{
+		sheetSvc:         sheetSvc,
+		sheetName:        "sheet",
+		spreadsheetID:    "dummy",
+		valueInputOption: "RAW",
+		headerRow:        1,
+		writeMode:        writeMode,
+		sheetID:          5,
+	}
+}
+
+func TestWriter_ReplaceOnSnapshot(t *testing.T) {
+	fake := &fakeSpreadsheet{t: t, sheets: []*sheets.Sheet{{Properties: &sheets.SheetProperties{
+		SheetId: 5, Title: "sheet", GridProperties: &sheets.GridProperties{RowCount: 100, ColumnCount: 26},
+	}}}}
+	writer := newFakeSpreadsheetWriter(t, fake, WriteModeReplaceOnSnapshot)
+
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataSnapshot: "true"}, Payload: sdk.RawData(`["1"]`)},
+		{Metadata: map[string]string{MetadataSnapshot: "true", MetadataSnapshotCompleted: "true"}, Payload: sdk.RawData(`["2"]`)},
+		{Metadata: map[string]string{MetadataOperation: OperationCreate}, Payload: sdk.RawData(`["3"]`)},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, writer.staging)
+	assert.Equal(t, []string{
+		"get", "batchUpdate", "batchUpdate", // the staging sheet is created and cleared
+		"sheet_staging:append OVERWRITE",
+		"get", "get", "batchUpdate", // the sheet is replaced with the staging sheet
+		"sheet:append INSERT_ROWS",
+	}, fake.requests)
+
+	replace := fake.updates[len(fake.updates)-4:]
+	assert.Equal(t, clearValuesRequest(5, 0), replace[0])
+	assert.Equal(t, &sheets.GridProperties{RowCount: 1000, ColumnCount: 26}, replace[1].UpdateSheetProperties.Properties.GridProperties)
+	assert.Equal(t, &sheets.GridRange{SheetId: 9, EndRowIndex: 1000, EndColumnIndex: 26}, replace[2].CopyPaste.Source)
+	assert.Equal(t, &sheets.GridRange{SheetId: 5, EndRowIndex: 1000, EndColumnIndex: 26}, replace[2].CopyPaste.Destination)
+	assert.Equal(t, int64(9), replace[3].DeleteSheet.SheetId)
+	assert.Len(t, fake.sheets, 1)
+}
+
+func TestWriter_ReplaceOnSnapshot_CompletedByChange(t *testing.T) {
+	fake := &fakeSpreadsheet{t: t, sheets: []*sheets.Sheet{{Properties: &sheets.SheetProperties{
+		SheetId: 5, Title: "sheet", GridProperties: &sheets.GridProperties{RowCount: 100, ColumnCount: 26},
+	}}}}
+	writer := newFakeSpreadsheetWriter(t, fake, WriteModeReplaceOnSnapshot)
+
+	// the snapshot is not completed by the records of the first write
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataOperation: OperationSnapshot}, Payload: sdk.RawData(`["1"]`)},
+	})
+	assert.NoError(t, err)
+	assert.NotNil(t, writer.staging)
+	assert.Len(t, fake.sheets, 2)
+
+	err = writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataOperation: OperationCreate}, Payload: sdk.RawData(`["2"]`)},
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, writer.staging)
+	assert.Len(t, fake.sheets, 1)
+}
+
+func TestWriter_Overwrite(t *testing.T) {
+	fake := &fakeSpreadsheet{t: t}
+	writer := newFakeSpreadsheetWriter(t, fake, WriteModeOverwrite)
+
+	// the rows are kept by the records read after a restart following the snapshot
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataOperation: OperationCreate}, Payload: sdk.RawData(`["0"]`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sheet:append OVERWRITE"}, fake.requests)
+	assert.Empty(t, fake.updates)
+
+	fake.requests = nil
+	err = writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataSnapshot: "true", MetadataSnapshotCompleted: "true"}, Payload: sdk.RawData(`["1"]`)},
+		{Payload: sdk.RawData(`["2"]`)},
+		{Metadata: map[string]string{MetadataSnapshot: "true"}, Payload: sdk.RawData(`["3"]`)},
+		{Metadata: map[string]string{MetadataSnapshot: "true"}, Payload: sdk.RawData(`["4"]`)},
+	})
+	assert.NoError(t, err)
+	// the rows are cleared at the start of each snapshot, the sheet is marked till the snapshot is completed
+	assert.Equal(t, []string{
+		"batchUpdate",
+		"sheet:append OVERWRITE",
+		"sheet:append OVERWRITE",
+		"batchUpdate",
+		"sheet:append OVERWRITE",
+	}, fake.requests)
+	assert.Equal(t, clearValuesRequest(5, 1), fake.updates[0])
+	assert.Equal(t, clearValuesRequest(5, 1), fake.updates[1])
+	assert.Equal(t, snapshotMarkerKey, fake.updates[2].CreateDeveloperMetadata.DeveloperMetadata.MetadataKey)
+	assert.Len(t, fake.metadata, 1)
+	assert.True(t, writer.inSnapshot)
+
+	fake.requests = nil
+	err = writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataSnapshot: "true", MetadataSnapshotCompleted: "true"}, Payload: sdk.RawData(`["5"]`)},
+	})
+	assert.NoError(t, err)
+	// the mark is removed once the snapshot is completed
+	assert.Equal(t, []string{"sheet:append OVERWRITE", "batchUpdate"}, fake.requests)
+	assert.Empty(t, fake.metadata)
+	assert.False(t, writer.inSnapshot)
+}
+
+func TestWriter_Overwrite_RestartDuringSnapshot(t *testing.T) {
+	fake := &fakeSpreadsheet{t: t}
+	writer := newFakeSpreadsheetWriter(t, fake, WriteModeOverwrite)
+
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataSnapshot: "true"}, Payload: sdk.RawData(`["1"]`)},
+	})
+	assert.NoError(t, err)
+
+	// the writer of the restarted connector resumes the snapshot marked on the sheet
+	fake.requests, fake.updates = nil, nil
+	restarted := newFakeSpreadsheetWriter(t, fake, WriteModeOverwrite)
+	assert.NoError(t, restarted.loadSnapshotMarker(context.Background()))
+	assert.True(t, restarted.inSnapshot)
+
+	err = restarted.Write(context.Background(), []sdk.Record{
+		{Metadata: map[string]string{MetadataSnapshot: "true"}, Payload: sdk.RawData(`["2"]`)},
+		{Metadata: map[string]string{MetadataOperation: OperationCreate}, Payload: sdk.RawData(`["3"]`)},
+	})
+	assert.NoError(t, err)
+	// the rows written before the restart are not cleared, the snapshot is completed by the change
+	assert.Equal(t, []string{"search", "sheet:append OVERWRITE", "batchUpdate", "sheet:append OVERWRITE"}, fake.requests)
+	assert.Len(t, fake.updates, 1)
+	assert.NotNil(t, fake.updates[0].DeleteDeveloperMetadata)
+	assert.Empty(t, fake.metadata)
+}
@@ -0,0 +1,418 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/conduitio/conduit-connector-google-sheets/internal/jsonfile"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// RolloverToSheet rolls over to a new sheet of the spreadsheet, named after the sheet with a sequence number
+	RolloverToSheet = "sheet"
+	// RolloverToSpreadsheet rolls over to a new spreadsheet, named after the spreadsheet with a sequence number
+	RolloverToSpreadsheet = "spreadsheet"
+
+	// MaxSpreadsheetCells is the maximum number of cells of a spreadsheet
+	MaxSpreadsheetCells = 10_000_000
+)
+
+// Rollover configures rolling over to a new sheet or spreadsheet, before the cell limit of the spreadsheet is hit
+type Rollover struct {
+	// Cells is the number of cells of the grid the writer rolls over at, 0 if rolling over is disabled. The cells of
+	// all the sheets of the spreadsheet are counted with RolloverToSpreadsheet. With RolloverToSheet the cells of the
+	// sheet are counted, and as the sheets rolled over from are kept, the writer fails before the cells of all the
+	// sheets exceed MaxSpreadsheetCells.
+	Cells int64
+	// To is RolloverToSheet or RolloverToSpreadsheet
+	To string
+	// State stores the sheet and spreadsheet written to, so that the writer keeps writing to them after a restart
+	State RolloverStateFile
+}
+
+// RolloverState holds the sheets and spreadsheets written to by the writers sharing the state file. The file is
+// read once, and rewritten with the targets of all the writers on every rollover, so that the writers don't
+// overwrite the targets of each other.
+type RolloverState struct {
+	file RolloverStateFile
+
+	mux     sync.Mutex
+	content *rolloverStateContent
+}
+
+// NewRolloverState returns the state stored in the file, the file is read on the first use
+func NewRolloverState(file RolloverStateFile) *RolloverState {
+	return &RolloverState{file: file}
+}
+
+// target returns the target of the writer with the key, false if the writer didn't roll over yet
+func (s *RolloverState) target(key string) (rolloverTarget, bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.load(); err != nil {
+		return rolloverTarget{}, false, err
+	}
+	target, ok := s.content.Targets[key]
+	return target, ok, nil
+}
+
+// setTarget records the target of the writer with the key, and saves the targets of all the writers
+func (s *RolloverState) setTarget(key string, target rolloverTarget) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.content.Targets[key] = target
+	return s.file.Save(*s.content)
+}
+
+func (s *RolloverState) load() error {
+	if s.content != nil {
+		return nil
+	}
+	content, err := s.file.Load()
+	if err != nil {
+		return err
+	}
+	s.content = &content
+	return nil
+}
+
+// rolloverTarget is the sheet and spreadsheet written to, Seq is 1 for the configured sheet and spreadsheet
+type rolloverTarget struct {
+	SpreadsheetID string `json:"spreadsheet_id"`
+	SheetName     string `json:"sheet_name"`
+	Seq           int64  `json:"seq"`
+}
+
+// rolloverStateContent is the content of the rollover state file, the targets are keyed by the configured
+// spreadsheet and sheet, see rolloverKey
+type rolloverStateContent struct {
+	Targets map[string]rolloverTarget `json:"targets"`
+}
+
+// RolloverStateFile stores the sheets and spreadsheets written to as JSON in the file at Path
+type RolloverStateFile struct {
+	Path string
+}
+
+// Load reads the state file, an empty state is returned if the file doesn't exist yet
+func (f RolloverStateFile) Load() (rolloverStateContent, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rolloverStateContent{Targets: make(map[string]rolloverTarget)}, nil
+	}
+	if err != nil {
+		return rolloverStateContent{}, fmt.Errorf("unable to read state file: %w", err)
+	}
+
+	var content rolloverStateContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return rolloverStateContent{}, fmt.Errorf("unable to parse state file %s: %w", f.Path, err)
+	}
+	if content.Targets == nil {
+		content.Targets = make(map[string]rolloverTarget)
+	}
+	return content, nil
+}
+
+// Save atomically rewrites the state file
+func (f RolloverStateFile) Save(content rolloverStateContent) error {
+	return jsonfile.Save(f.Path, content)
+}
+
+// rolloverKey returns the key of the target of the writer in the state file
+func (w *Writer) rolloverKey() string {
+	return w.baseSpreadsheetID + "/" + w.baseSheetName
+}
+
+// restoreRollover sets the sheet and spreadsheet written to from the state file, if the writer rolled over before
+func (w *Writer) restoreRollover(ctx context.Context) error {
+	w.baseSpreadsheetID, w.baseSheetName, w.rolloverSeq = w.spreadsheetID, w.sheetName, 1
+	target, ok, err := w.rolloverState.target(w.rolloverKey())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+	w.spreadsheetID, w.sheetName, w.rolloverSeq = target.SpreadsheetID, target.SheetName, target.Seq
+	sdk.Logger(ctx).Info().
+		Str("spreadsheet_id", w.spreadsheetID).
+		Str("sheet_name", w.sheetName).
+		Msg("writing to the sheet rolled over to")
+	return nil
+}
+
+// rollOverIfFull rolls over to a new sheet or spreadsheet if the grid would exceed the rollover cells once
+// the rows are appended. The sheet rolled over to is not rolled over before any row is appended to it, even
+// if its grid exceeds the rollover cells.
+func (w *Writer) rollOverIfFull(ctx context.Context, rows int) error {
+	if w.rollover.Cells == 0 || w.rowCount <= w.rolloverRows {
+		return nil
+	}
+	if w.otherCells < 0 {
+		otherCells, err := w.countOtherCells(ctx)
+		if err != nil {
+			return err
+		}
+		w.otherCells = otherCells
+	}
+	rowCells := int64(rows) * w.columnCount
+	cells := w.rowCount*w.columnCount + w.otherCells
+	if w.rollover.To == RolloverToSheet {
+		if cells+rowCells > MaxSpreadsheetCells {
+			// the other sheets are counted again by the next write, once the sheets rolled over from are removed
+			w.otherCells = -1
+			return fmt.Errorf(
+				"appending %d rows to sheet(%s) would exceed the %d cells limit of spreadsheet(%s) holding %d cells, "+
+					"the sheets rolled over from must be removed or the writer rolled over to a new spreadsheet",
+				rows, w.sheetName, MaxSpreadsheetCells, w.spreadsheetID, cells,
+			)
+		}
+		cells = w.rowCount * w.columnCount
+	}
+	if cells+rowCells <= w.rollover.Cells {
+		return nil
+	}
+	return w.rollOver(ctx, cells)
+}
+
+// rollOver creates the next sheet or spreadsheet, copies the header row to it, and records it in the state file
+func (w *Writer) rollOver(ctx context.Context, cells int64) error {
+	var headers []string
+	if w.headerRow > 0 {
+		var err error
+		headers, err = w.readHeaders(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	previousSpreadsheetID, previousSheetName := w.spreadsheetID, w.sheetName
+	seq := w.rolloverSeq + 1
+	if w.rollover.To == RolloverToSpreadsheet {
+		if err := w.addSpreadsheet(ctx, seq); err != nil {
+			return err
+		}
+	} else {
+		// the sheet exists if the state was not saved after it was created
+		w.sheetName = fmt.Sprintf("%s_%04d", w.baseSheetName, seq)
+		properties, _, err := w.findSheet(ctx, w.sheetName)
+		if err != nil {
+			return err
+		}
+		if properties != nil {
+			w.setSheetProperties(properties)
+		} else if err := w.addSheet(ctx); err != nil {
+			return err
+		}
+		// the sheet rolled over from is one of the other sheets now
+		w.otherCells = -1
+	}
+
+	// the header row of the sheet rolled over to is read
+	w.headerCache = nil
+	if len(headers) > 0 {
+		current, err := w.readHeaders(ctx)
+		if err != nil {
+			return err
+		}
+		if len(current) == 0 {
+			if _, err := w.writeHeaders(ctx, nil, headers); err != nil {
+				return err
+			}
+		}
+	}
+	w.rolloverSeq = seq
+	w.rolloverRows = w.rowCount
+	w.lastRow = 0
+	w.width = 0
+
+	err := w.rolloverState.setTarget(w.rolloverKey(), rolloverTarget{SpreadsheetID: w.spreadsheetID, SheetName: w.sheetName, Seq: seq})
+	if err != nil {
+		return err
+	}
+	sdk.Logger(ctx).Info().
+		Int64("cells", cells).
+		Int64("rollover_cells", w.rollover.Cells).
+		Str("previous_spreadsheet_id", previousSpreadsheetID).
+		Str("previous_sheet_name", previousSheetName).
+		Str("spreadsheet_id", w.spreadsheetID).
+		Str("sheet_name", w.sheetName).
+		Msg("rolled over the sheet written to")
+	return nil
+}
+
+// addSpreadsheet creates the spreadsheet named after the configured spreadsheet with the sequence number, holding
+// the sheet written to with the configured layout. The spreadsheet is shared like the configured spreadsheet.
+func (w *Writer) addSpreadsheet(ctx context.Context, seq int64) error {
+	base, err := w.sheetSvc.Spreadsheets.Get(w.baseSpreadsheetID).Fields("properties.title").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting spreadsheet(%s) metadata: %w", w.baseSpreadsheetID, err)
+	}
+	var title string
+	if base.Properties != nil {
+		title = base.Properties.Title
+	}
+
+	spreadsheet, err := w.sheetSvc.Spreadsheets.Create(&sheets.Spreadsheet{
+		Properties: &sheets.SpreadsheetProperties{Title: fmt.Sprintf("%s_%04d", title, seq)},
+		Sheets: []*sheets.Sheet{{
+			Properties: &sheets.SheetProperties{
+				Title: w.baseSheetName,
+				GridProperties: &sheets.GridProperties{
+					RowCount:       w.layout.RowCount,
+					ColumnCount:    w.layout.ColumnCount,
+					FrozenRowCount: w.layout.FrozenRows,
+				},
+			},
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error creating the spreadsheet rolled over to from spreadsheet(%s): %w", w.baseSpreadsheetID, err)
+	}
+	if len(spreadsheet.Sheets) == 0 || spreadsheet.Sheets[0].Properties == nil {
+		return fmt.Errorf("error creating spreadsheet(%s): no sheet properties returned", spreadsheet.SpreadsheetId)
+	}
+	w.spreadsheetID = spreadsheet.SpreadsheetId
+	w.sheetName = spreadsheet.Sheets[0].Properties.Title
+	w.setSheetProperties(spreadsheet.Sheets[0].Properties)
+	w.otherCells = 0
+	if err := w.shareLikeBase(ctx); err != nil {
+		// the spreadsheet is written to anyway, it is created again on every write otherwise
+		sdk.Logger(ctx).Error().Err(err).
+			Str("spreadsheet_id", w.spreadsheetID).
+			Str("base_spreadsheet_id", w.baseSpreadsheetID).
+			Msg("unable to share the spreadsheet rolled over to like the configured spreadsheet, it must be shared manually")
+	}
+	return w.setColumnWidths(ctx)
+}
+
+// shareLikeBase moves the spreadsheet written to into the folders of the configured spreadsheet, and shares it with
+// the users, groups and domains the configured spreadsheet is shared with. The spreadsheet is created in the Drive
+// of the authenticated account, which only the account, e.g. a service account, can access otherwise. The owner
+// of the configured spreadsheet is granted write access, as the ownership is not transferred.
+func (w *Writer) shareLikeBase(ctx context.Context) error {
+	if w.driveSvc == nil {
+		return errors.New("drive service not initialized")
+	}
+	base, err := w.driveSvc.Files.Get(w.baseSpreadsheetID).Fields("parents,driveId").SupportsAllDrives(true).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting spreadsheet(%s) metadata: %w", w.baseSpreadsheetID, err)
+	}
+	if len(base.Parents) > 0 {
+		created, err := w.driveSvc.Files.Get(w.spreadsheetID).Fields("parents").SupportsAllDrives(true).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error getting spreadsheet(%s) metadata: %w", w.spreadsheetID, err)
+		}
+		_, err = w.driveSvc.Files.Update(w.spreadsheetID, &drive.File{}).
+			AddParents(strings.Join(base.Parents, ",")).
+			RemoveParents(strings.Join(created.Parents, ",")).
+			SupportsAllDrives(true).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("error moving spreadsheet(%s) to the folders of spreadsheet(%s): %w", w.spreadsheetID, w.baseSpreadsheetID, err)
+		}
+	}
+	if base.DriveId != "" {
+		// the spreadsheet inherits the permissions of the shared drive it is moved to
+		return nil
+	}
+
+	permissions, err := w.listPermissions(ctx, w.baseSpreadsheetID)
+	if err != nil {
+		return err
+	}
+	// the authenticated account owns the spreadsheet already
+	owners, err := w.listPermissions(ctx, w.spreadsheetID)
+	if err != nil {
+		return err
+	}
+	shared := make(map[string]bool, len(owners))
+	for _, permission := range owners {
+		shared[permission.EmailAddress] = true
+	}
+	for _, permission := range permissions {
+		if permission.EmailAddress != "" && shared[permission.EmailAddress] {
+			continue
+		}
+		role := permission.Role
+		if role == "owner" {
+			role = "writer"
+		}
+		call := w.driveSvc.Permissions.Create(w.spreadsheetID, &drive.Permission{
+			Type:               permission.Type,
+			Role:               role,
+			EmailAddress:       permission.EmailAddress,
+			Domain:             permission.Domain,
+			AllowFileDiscovery: permission.AllowFileDiscovery,
+		}).SupportsAllDrives(true)
+		if permission.Type == "user" || permission.Type == "group" {
+			call = call.SendNotificationEmail(false)
+		}
+		if _, err := call.Context(ctx).Do(); err != nil {
+			return fmt.Errorf("error sharing spreadsheet(%s) with %s %s: %w",
+				w.spreadsheetID, permission.Type, permission.EmailAddress+permission.Domain, err)
+		}
+	}
+	return nil
+}
+
+// listPermissions returns the permissions of the file
+func (w *Writer) listPermissions(ctx context.Context, fileID string) ([]*drive.Permission, error) {
+	var permissions []*drive.Permission
+	err := w.driveSvc.Permissions.List(fileID).
+		Fields("nextPageToken,permissions(type,role,emailAddress,domain,allowFileDiscovery)").
+		SupportsAllDrives(true).Pages(ctx, func(list *drive.PermissionList) error {
+		permissions = append(permissions, list.Permissions...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing the permissions of spreadsheet(%s): %w", fileID, err)
+	}
+	return permissions, nil
+}
+
+// countOtherCells returns the number of cells of the grids of the other sheets of the spreadsheet
+func (w *Writer) countOtherCells(ctx context.Context) (int64, error) {
+	spreadsheet, err := w.sheetSvc.Spreadsheets.Get(w.spreadsheetID).
+		Fields("sheets.properties(sheetId,title,gridProperties(rowCount,columnCount))").
+		Context(ctx).Do()
+	if err != nil {
+		return 0, fmt.Errorf("error getting spreadsheet(%s) metadata: %w", w.spreadsheetID, err)
+	}
+	var cells int64
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil || sheet.Properties.GridProperties == nil || sheet.Properties.SheetId == w.sheetID {
+			continue
+		}
+		cells += sheet.Properties.GridProperties.RowCount * sheet.Properties.GridProperties.ColumnCount
+	}
+	return cells, nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeRollover serves the header rows of the sheets, the sheets and spreadsheets created, the Drive folders and
+// permissions of the spreadsheets, and captures the requests made in order
+type fakeRollover struct {
+	t       *testing.T
+	headers map[string][]interface{}
+	// notesRows is the number of rows of the other sheet, 1 if not set
+	notesRows int64
+	requests  []string
+	// shared are the permissions created on the spreadsheet rolled over to
+	shared []*drive.Permission
+}
+
+func (f *fakeRollover) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	path := strings.TrimPrefix(r.URL.Path, "/v4/spreadsheets")
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/files/"):
+		f.serveDrive(w, r, body)
+	case r.Method == http.MethodPost && path == "":
+		f.requests = append(f.requests, "create")
+		var spreadsheet sheets.Spreadsheet
+		assert.NoError(f.t, json.Unmarshal(body, &spreadsheet))
+		assert.Equal(f.t, "Audit_0002", spreadsheet.Properties.Title)
+		spreadsheet.SpreadsheetId = "dummy2"
+		spreadsheet.Sheets[0].Properties.SheetId = 0
+		spreadsheet.Sheets[0].Properties.GridProperties = &sheets.GridProperties{RowCount: 1000, ColumnCount: 26}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&spreadsheet))
+	case r.Method == http.MethodGet && path == "/dummy":
+		f.requests = append(f.requests, "get")
+		notesRows := f.notesRows
+		if notesRows == 0 {
+			notesRows = 1
+		}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&sheets.Spreadsheet{
+			Properties: &sheets.SpreadsheetProperties{Title: "Audit"},
+			Sheets: []*sheets.Sheet{
+				{Properties: &sheets.SheetProperties{SheetId: 5, Title: "Log", GridProperties: &sheets.GridProperties{RowCount: 10, ColumnCount: 2}}},
+				{Properties: &sheets.SheetProperties{SheetId: 6, Title: "Notes", GridProperties: &sheets.GridProperties{RowCount: notesRows, ColumnCount: 2}}},
+			},
+		}))
+	case r.Method == http.MethodPost && path == "/dummy:batchUpdate":
+		f.requests = append(f.requests, "batchUpdate")
+		var req sheets.BatchUpdateSpreadsheetRequest
+		assert.NoError(f.t, json.Unmarshal(body, &req))
+		properties := req.Requests[0].AddSheet.Properties
+		properties.SheetId = 9
+		properties.GridProperties = &sheets.GridProperties{RowCount: 1000, ColumnCount: 26}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&sheets.BatchUpdateSpreadsheetResponse{
+			Replies: []*sheets.Response{{AddSheet: &sheets.AddSheetResponse{Properties: properties}}},
+		}))
+	case strings.Contains(path, "/values/"):
+		valuesRange := path[strings.Index(path, "/values/")+len("/values/"):]
+		request := strings.ToLower(r.Method) + " " + strings.TrimPrefix(path, "/")
+		f.requests = append(f.requests, request[:strings.Index(request, "/")]+" "+valuesRange)
+		if r.Method == http.MethodGet {
+			header := f.headers[strings.TrimSuffix(valuesRange, "!1:1")]
+			assert.NoError(f.t, json.NewEncoder(w).Encode(&sheets.ValueRange{Values: [][]interface{}{header}}))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// serveDrive serves the Drive files and permissions, the configured spreadsheet is in a folder and is shared
+// with its owner, a group and a domain
+func (f *fakeRollover) serveDrive(w http.ResponseWriter, r *http.Request, body []byte) {
+	path := strings.TrimPrefix(r.URL.Path, "/files/")
+	f.requests = append(f.requests, "drive "+strings.ToLower(r.Method)+" "+path)
+	switch {
+	case r.Method == http.MethodGet && path == "dummy":
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.File{Parents: []string{"folder"}}))
+	case r.Method == http.MethodGet && path == "dummy2":
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.File{Parents: []string{"root"}}))
+	case r.Method == http.MethodPatch && path == "dummy2":
+		assert.Equal(f.t, "folder", r.URL.Query().Get("addParents"))
+		assert.Equal(f.t, "root", r.URL.Query().Get("removeParents"))
+		_, _ = w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet && path == "dummy/permissions":
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.PermissionList{Permissions: []*drive.Permission{
+			{Type: "user", Role: "owner", EmailAddress: "owner@example.com"},
+			{Type: "user", Role: "writer", EmailAddress: "connector@example.com"},
+			{Type: "group", Role: "reader", EmailAddress: "team@example.com"},
+			{Type: "domain", Role: "reader", Domain: "example.com"},
+		}}))
+	case r.Method == http.MethodGet && path == "dummy2/permissions":
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.PermissionList{Permissions: []*drive.Permission{
+			{Type: "user", Role: "owner", EmailAddress: "connector@example.com"},
+		}}))
+	case r.Method == http.MethodPost && path == "dummy2/permissions":
+		var permission drive.Permission
+		assert.NoError(f.t, json.Unmarshal(body, &permission))
+		f.shared = append(f.shared, &permission)
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newFakeRolloverWriter(t *testing.T, fake *fakeRollover, rollover Rollover) *Writer {
+	testServer := httptest.NewServer(fake)
+	t.Cleanup(testServer.Close)
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	driveSvc, err := drive.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	w := &Writer{
+		sheetSvc:         sheetSvc,
+		driveSvc:         driveSvc,
+		sheetName:        "Log",
+		spreadsheetID:    "dummy",
+		valueInputOption: "RAW",
+		headerRow:        1,
+		writeMode:        WriteModeAppend,
+		sheetID:          5,
+		rowCount:         10,
+		columnCount:      2,
+		rollover:         rollover,
+		rolloverState:    NewRolloverState(rollover.State),
+		otherCells:       -1,
+	}
+	assert.NoError(t, w.restoreRollover(context.Background()))
+	return w
+}
+
+func TestWriter_RollOverToSheet(t *testing.T) {
+	fake := &fakeRollover{t: t, headers: map[string][]interface{}{"'Log'": {"id"}}}
+	state := RolloverStateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	writer := newFakeRolloverWriter(t, fake, Rollover{Cells: 25, To: RolloverToSheet, State: state})
+	records := []sdk.Record{{Payload: sdk.RawData(`["1"]`)}, {Payload: sdk.RawData(`["2"]`)}, {Payload: sdk.RawData(`["3"]`)}}
+
+	// the 20 cells of the grid and the 6 cells of the rows exceed the rollover cells
+	err := writer.Write(context.Background(), records)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"get", // the cells of the other sheets are counted against the spreadsheet limit
+		"get dummy 'Log'!1:1",
+		"get", "batchUpdate", // the sheet is created
+		"get dummy 'Log_0002'!1:1", "put dummy 'Log_0002'!1:1", // the header row is copied
+		"post dummy Log_0002:append",
+	}, fake.requests)
+	assert.Equal(t, "Log_0002", writer.sheetName)
+	assert.Equal(t, int64(1003), writer.rowCount)
+
+	content, err := state.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]rolloverTarget{
+		"dummy/Log": {SpreadsheetID: "dummy", SheetName: "Log_0002", Seq: 2},
+	}, content.Targets)
+
+	// the sheet rolled over to is written to after a restart
+	restored := newFakeRolloverWriter(t, fake, Rollover{Cells: 25, To: RolloverToSheet, State: state})
+	assert.Equal(t, "Log_0002", restored.sheetName)
+	assert.Equal(t, int64(2), restored.rolloverSeq)
+}
+
+func TestWriter_RollOverToSheet_SpreadsheetFull(t *testing.T) {
+	fake := &fakeRollover{t: t, notesRows: MaxSpreadsheetCells / 2}
+	state := RolloverStateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	writer := newFakeRolloverWriter(t, fake, Rollover{Cells: 25, To: RolloverToSheet, State: state})
+
+	// the cells of the other sheet fill the spreadsheet, a new sheet would exceed its limit
+	err := writer.Write(context.Background(), []sdk.Record{{Payload: sdk.RawData(`["1"]`)}})
+	assert.ErrorContains(t, err, "would exceed the 10000000 cells limit of spreadsheet(dummy)")
+	assert.Equal(t, []string{"get"}, fake.requests)
+	assert.Equal(t, "Log", writer.sheetName)
+}
+
+func TestWriter_RollOverToSpreadsheet(t *testing.T) {
+	fake := &fakeRollover{t: t}
+	state := RolloverStateFile{Path: filepath.Join(t.TempDir(), "state.json")}
+	writer := newFakeRolloverWriter(t, fake, Rollover{Cells: 25, To: RolloverToSpreadsheet, State: state})
+	writer.headerRow = 0
+
+	// the 20 cells of the sheet and the 2 cells of the other sheet don't exceed the rollover cells
+	err := writer.Write(context.Background(), []sdk.Record{{Payload: sdk.RawData(`["1"]`)}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"get", "post dummy Log:append"}, fake.requests)
+
+	fake.requests = nil
+	err = writer.Write(context.Background(), []sdk.Record{{Payload: sdk.RawData(`["2"]`)}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"get", "create",
+		"drive get dummy", "drive get dummy2", "drive patch dummy2", // the spreadsheet is moved to the folder
+		"drive get dummy/permissions", "drive get dummy2/permissions",
+		"drive post dummy2/permissions", "drive post dummy2/permissions", "drive post dummy2/permissions",
+		"post dummy2 Log:append",
+	}, fake.requests)
+	assert.Equal(t, "dummy2", writer.spreadsheetID)
+	assert.Equal(t, "Log", writer.sheetName)
+	// the spreadsheet is shared like the configured spreadsheet, the owner can write to it
+	assert.Equal(t, []*drive.Permission{
+		{Type: "user", Role: "writer", EmailAddress: "owner@example.com"},
+		{Type: "group", Role: "reader", EmailAddress: "team@example.com"},
+		{Type: "domain", Role: "reader", Domain: "example.com"},
+	}, fake.shared)
+
+	content, err := state.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]rolloverTarget{
+		"dummy/Log": {SpreadsheetID: "dummy2", SheetName: "Log", Seq: 2},
+	}, content.Targets)
+}
+
+func TestWriter_RollOver_SharedState(t *testing.T) {
+	fake := &fakeRollover{t: t}
+	state := NewRolloverState(RolloverStateFile{Path: filepath.Join(t.TempDir(), "state.json")})
+	rollover := Rollover{Cells: 25, To: RolloverToSheet, State: state.file}
+	log := newFakeRolloverWriter(t, fake, rollover)
+	notes := newFakeRolloverWriter(t, fake, rollover)
+	log.rolloverState, log.headerRow = state, 0
+	notes.rolloverState, notes.headerRow = state, 0
+	notes.sheetName, notes.sheetID = "Notes", 6
+	assert.NoError(t, notes.restoreRollover(context.Background()))
+
+	// the writers roll over one after the other, the target of each writer is kept
+	records := []sdk.Record{{Payload: sdk.RawData(`["1"]`)}, {Payload: sdk.RawData(`["2"]`)}, {Payload: sdk.RawData(`["3"]`)}}
+	assert.NoError(t, log.Write(context.Background(), records))
+	assert.NoError(t, notes.Write(context.Background(), records))
+
+	content, err := state.file.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]rolloverTarget{
+		"dummy/Log":   {SpreadsheetID: "dummy", SheetName: "Log_0002", Seq: 2},
+		"dummy/Notes": {SpreadsheetID: "dummy", SheetName: "Notes_0002", Seq: 2},
+	}, content.Targets)
+}
@@ -0,0 +1,375 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+const (
+	// SchemaFormatAvro is the schema format of the Avro record schemas
+	SchemaFormatAvro = "avro"
+	// SchemaFormatJSON is the schema format of the JSON Schema documents
+	SchemaFormatJSON = "json"
+
+	// MetadataSchemaFormat is the metadata key for the format of the schema definition, SchemaFormatAvro or SchemaFormatJSON
+	MetadataSchemaFormat = "google.sheets.schemaFormat"
+	// MetadataSchemaDefinition is the metadata key for the schema definition of the sheet the record was read from
+	MetadataSchemaDefinition = "google.sheets.schemaDefinition"
+	// MetadataSchemaVersion is the metadata key for the version of the schema, incremented when the columns change
+	MetadataSchemaVersion = "google.sheets.schemaVersion"
+
+	jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+)
+
+// avroInvalidChars matches the characters not allowed in Avro names
+var avroInvalidChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// trackSchema builds the schema definition of the sheet from the header names and the column types. The version
+// of the schema is incremented when the columns change, i.e. a column is added, renamed or its type changes.
+func (b *BatchReader) trackSchema(ctx context.Context, sheetRange SheetRange) {
+	if b.schemaFormat == "" {
+		return
+	}
+	sheetID := sheetRange.GridRange.SheetId
+	columns := b.schemaColumns(sheetRange)
+	flat := make([]interface{}, 0, len(columns)*2)
+	for _, column := range columns {
+		flat = append(flat, column.Name, column.Type)
+	}
+	hash := fingerprint(flat)
+
+	last, ok := b.schemaVersions[sheetID]
+	if ok && last.Fingerprint == hash {
+		if _, built := b.definitions[sheetID]; built {
+			return
+		}
+	}
+	version := last
+	if !ok || last.Fingerprint != hash {
+		version = position.SchemaVersion{Version: last.Version + 1, Fingerprint: hash}
+	}
+
+	var definition interface{}
+	if b.schemaFormat == SchemaFormatAvro {
+		definition = avroSchema(sheetRange, columns)
+	} else {
+		formatType := b.payloadFormat().Type
+		definition = jsonSchema(sheetRange, columns, formatType == FormatStructured || formatType == FormatJSONObject)
+	}
+	data, err := json.Marshal(definition)
+	if err != nil {
+		// not expected, the definitions are made of strings
+		return
+	}
+
+	if b.schemaVersions == nil {
+		b.schemaVersions = make(map[int64]position.SchemaVersion)
+	}
+	if b.definitions == nil {
+		b.definitions = make(map[int64]string)
+	}
+	b.schemaVersions[sheetID] = version
+	b.definitions[sheetID] = string(data)
+	if ok && version.Version != last.Version {
+		sdk.Logger(ctx).Info().
+			Int64("sheet_id", sheetID).
+			Int64("schema_version", version.Version).
+			Msg("sheet schema changed")
+	}
+}
+
+// loadSchemaVersions loads the schema versions from the position on the first read
+func (b *BatchReader) loadSchemaVersions(pos position.SheetPosition) {
+	if b.schemaFormat == "" || b.schemaVersions != nil {
+		return
+	}
+	b.schemaVersions = make(map[int64]position.SchemaVersion, len(pos.Schemas))
+	for sheetID, version := range pos.Schemas {
+		b.schemaVersions[sheetID] = version
+	}
+}
+
+// setSchemaMetadata sets the schema definition and version of the sheet in the record metadata
+func (b *BatchReader) setSchemaMetadata(metadata map[string]string, sheetID int64) {
+	definition, ok := b.definitions[sheetID]
+	if b.schemaFormat == "" || !ok {
+		return
+	}
+	metadata[MetadataSchemaFormat] = b.schemaFormat
+	metadata[MetadataSchemaDefinition] = definition
+	metadata[MetadataSchemaVersion] = strconv.FormatInt(b.schemaVersions[sheetID].Version, 10)
+}
+
+// schemaName returns the name of the sheet used in the schema definitions
+func schemaName(sheetRange SheetRange) string {
+	if sheetRange.Title != "" {
+		return sheetRange.Title
+	}
+	return "sheet_" + strconv.FormatInt(sheetRange.GridRange.SheetId, 10)
+}
+
+type avroRecord struct {
+	Type      string      `json:"type"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Doc       string      `json:"doc,omitempty"`
+	Fields    []avroField `json:"fields"`
+}
+
+type avroField struct {
+	Name    string      `json:"name"`
+	Doc     string      `json:"doc,omitempty"`
+	Type    interface{} `json:"type"`
+	Default interface{} `json:"default"`
+}
+
+// avroSchema returns the Avro record schema of the columns, the fields are nullable. The column names are
+// sanitized to valid Avro names, the original column name is set as the doc of the fields.
+func avroSchema(sheetRange SheetRange, columns []SchemaColumn) avroRecord {
+	record := avroRecord{
+		Type:      "record",
+		Name:      avroName(schemaName(sheetRange), nil),
+		Namespace: "google.sheets",
+		Doc:       sheetRange.Title,
+		Fields:    make([]avroField, len(columns)),
+	}
+	used := make(map[string]bool, len(columns))
+	for i, column := range columns {
+		fieldType := []interface{}{"null", avroType(column.Type)}
+		if column.Type == ColumnAny {
+			fieldType = []interface{}{"null", "boolean", "long", "double", "string"}
+		}
+		record.Fields[i] = avroField{Name: avroName(column.Name, used), Doc: column.Name, Type: fieldType}
+	}
+	return record
+}
+
+// avroName returns the name sanitized to a valid Avro name, made unique among the used names if used is set
+func avroName(name string, used map[string]bool) string {
+	name = avroInvalidChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	if used == nil {
+		return name
+	}
+	unique := name
+	for n := 2; used[unique]; n++ {
+		unique = name + "_" + strconv.Itoa(n)
+	}
+	used[unique] = true
+	return unique
+}
+
+// avroType returns the Avro type of the column type, the dates and times are strings as they are read as text
+func avroType(columnType string) string {
+	switch columnType {
+	case ColumnInteger:
+		return "long"
+	case ColumnDecimal:
+		return "double"
+	case ColumnBoolean:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// jsonSchema returns the JSON Schema document of the rows, an object keyed by the column names if the payloads
+// are objects, an array of the column values otherwise. The values are nullable.
+func jsonSchema(sheetRange SheetRange, columns []SchemaColumn, object bool) map[string]interface{} {
+	schema := map[string]interface{}{
+		"$schema": jsonSchemaDraft,
+		"title":   schemaName(sheetRange),
+	}
+	if object {
+		properties := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			properties[column.Name] = jsonSchemaType(column.Type)
+		}
+		schema["type"] = "object"
+		schema["properties"] = properties
+		return schema
+	}
+	items := make([]interface{}, len(columns))
+	for i, column := range columns {
+		item := jsonSchemaType(column.Type)
+		item["title"] = column.Name
+		items[i] = item
+	}
+	schema["type"] = "array"
+	schema["prefixItems"] = items
+	return schema
+}
+
+// jsonSchemaType returns the JSON Schema of the values of the column type
+func jsonSchemaType(columnType string) map[string]interface{} {
+	switch columnType {
+	case ColumnInteger:
+		return map[string]interface{}{"type": []string{"integer", "null"}}
+	case ColumnDecimal:
+		return map[string]interface{}{"type": []string{"number", "null"}}
+	case ColumnBoolean:
+		return map[string]interface{}{"type": []string{"boolean", "null"}}
+	case ColumnDate, ColumnTimestamp:
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"}
+	case ColumnTime:
+		return map[string]interface{}{"type": []string{"string", "null"}, "format": "time"}
+	case ColumnString:
+		return map[string]interface{}{"type": []string{"string", "null"}}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// Schema is a declared schema of the rows, the columns in the format of the google.sheets.schema metadata
+type Schema []SchemaColumn
+
+// ParseSchema parses the JSON array of the schema columns, e.g. [{"name":"id","type":"integer"}]
+func ParseSchema(data string) (Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal([]byte(data), &schema); err != nil {
+		return nil, fmt.Errorf("error parsing the schema: %w", err)
+	}
+	used := make(map[string]bool, len(schema))
+	for _, column := range schema {
+		if strings.TrimSpace(column.Name) == "" {
+			return nil, fmt.Errorf("schema column name must be set")
+		}
+		if used[column.Name] {
+			return nil, fmt.Errorf("schema column %q is declared more than once", column.Name)
+		}
+		used[column.Name] = true
+		switch column.Type {
+		case ColumnString, ColumnInteger, ColumnDecimal, ColumnBoolean, ColumnTimestamp, ColumnDate, ColumnTime, ColumnAny:
+		default:
+			return nil, fmt.Errorf("schema column %q has an invalid type %q", column.Name, column.Type)
+		}
+	}
+	return schema, nil
+}
+
+// Validate validates the payload of the record, parsed in the format, against the schema. The fields of structured and JSON object
+// payloads must be declared columns, the values of JSON array payloads are matched to the columns by position.
+// Null values match any type.
+func (s Schema) Validate(format Format, record sdk.Record) error {
+	row, fields, err := format.Decode(record.Payload)
+	if err != nil {
+		return err
+	}
+	if fields != nil {
+		columns := make(map[string]SchemaColumn, len(s))
+		for _, column := range s {
+			columns[column.Name] = column
+		}
+		for _, name := range sortedFieldNames(fields) {
+			column, ok := columns[name]
+			if !ok {
+				return fmt.Errorf("field %q is not declared in the schema", name)
+			}
+			if err := validateValue(fields[name], column.Type); err != nil {
+				return fmt.Errorf("field %q: %w", name, err)
+			}
+		}
+		return nil
+	}
+	if len(row) > len(s) {
+		return fmt.Errorf("row has %d values, the schema declares %d columns", len(row), len(s))
+	}
+	for i, value := range row {
+		if err := validateValue(value, s[i].Type); err != nil {
+			return fmt.Errorf("column %q: %w", s[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// validateValue validates that the value matches the column type
+func validateValue(value interface{}, columnType string) error {
+	if value == nil {
+		return nil
+	}
+	valid := true
+	switch columnType {
+	case ColumnString:
+		_, valid = value.(string)
+	case ColumnBoolean:
+		_, valid = value.(bool)
+	case ColumnInteger:
+		var n float64
+		n, valid = number(value)
+		valid = valid && n == math.Trunc(n)
+	case ColumnDecimal:
+		_, valid = number(value)
+	case ColumnDate, ColumnTimestamp:
+		var text string
+		if text, valid = value.(string); valid {
+			_, err := time.Parse(time.RFC3339, text)
+			valid = err == nil
+		}
+	case ColumnTime:
+		var text string
+		if text, valid = value.(string); valid {
+			_, err := time.Parse("15:04:05", text)
+			valid = err == nil
+		}
+	}
+	if !valid {
+		return fmt.Errorf("value %v is not a valid %s", value, columnType)
+	}
+	return nil
+}
+
+// number returns the numeric value, and whether the value is a number
+func number(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func sortedFieldNames(fields map[string]interface{}) []string {
+	set := make(map[string]bool, len(fields))
+	for name := range fields {
+		set[name] = true
+	}
+	return sortedKeys(set)
+}
@@ -0,0 +1,193 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestAvroSchema(t *testing.T) {
+	sheetRange := SheetRange{Title: "Q3 Orders", GridRange: &sheets.GridRange{SheetId: 42}}
+	columns := []SchemaColumn{
+		{Name: "order id", Type: ColumnInteger},
+		{Name: "2nd price", Type: ColumnDecimal},
+		{Name: "order-id", Type: ColumnAny},
+	}
+	data, err := json.Marshal(avroSchema(sheetRange, columns))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"type": "record",
+		"name": "Q3_Orders",
+		"namespace": "google.sheets",
+		"doc": "Q3 Orders",
+		"fields": [
+			{"name": "order_id", "doc": "order id", "type": ["null", "long"], "default": null},
+			{"name": "_2nd_price", "doc": "2nd price", "type": ["null", "double"], "default": null},
+			{"name": "order_id_2", "doc": "order-id", "type": ["null", "boolean", "long", "double", "string"], "default": null}
+		]
+	}`, string(data))
+}
+
+func TestJSONSchema(t *testing.T) {
+	sheetRange := SheetRange{GridRange: &sheets.GridRange{SheetId: 42}}
+	columns := []SchemaColumn{{Name: "A", Type: ColumnString}, {Name: "B", Type: ColumnTimestamp}}
+
+	data, err := json.Marshal(jsonSchema(sheetRange, columns, true))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "sheet_42",
+		"type": "object",
+		"properties": {
+			"A": {"type": ["string", "null"]},
+			"B": {"type": ["string", "null"], "format": "date-time"}
+		}
+	}`, string(data))
+
+	// the rows are JSON arrays without a header row, or in the jsonArray and csv formats
+	data, err = json.Marshal(jsonSchema(sheetRange, columns, false))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title": "sheet_42",
+		"type": "array",
+		"prefixItems": [
+			{"title": "A", "type": ["string", "null"]},
+			{"title": "B", "type": ["string", "null"], "format": "date-time"}
+		]
+	}`, string(data))
+}
+
+func TestBatchReader_trackSchema(t *testing.T) {
+	ctx := context.Background()
+	sheetRange := SheetRange{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42}}
+	br := &BatchReader{schemaFormat: SchemaFormatJSON, headerRow: 1}
+	br.loadSchemaVersions(position.SheetPosition{Schemas: map[int64]position.SchemaVersion{
+		42: {Version: 3, Fingerprint: "stale"},
+	}})
+
+	// the version loaded from the position is incremented, as the columns differ
+	br.trackHeaders(ctx, sheetRange, []interface{}{"id", "name"})
+	br.trackSchema(ctx, sheetRange)
+	metadata := make(map[string]string)
+	br.setSchemaMetadata(metadata, 42)
+	assert.Equal(t, SchemaFormatJSON, metadata[MetadataSchemaFormat])
+	assert.Equal(t, "4", metadata[MetadataSchemaVersion])
+	assert.Contains(t, metadata[MetadataSchemaDefinition], `"name":{"type":["string","null"]}`)
+
+	// the version is kept while the columns are unchanged
+	br.trackSchema(ctx, sheetRange)
+	assert.Equal(t, int64(4), br.schemaVersions[42].Version)
+
+	// a renamed column creates a new version
+	br.trackHeaders(ctx, sheetRange, []interface{}{"id", "full_name"})
+	br.trackSchema(ctx, sheetRange)
+	br.setSchemaMetadata(metadata, 42)
+	assert.Equal(t, "5", metadata[MetadataSchemaVersion])
+	assert.Contains(t, metadata[MetadataSchemaDefinition], `"full_name"`)
+}
+
+func TestBatchReader_trackSchema_Headerless(t *testing.T) {
+	ctx := context.Background()
+	sheetRange := SheetRange{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 42, StartColumnIndex: 1}}
+	br := &BatchReader{schemaFormat: SchemaFormatJSON}
+
+	// the columns are counted from the widest row, named by the column letters
+	br.trackWidth(sheetRange, [][]interface{}{{"1"}, {"2", "b", "x"}})
+	br.trackSchema(ctx, sheetRange)
+	assert.Equal(t, []SchemaColumn{
+		{Name: "B", Type: ColumnString}, {Name: "C", Type: ColumnString}, {Name: "D", Type: ColumnString},
+	}, br.schemaColumns(sheetRange))
+	assert.Equal(t, int64(1), br.schemaVersions[42].Version)
+
+	// narrower rows keep the columns
+	br.trackWidth(sheetRange, [][]interface{}{{"3"}})
+	br.trackSchema(ctx, sheetRange)
+	assert.Len(t, br.schemaColumns(sheetRange), 3)
+	assert.Equal(t, int64(1), br.schemaVersions[42].Version)
+
+	// a wider row adds a column
+	br.trackWidth(sheetRange, [][]interface{}{{"4", "d", "y", "z"}})
+	br.trackSchema(ctx, sheetRange)
+	metadata := make(map[string]string)
+	br.setSchemaMetadata(metadata, 42)
+	assert.Equal(t, "2", metadata[MetadataSchemaVersion])
+	assert.Contains(t, metadata[MetadataSchemaDefinition], `"title":"E"`)
+}
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema(`[{"name":"id","type":"integer"},{"name":"ordered","type":"date"},{"name":"paid","type":"boolean"}]`)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		payload sdk.Data
+		err     error
+	}{
+		{
+			name:    "structured data",
+			payload: sdk.StructuredData{"id": 1, "ordered": "2022-07-01T00:00:00Z", "paid": true},
+		},
+		{
+			name:    "JSON object with null",
+			payload: sdk.RawData(`{"id":1,"ordered":null}`),
+		},
+		{
+			name:    "JSON array",
+			payload: sdk.RawData(`[1,"2022-07-01T00:00:00Z",false]`),
+		},
+		{
+			name:    "undeclared field",
+			payload: sdk.StructuredData{"id": 1, "total": 5},
+			err:     fmt.Errorf("field \"total\" is not declared in the schema"),
+		},
+		{
+			name:    "fraction in integer column",
+			payload: sdk.RawData(`{"id":1.5}`),
+			err:     fmt.Errorf("field \"id\": value 1.5 is not a valid integer"),
+		},
+		{
+			name:    "invalid date",
+			payload: sdk.RawData(`[1,"yesterday"]`),
+			err:     fmt.Errorf("column \"ordered\": value yesterday is not a valid date"),
+		},
+		{
+			name:    "too many values",
+			payload: sdk.RawData(`[1,null,true,"extra"]`),
+			err:     fmt.Errorf("row has 4 values, the schema declares 3 columns"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Validate(Format{}, sdk.Record{Payload: tc.payload})
+			if tc.err != nil {
+				assert.EqualError(t, err, tc.err.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+// SheetLayout is the layout of the sheets created by the writer, the zero values keep the defaults of Google Sheets
+type SheetLayout struct {
+	// FrozenRows is the number of rows frozen at the top of the sheet, usually the rows up to the header row
+	FrozenRows int64
+	// ColumnWidths are the widths in pixels of the first columns, in order
+	ColumnWidths []int64
+	// RowCount and ColumnCount are the size of the grid of the sheet
+	RowCount    int64
+	ColumnCount int64
+}
+
+// loadSheet reads the properties of the sheet written to. The sheet is created if it's not found and creating
+// the sheet is enabled, an error listing the sheets of the spreadsheet is returned otherwise.
+func (w *Writer) loadSheet(ctx context.Context) error {
+	properties, titles, err := w.findSheet(ctx, w.sheetName)
+	if err != nil {
+		return err
+	}
+	if properties != nil {
+		w.setSheetProperties(properties)
+		return nil
+	}
+	if !w.createSheet {
+		return fmt.Errorf("sheet %q not found in spreadsheet(%s), sheets found: %q", w.sheetName, w.spreadsheetID, titles)
+	}
+	return w.addSheet(ctx)
+}
+
+// findSheet returns the properties of the sheet with the title, nil if it's not found, along with the titles of the sheets
+func (w *Writer) findSheet(ctx context.Context, title string) (*sheets.SheetProperties, []string, error) {
+	spreadsheet, err := w.sheetSvc.Spreadsheets.Get(w.spreadsheetID).
+		Fields("sheets.properties(sheetId,title,gridProperties(rowCount,columnCount))").
+		Context(ctx).Do()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting spreadsheet(%s) metadata: %w", w.spreadsheetID, err)
+	}
+	titles := make([]string, 0, len(spreadsheet.Sheets))
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil {
+			continue
+		}
+		if sheet.Properties.Title == title {
+			return sheet.Properties, nil, nil
+		}
+		titles = append(titles, sheet.Properties.Title)
+	}
+	return nil, titles, nil
+}
+
+// addSheet creates the sheet written to with the configured layout
+func (w *Writer) addSheet(ctx context.Context) error {
+	res, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: []*sheets.Request{{
+			AddSheet: &sheets.AddSheetRequest{
+				Properties: &sheets.SheetProperties{
+					Title: w.sheetName,
+					GridProperties: &sheets.GridProperties{
+						RowCount:       w.layout.RowCount,
+						ColumnCount:    w.layout.ColumnCount,
+						FrozenRowCount: w.layout.FrozenRows,
+					},
+				},
+			},
+		}},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error creating sheet(%s) in spreadsheet(%s): %w", w.sheetName, w.spreadsheetID, err)
+	}
+	if len(res.Replies) == 0 || res.Replies[0].AddSheet == nil || res.Replies[0].AddSheet.Properties == nil {
+		return fmt.Errorf("error creating sheet(%s) in spreadsheet(%s): no sheet properties returned", w.sheetName, w.spreadsheetID)
+	}
+	w.setSheetProperties(res.Replies[0].AddSheet.Properties)
+
+	// the widths are set once the sheet gid is known
+	if err := w.setColumnWidths(ctx); err != nil {
+		return err
+	}
+
+	sdk.Logger(ctx).Info().
+		Str("sheet_name", w.sheetName).
+		Int64("sheet_id", w.sheetID).
+		Msg("created sheet")
+	return nil
+}
+
+// setColumnWidths sets the configured widths of the first columns of the sheet written to
+func (w *Writer) setColumnWidths(ctx context.Context) error {
+	if len(w.layout.ColumnWidths) == 0 {
+		return nil
+	}
+	requests := make([]*sheets.Request, len(w.layout.ColumnWidths))
+	for i, width := range w.layout.ColumnWidths {
+		requests[i] = &sheets.Request{
+			UpdateDimensionProperties: &sheets.UpdateDimensionPropertiesRequest{
+				Range: &sheets.DimensionRange{
+					SheetId:    w.sheetID,
+					Dimension:  "COLUMNS",
+					StartIndex: int64(i),
+					EndIndex:   int64(i) + 1,
+				},
+				Properties: &sheets.DimensionProperties{PixelSize: width},
+				Fields:     "pixelSize",
+			},
+		}
+	}
+	_, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+		Requests: requests,
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error setting the column widths of sheet(%s): %w", w.sheetName, err)
+	}
+	return nil
+}
+
+// setSheetProperties sets the gid and the grid size of the sheet written to
+func (w *Writer) setSheetProperties(properties *sheets.SheetProperties) {
+	w.sheetID = properties.SheetId
+	if properties.GridProperties != nil {
+		w.rowCount = properties.GridProperties.RowCount
+		w.columnCount = properties.GridProperties.ColumnCount
+	}
+}
+
+// initHeaders writes the configured headers to the header row, if the header row is empty.
+// Without configured headers, the header row is written from the fields of the first record.
+func (w *Writer) initHeaders(ctx context.Context) error {
+	if w.headerRow == 0 || len(w.headers) == 0 {
+		return nil
+	}
+	headers, err := w.readHeaders(ctx)
+	if err != nil {
+		return err
+	}
+	if len(headers) > 0 {
+		return nil
+	}
+	_, err = w.writeHeaders(ctx, nil, w.headers)
+	return err
+}
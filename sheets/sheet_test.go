@@ -0,0 +1,126 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestWriter_loadSheet_Create(t *testing.T) {
+	var batchUpdates []sheets.BatchUpdateSpreadsheetRequest
+	var headers sheets.ValueRange
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy":
+			_, _ = w.Write([]byte(`{"sheets": [{"properties": {"sheetId": 1, "title": "Sheet1"}}]}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/dummy:batchUpdate":
+			var req sheets.BatchUpdateSpreadsheetRequest
+			assert.NoError(t, json.Unmarshal(body, &req))
+			batchUpdates = append(batchUpdates, req)
+			_, _ = w.Write([]byte(`{"replies": [{"addSheet": {"properties": {"sheetId": 7, "title": "Orders", "gridProperties": {"rowCount": 100}}}}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy/values/'Orders'!1:1":
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/v4/spreadsheets/dummy/values/'Orders'!1:1":
+			assert.NoError(t, json.Unmarshal(body, &headers))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+
+	writer := &Writer{
+		sheetSvc:      sheetSvc,
+		sheetName:     "Orders",
+		spreadsheetID: "dummy",
+		headerRow:     1,
+		createSheet:   true,
+		layout:        SheetLayout{FrozenRows: 1, ColumnWidths: []int64{120}, RowCount: 100},
+		headers:       []string{"id", "name"},
+	}
+	assert.NoError(t, writer.loadSheet(context.Background()))
+	assert.NoError(t, writer.initHeaders(context.Background()))
+	assert.Equal(t, int64(7), writer.sheetID)
+	assert.Equal(t, int64(100), writer.rowCount)
+
+	assert.Len(t, batchUpdates, 2)
+	assert.Equal(t, &sheets.SheetProperties{
+		Title:          "Orders",
+		GridProperties: &sheets.GridProperties{RowCount: 100, FrozenRowCount: 1},
+	}, batchUpdates[0].Requests[0].AddSheet.Properties)
+	assert.Equal(t, &sheets.UpdateDimensionPropertiesRequest{
+		Range:      &sheets.DimensionRange{SheetId: 7, Dimension: "COLUMNS", StartIndex: 0, EndIndex: 1},
+		Properties: &sheets.DimensionProperties{PixelSize: 120},
+		Fields:     "pixelSize",
+	}, batchUpdates[1].Requests[0].UpdateDimensionProperties)
+	assert.Equal(t, [][]interface{}{{"id", "name"}}, headers.Values)
+}
+
+func TestWriter_loadSheet_NotFound(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"sheets": [{"properties": {"sheetId": 1, "title": "Sheet1"}}]}`))
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+
+	writer := &Writer{sheetSvc: sheetSvc, sheetName: "Ordres", spreadsheetID: "dummy"}
+	err = writer.loadSheet(context.Background())
+	assert.EqualError(t, err, "sheet \"Ordres\" not found in spreadsheet(dummy), sheets found: [\"Sheet1\"]")
+}
+
+func TestWriter_HeaderRowFromFirstRecord(t *testing.T) {
+	var headers sheets.ValueRange
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy/values/'sheet'!1:1":
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/v4/spreadsheets/dummy/values/'sheet'!1:1":
+			assert.NoError(t, json.Unmarshal(body, &headers))
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/dummy/values/sheet:append":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+
+	// the empty header row is written from the fields of the first record, without appending the headers enabled
+	writer := &Writer{sheetSvc: sheetSvc, sheetName: "sheet", spreadsheetID: "dummy", valueInputOption: "RAW", headerRow: 1}
+	err = writer.Write(context.Background(), []sdk.Record{{Payload: sdk.StructuredData{"name": "a", "id": 1}}})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"id", "name"}}, headers.Values)
+}
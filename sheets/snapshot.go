@@ -0,0 +1,102 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"fmt"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+)
+
+const (
+	// MetadataSnapshot is the metadata key marking the records read during the snapshot phase, set to "true"
+	MetadataSnapshot = "google.sheets.snapshot"
+	// MetadataSnapshotCompleted is the metadata key marking the last record read during the snapshot phase, set to "true".
+	// It is not set if the snapshot is completed by a read without any record.
+	MetadataSnapshotCompleted = "google.sheets.snapshotCompleted"
+
+	OperationSnapshot = "snapshot"
+)
+
+// loadPhase loads the phase from the position on the first read. A position of a record without a phase was returned
+// before the snapshot phase was added, the rows read up to it are not read again as snapshots. The rows are read
+// as snapshots without a position if the snapshot is enabled.
+func (b *BatchReader) loadPhase(pos position.SheetPosition) {
+	if b.phase != "" {
+		return
+	}
+	switch {
+	case pos.Phase != "":
+		b.phase = pos.Phase
+	case pos.SpreadsheetID != "":
+		// the spreadsheet is set in the positions of all the records
+		b.phase = position.PhaseCDC
+	case b.snapshotEnabled:
+		b.phase = position.PhaseSnapshot
+	default:
+		b.phase = position.PhaseCDC
+	}
+}
+
+// setOperations sets the operation of the records, OperationSnapshot along with the snapshot marker during the
+// snapshot phase. Afterwards the records of the row changes keep their operation, the other records are creates.
+func (b *BatchReader) setOperations(records []sdk.Record) {
+	for i := range records {
+		if records[i].Metadata == nil {
+			records[i].Metadata = make(map[string]string)
+		}
+		metadata := records[i].Metadata
+		if b.phase == position.PhaseSnapshot {
+			metadata[MetadataOperation] = OperationSnapshot
+			metadata[MetadataSnapshot] = "true"
+		} else if _, ok := metadata[MetadataOperation]; !ok {
+			metadata[MetadataOperation] = OperationCreate
+		}
+	}
+}
+
+// completeRead is called once all the rows of the ranges have been read, the version of the spreadsheet is recorded,
+// and the snapshot phase is completed. The version and the completion of the snapshot are set in the position
+// of the last record only, as the rows are read again from the positions of the other records,
+// e.g. if the connector is restarted before all the records are processed.
+func (b *BatchReader) completeRead(records []sdk.Record, version int64) ([]sdk.Record, error) {
+	if version != 0 {
+		b.version = version
+	}
+	snapshotCompleted := b.phase == position.PhaseSnapshot
+	b.phase = position.PhaseCDC
+	if len(records) == 0 || (version == 0 && !snapshotCompleted) {
+		return records, nil
+	}
+
+	last := &records[len(records)-1]
+	pos, err := position.ParseRecordPosition(last.Position)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing record position: %w", err)
+	}
+	if version != 0 {
+		pos.Version = version
+	}
+	pos.Phase = position.PhaseCDC
+	last.Position = pos.RecordPosition()
+	if snapshotCompleted && last.Metadata != nil {
+		last.Metadata[MetadataSnapshotCompleted] = "true"
+	}
+	return records, nil
+}
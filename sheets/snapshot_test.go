@@ -0,0 +1,100 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+)
+
+func recordPhases(t *testing.T, records []sdk.Record) ([]string, []string) {
+	var operations, phases []string
+	for _, record := range records {
+		operations = append(operations, record.Metadata[MetadataOperation])
+		pos, err := position.ParseRecordPosition(record.Position)
+		assert.NoError(t, err)
+		phases = append(phases, pos.Phase)
+	}
+	return operations, phases
+}
+
+func TestBatchReader_GetSheetRecords_Snapshot(t *testing.T) {
+	fake := &fakeVersionServer{t: t, version: 1, rows: [][]interface{}{{"a"}, {"b"}}}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+	ctx := context.Background()
+
+	br := newVersionReader(t, testServer.URL)
+	br.snapshotEnabled = true
+	recs, err := br.GetSheetRecords(ctx, position.SheetPosition{})
+	assert.NoError(t, err)
+	operations, phases := recordPhases(t, recs)
+	assert.Equal(t, []string{OperationSnapshot, OperationSnapshot}, operations)
+	assert.Equal(t, []string{position.PhaseSnapshot, position.PhaseCDC}, phases)
+	assert.Equal(t, "true", recs[0].Metadata[MetadataSnapshot])
+
+	// the rows appended after the snapshot are creates
+	fake.version = 2
+	fake.rows = [][]interface{}{{"c"}}
+	last, err := position.ParseRecordPosition(recs[1].Position)
+	assert.NoError(t, err)
+	recs, err = br.GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	operations, phases = recordPhases(t, recs)
+	assert.Equal(t, []string{OperationCreate}, operations)
+	assert.Equal(t, []string{position.PhaseCDC}, phases)
+	assert.NotContains(t, recs[0].Metadata, MetadataSnapshot)
+
+	// the snapshot is resumed from a position returned before its completion
+	br = newVersionReader(t, testServer.URL)
+	br.snapshotEnabled = true
+	recs, err = br.GetSheetRecords(ctx, position.SheetPosition{RowOffset: 1, Phase: position.PhaseSnapshot})
+	assert.NoError(t, err)
+	operations, phases = recordPhases(t, recs)
+	assert.Equal(t, []string{OperationSnapshot}, operations)
+	assert.Equal(t, []string{position.PhaseCDC}, phases)
+
+	// the rows read after a position returned before the snapshot phase was added are not snapshots
+	legacy := last
+	legacy.Phase = ""
+	br = newVersionReader(t, testServer.URL)
+	br.snapshotEnabled = true
+	recs, err = br.GetSheetRecords(ctx, legacy)
+	assert.NoError(t, err)
+	operations, phases = recordPhases(t, recs)
+	assert.Equal(t, []string{OperationCreate}, operations)
+	assert.Equal(t, []string{position.PhaseCDC}, phases)
+}
+
+func TestBatchReader_GetSheetRecords_SnapshotDisabled(t *testing.T) {
+	fake := &fakeVersionServer{t: t, version: 1, rows: [][]interface{}{{"a"}, {"b"}}}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+
+	br := newVersionReader(t, testServer.URL)
+	recs, err := br.GetSheetRecords(context.Background(), position.SheetPosition{})
+	assert.NoError(t, err)
+	operations, phases := recordPhases(t, recs)
+	assert.Equal(t, []string{OperationCreate, OperationCreate}, operations)
+	assert.Equal(t, []string{position.PhaseCDC, position.PhaseCDC}, phases)
+}
@@ -0,0 +1,77 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/conduitio/conduit-connector-google-sheets/internal/jsonfile"
+)
+
+// rowState is the state of a row read in cdc mode
+type rowState struct {
+	// Hash is the fingerprint of the row values
+	Hash string `json:"hash"`
+	// Values are the row values, used as the before data of the row changes
+	Values []interface{} `json:"values,omitempty"`
+}
+
+// stateFileContent is the content of the state file
+type stateFileContent struct {
+	SpreadsheetID string              `json:"spreadsheet_id"`
+	Seq           int64               `json:"seq"`
+	Rows          map[string]rowState `json:"rows"`
+}
+
+// StateFile stores the state of the rows read in cdc mode as JSON in the file at Path
+type StateFile struct {
+	Path string
+}
+
+// Load reads the state file, an empty state is returned if the file doesn't exist yet
+func (f StateFile) Load(spreadsheetID string) (stateFileContent, error) {
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return stateFileContent{SpreadsheetID: spreadsheetID, Rows: make(map[string]rowState)}, nil
+	}
+	if err != nil {
+		return stateFileContent{}, fmt.Errorf("unable to read state file: %w", err)
+	}
+
+	var content stateFileContent
+	if err := json.Unmarshal(data, &content); err != nil {
+		return stateFileContent{}, fmt.Errorf("unable to parse state file %s: %w", f.Path, err)
+	}
+	if content.SpreadsheetID != spreadsheetID {
+		return stateFileContent{}, fmt.Errorf(
+			"state file %s belongs to spreadsheet(%s), expected spreadsheet(%s)",
+			f.Path, content.SpreadsheetID, spreadsheetID,
+		)
+	}
+	if content.Rows == nil {
+		content.Rows = make(map[string]rowState)
+	}
+	return content, nil
+}
+
+// Save atomically rewrites the state file
+func (f StateFile) Save(content stateFileContent) error {
+	return jsonfile.Save(f.Path, content)
+}
@@ -0,0 +1,350 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+// The types of the columns, inferred from the number formats of the cells
+const (
+	ColumnString    = "string"
+	ColumnInteger   = "integer"
+	ColumnDecimal   = "decimal"
+	ColumnBoolean   = "boolean"
+	ColumnTimestamp = "timestamp"
+	ColumnDate      = "date"
+	ColumnTime      = "time"
+	// ColumnAny is the type of the columns without a format and a value to infer the type from,
+	// the values are converted based on their own type
+	ColumnAny = "any"
+)
+
+// MetadataSchema is the metadata key for the schema of the columns of the sheet, in JSON,
+// set if the values are typed
+const MetadataSchema = "google.sheets.schema"
+
+// serialEpoch is the day 0 of the serial numbers of the dates
+var serialEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// SchemaColumn is a column of the schema of a sheet
+type SchemaColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// loadColumnTypes reads the number formats of the first row after the header row of every range, and
+// the time zone of the spreadsheet. The types of the columns are inferred from the formats, or from the values
+// of the cells without a format. An error is logged and the last types are kept if the formats can't be read.
+func (b *BatchReader) loadColumnTypes(ctx context.Context) {
+	var ranges []string
+	for _, sheetRange := range b.ranges {
+		if sheetRange.Title == "" {
+			continue
+		}
+		ranges = append(ranges, sampleRange(sheetRange, b.headerRow))
+	}
+	if len(ranges) == 0 {
+		return
+	}
+
+	spreadsheet, err := b.sheetSvc.Spreadsheets.Get(b.spreadsheetID).
+		Ranges(ranges...).
+		Fields(
+			"properties.timeZone",
+			"sheets(properties.sheetId,data(startColumn,rowData.values(effectiveFormat.numberFormat,effectiveValue)))",
+		).
+		Context(ctx).Do()
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).
+			Str("spreadsheet_id", b.spreadsheetID).
+			Msg("unable to read the number formats, the last column types are used")
+		return
+	}
+
+	if spreadsheet.Properties != nil && spreadsheet.Properties.TimeZone != "" {
+		location, err := time.LoadLocation(spreadsheet.Properties.TimeZone)
+		if err != nil {
+			sdk.Logger(ctx).Warn().Err(err).
+				Str("time_zone", spreadsheet.Properties.TimeZone).
+				Msg("unknown spreadsheet time zone, the dates are converted in UTC")
+		} else {
+			b.location = location
+		}
+	}
+
+	for _, sheet := range spreadsheet.Sheets {
+		if sheet.Properties == nil {
+			continue
+		}
+		sheetID := sheet.Properties.SheetId
+		sheetRange, ok := b.sheetRange(sheetID)
+		if !ok {
+			continue
+		}
+		types := sheetColumnTypes(sheet, sheetRange.GridRange.StartColumnIndex)
+		last, ok := b.columnTypes[sheetID]
+		if ok && sameHeaders(last, types) {
+			continue
+		}
+		if b.columnTypes == nil {
+			b.columnTypes = make(map[int64][]string)
+		}
+		b.columnTypes[sheetID] = types
+		delete(b.schemas, sheetID)
+		if ok {
+			sdk.Logger(ctx).Info().
+				Int64("sheet_id", sheetID).
+				Strs("old_types", last).
+				Strs("new_types", types).
+				Msg("sheet column types changed")
+		}
+	}
+}
+
+// sheetRange returns the range read from the sheet
+func (b *BatchReader) sheetRange(sheetID int64) (SheetRange, bool) {
+	for _, sheetRange := range b.ranges {
+		if sheetRange.GridRange.SheetId == sheetID {
+			return sheetRange, true
+		}
+	}
+	return SheetRange{}, false
+}
+
+// sampleRange returns the range in A1 notation of the first row after the header row, whose formats are read
+func sampleRange(sheetRange SheetRange, headerRow int64) string {
+	gridRange := sheetRange.GridRange
+	rowNumber := gridRange.StartRowIndex + headerRow + 1
+	if gridRange.EndColumnIndex > 0 {
+		return rowRange(sheetRange, rowNumber, int(gridRange.EndColumnIndex-gridRange.StartColumnIndex))
+	}
+	// the range spans to the last column of the sheet
+	return fmt.Sprintf("%s!%s%d:%d", quoteSheetName(sheetRange.Title), ColumnLetters(gridRange.StartColumnIndex), rowNumber, rowNumber)
+}
+
+// sheetColumnTypes returns the types of the columns of the sampled row, starting from the start column of the range
+func sheetColumnTypes(sheet *sheets.Sheet, startColumn int64) []string {
+	var types []string
+	for _, data := range sheet.Data {
+		if len(data.RowData) == 0 {
+			continue
+		}
+		for i, cell := range data.RowData[0].Values {
+			column := data.StartColumn + int64(i) - startColumn
+			if column < 0 {
+				continue
+			}
+			for int64(len(types)) <= column {
+				types = append(types, ColumnAny)
+			}
+			types[column] = cellType(cell)
+		}
+	}
+	return types
+}
+
+// cellType returns the type of the column of the cell, from its number format,
+// or from its value if the cell has no format
+func cellType(cell *sheets.CellData) string {
+	if cell == nil {
+		return ColumnAny
+	}
+	if cell.EffectiveFormat != nil && cell.EffectiveFormat.NumberFormat != nil {
+		format := cell.EffectiveFormat.NumberFormat
+		switch format.Type {
+		case "TEXT":
+			return ColumnString
+		case "DATE":
+			return ColumnDate
+		case "DATE_TIME":
+			return ColumnTimestamp
+		case "TIME":
+			return ColumnTime
+		case "PERCENT", "SCIENTIFIC":
+			return ColumnDecimal
+		case "NUMBER", "CURRENCY":
+			if format.Pattern != "" {
+				if strings.Contains(format.Pattern, ".") {
+					return ColumnDecimal
+				}
+				return ColumnInteger
+			}
+		}
+	}
+	if value := cell.EffectiveValue; value != nil {
+		switch {
+		case value.BoolValue != nil:
+			return ColumnBoolean
+		case value.NumberValue != nil:
+			if isWhole(*value.NumberValue) {
+				return ColumnInteger
+			}
+			return ColumnDecimal
+		case value.StringValue != nil:
+			return ColumnString
+		}
+	}
+	return ColumnAny
+}
+
+// typedRow returns the values of the row converted to the types of the columns of the sheet
+func (b *BatchReader) typedRow(sheetID int64, row []interface{}) []interface{} {
+	typed := make([]interface{}, len(row))
+	for i, value := range row {
+		typed[i] = b.typedValue(sheetID, i, value)
+	}
+	return typed
+}
+
+// typedValue returns the value of the i-th column of the sheet converted to the type of the column
+func (b *BatchReader) typedValue(sheetID int64, i int, value interface{}) interface{} {
+	columnType := ColumnAny
+	if types := b.columnTypes[sheetID]; i < len(types) {
+		columnType = types[i]
+	}
+	return convertValue(value, columnType, b.location)
+}
+
+// convertValue converts the unformatted value to the column type, serial numbers of dates
+// and times are converted to RFC 3339 timestamps and times in the location, UTC if nil.
+// The values not matching the column type, e.g. text in a number column, are returned as they are,
+// whole numbers are returned as int64 and the other numbers as float64.
+func convertValue(value interface{}, columnType string, location *time.Location) interface{} {
+	switch v := value.(type) {
+	case float64:
+		switch columnType {
+		case ColumnDate, ColumnTimestamp:
+			return serialToTime(v, location).Format(time.RFC3339)
+		case ColumnTime:
+			return serialToTime(v, time.UTC).Format("15:04:05")
+		case ColumnString:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case ColumnBoolean:
+			return v != 0
+		case ColumnDecimal:
+			return v
+		}
+		if isWhole(v) {
+			return int64(v)
+		}
+		return v
+	case bool:
+		if columnType == ColumnString {
+			return strconv.FormatBool(v)
+		}
+		return v
+	}
+	return value
+}
+
+// serialToTime converts the serial number of a date, the days since December 30th 1899 with the fraction
+// of the day, to the wall clock time in the location, UTC if nil
+func serialToTime(serial float64, location *time.Location) time.Time {
+	if location == nil {
+		location = time.UTC
+	}
+	// rounding to the second, as the fraction of the day is not exact
+	wall := serialEpoch.Add(time.Duration(math.Round(serial*24*60*60)) * time.Second)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), 0, location)
+}
+
+// isWhole reports whether the number has no fraction, and fits an int64
+func isWhole(v float64) bool {
+	return v == math.Trunc(v) && math.Abs(v) < 1<<63
+}
+
+// schema returns the schema of the columns of the sheet in JSON, an empty string is returned if the values are not typed
+func (b *BatchReader) schema(sheetRange SheetRange) string {
+	if !b.typedValues {
+		return ""
+	}
+	sheetID := sheetRange.GridRange.SheetId
+	if schema, ok := b.schemas[sheetID]; ok {
+		return schema
+	}
+	schema, err := json.Marshal(b.schemaColumns(sheetRange))
+	if err != nil {
+		// not expected, the columns are made of strings
+		return ""
+	}
+	if b.schemas == nil {
+		b.schemas = make(map[int64]string)
+	}
+	b.schemas[sheetID] = string(schema)
+	return string(schema)
+}
+
+// trackWidth records the number of values of the widest row of the sheet read without a header row,
+// the schema of the sheet is rebuilt when a wider row is read
+func (b *BatchReader) trackWidth(sheetRange SheetRange, rows [][]interface{}) {
+	sheetID := sheetRange.GridRange.SheetId
+	width := b.widths[sheetID]
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	if width == b.widths[sheetID] {
+		return
+	}
+	if b.widths == nil {
+		b.widths = make(map[int64]int)
+	}
+	b.widths[sheetID] = width
+	delete(b.schemas, sheetID)
+}
+
+// schemaColumns returns the columns of the sheet, named by the header names, or by the column letters without
+// a header row. Without a header row, the columns are counted from the widest row read if the types don't cover it.
+// The columns are strings if the values are not typed and read formatted, any type otherwise.
+func (b *BatchReader) schemaColumns(sheetRange SheetRange) []SchemaColumn {
+	sheetID := sheetRange.GridRange.SheetId
+	types := b.columnTypes[sheetID]
+	names := b.headers[sheetID]
+	width := len(types)
+	if len(names) > width {
+		width = len(names)
+	}
+	if b.headerRow == 0 && b.widths[sheetID] > width {
+		width = b.widths[sheetID]
+	}
+	defaultType := ColumnAny
+	if !b.typedValues && (b.valueRenderOption == "" || b.valueRenderOption == "FORMATTED_VALUE") {
+		defaultType = ColumnString
+	}
+	columns := make([]SchemaColumn, width)
+	for i := range columns {
+		columns[i] = SchemaColumn{Name: ColumnLetters(sheetRange.GridRange.StartColumnIndex + int64(i)), Type: defaultType}
+		if i < len(names) {
+			columns[i].Name = names[i]
+		}
+		if b.typedValues && i < len(types) {
+			columns[i].Type = types[i]
+		}
+	}
+	return columns
+}
@@ -0,0 +1,158 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+func TestConvertValue(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Berlin")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name       string
+		value      interface{}
+		columnType string
+		want       interface{}
+	}{
+		{name: "date", value: 44743.0, columnType: ColumnDate, want: "2022-07-01T00:00:00+02:00"},
+		{name: "timestamp", value: 44743.5, columnType: ColumnTimestamp, want: "2022-07-01T12:00:00+02:00"},
+		{name: "time", value: 0.75, columnType: ColumnTime, want: "18:00:00"},
+		{name: "integer", value: 42.0, columnType: ColumnInteger, want: int64(42)},
+		{name: "fraction in integer column", value: 4.5, columnType: ColumnInteger, want: 4.5},
+		{name: "decimal", value: 42.0, columnType: ColumnDecimal, want: 42.0},
+		{name: "number in text column", value: 42.0, columnType: ColumnString, want: "42"},
+		{name: "boolean", value: true, columnType: ColumnBoolean, want: true},
+		{name: "boolean in text column", value: false, columnType: ColumnString, want: "false"},
+		{name: "text in number column", value: "n/a", columnType: ColumnInteger, want: "n/a"},
+		{name: "whole number without format", value: 7.0, columnType: ColumnAny, want: int64(7)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, convertValue(tc.value, tc.columnType, location))
+		})
+	}
+}
+
+func TestCellType(t *testing.T) {
+	number := func(v float64) *sheets.ExtendedValue { return &sheets.ExtendedValue{NumberValue: &v} }
+	format := func(formatType, pattern string) *sheets.CellFormat {
+		return &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: formatType, Pattern: pattern}}
+	}
+	yes := true
+
+	testCases := []struct {
+		name string
+		cell *sheets.CellData
+		want string
+	}{
+		{name: "empty cell", cell: &sheets.CellData{}, want: ColumnAny},
+		{name: "date", cell: &sheets.CellData{EffectiveFormat: format("DATE", "yyyy-mm-dd")}, want: ColumnDate},
+		{name: "date time", cell: &sheets.CellData{EffectiveFormat: format("DATE_TIME", "")}, want: ColumnTimestamp},
+		{name: "time", cell: &sheets.CellData{EffectiveFormat: format("TIME", "hh:mm")}, want: ColumnTime},
+		{name: "integer pattern", cell: &sheets.CellData{EffectiveFormat: format("NUMBER", "#,##0")}, want: ColumnInteger},
+		{name: "decimal pattern", cell: &sheets.CellData{EffectiveFormat: format("CURRENCY", "$#,##0.00")}, want: ColumnDecimal},
+		{name: "percent", cell: &sheets.CellData{EffectiveFormat: format("PERCENT", "0%")}, want: ColumnDecimal},
+		{name: "text", cell: &sheets.CellData{EffectiveFormat: format("TEXT", ""), EffectiveValue: number(1)}, want: ColumnString},
+		{name: "whole number without format", cell: &sheets.CellData{EffectiveValue: number(3)}, want: ColumnInteger},
+		{name: "fraction without format", cell: &sheets.CellData{EffectiveValue: number(0.5)}, want: ColumnDecimal},
+		{name: "boolean", cell: &sheets.CellData{EffectiveValue: &sheets.ExtendedValue{BoolValue: &yes}}, want: ColumnBoolean},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, cellType(tc.cell))
+		})
+	}
+}
+
+func TestSampleRange(t *testing.T) {
+	assert.Equal(t, "'Orders'!B2:2", sampleRange(SheetRange{Title: "Orders", GridRange: &sheets.GridRange{StartColumnIndex: 1}}, 1))
+	assert.Equal(t, "'Q3 Orders'!A6:C6", sampleRange(SheetRange{
+		Title:     "Q3 Orders",
+		GridRange: &sheets.GridRange{StartRowIndex: 4, EndColumnIndex: 3},
+	}, 1))
+}
+
+func TestBatchReader_GetSheetRecords_TypedValues(t *testing.T) {
+	formatCalls := 0
+	dateFormat := "DATE"
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v4/spreadsheets/dummy_spreadsheet":
+			formatCalls++
+			assert.Equal(t, []string{"'Orders'!A2:2"}, r.URL.Query()["ranges"])
+			assert.NoError(t, json.NewEncoder(w).Encode(&sheets.Spreadsheet{
+				Properties: &sheets.SpreadsheetProperties{TimeZone: "UTC"},
+				Sheets: []*sheets.Sheet{{
+					Properties: &sheets.SheetProperties{SheetId: 1234},
+					Data: []*sheets.GridData{{RowData: []*sheets.RowData{{Values: []*sheets.CellData{
+						{EffectiveFormat: &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: "NUMBER", Pattern: "0"}}},
+						{EffectiveFormat: &sheets.CellFormat{NumberFormat: &sheets.NumberFormat{Type: dateFormat}}},
+					}}}}},
+				}},
+			}))
+		case "/v4/spreadsheets/dummy_spreadsheet/values:batchGetByDataFilter":
+			assert.NoError(t, json.NewEncoder(w).Encode(&sheets.BatchGetValuesByDataFilterResponse{
+				ValueRanges: []*sheets.MatchedValueRange{
+					{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{"id", "ordered"}}}},
+					{ValueRange: &sheets.ValueRange{Values: [][]interface{}{{1, 44743}}}},
+				},
+			}))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+	}))
+	defer testServer.Close()
+
+	sheetSvc, err := sheets.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	br := &BatchReader{
+		spreadsheetID: "dummy_spreadsheet",
+		ranges:        []SheetRange{{Title: "Orders", GridRange: &sheets.GridRange{SheetId: 1234}}},
+		sheetSvc:      sheetSvc,
+		headerRow:     1,
+		typedValues:   true,
+		pollingPeriod: 10 * time.Second,
+	}
+
+	records, err := br.GetSheetRecords(context.Background(), position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+	assert.Equal(t, sdk.StructuredData{"id": int64(1), "ordered": "2022-07-01T00:00:00Z"}, records[0].Payload)
+	assert.JSONEq(t, `[{"name":"id","type":"integer"},{"name":"ordered","type":"date"}]`, records[0].Metadata[MetadataSchema])
+
+	// the types are reloaded on every read, the schema follows the changed formats
+	dateFormat = "DATE_TIME"
+	records, err = br.GetSheetRecords(context.Background(), position.SheetPosition{RowOffset: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, formatCalls)
+	assert.Len(t, records, 1)
+	assert.JSONEq(t, `[{"name":"id","type":"integer"},{"name":"ordered","type":"timestamp"}]`, records[0].Metadata[MetadataSchema])
+}
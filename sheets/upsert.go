@@ -0,0 +1,407 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/sheets/v4"
+)
+
+const (
+	// WriteModeAppend appends the records as new rows
+	WriteModeAppend = "append"
+	// WriteModeUpsert updates the rows with the record keys, appending the records with new keys
+	WriteModeUpsert = "upsert"
+
+	// DeleteModeClear clears the values of the deleted rows, leaving blank rows
+	DeleteModeClear = "clear"
+	// DeleteModeRemove removes the deleted rows from the sheet, shifting the rows below up
+	DeleteModeRemove = "remove"
+
+	// metadataAction is the metadata key used by the conduit connectors to set the record operation
+	metadataAction = "action"
+)
+
+// IsDeleteRecord reports whether the record is a delete operation, set in the google.sheets.operation
+// metadata by the source in cdc mode, or in the action metadata by the other conduit connectors
+func IsDeleteRecord(record sdk.Record) bool {
+	return record.Metadata[MetadataOperation] == OperationDelete || record.Metadata[metadataAction] == OperationDelete
+}
+
+// upsertPlan holds the changes to be made to the sheet, for a batch of records
+type upsertPlan struct {
+	// updates are the new values of the existing rows, keyed by row number
+	updates map[int64][]interface{}
+	// deletes are the keys of the existing rows to be deleted, keyed by row number
+	deletes map[int64]string
+	// appends are the keys of the new rows in the order to be appended, appendValues holds their values
+	appends      []string
+	appendValues map[string][]interface{}
+}
+
+// loadIndex reads the sheet, building the index of the row numbers by key. The sheet properties are loaded by loadSheet.
+func (w *Writer) loadIndex(ctx context.Context) error {
+	// the keys are read unformatted, so that the keys of the records match the keys with a number format, e.g. 1,000
+	res, err := w.sheetSvc.Spreadsheets.Values.Get(w.spreadsheetID, quoteSheetName(w.sheetName)).
+		MajorDimension(majorDimension).ValueRenderOption("UNFORMATTED_VALUE").Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error getting sheet(%s) values: %w", w.sheetName, err)
+	}
+
+	var headers []string
+	if w.headerRow > 0 && int64(len(res.Values)) >= w.headerRow {
+		headers = writerHeaderNames(res.Values[w.headerRow-1])
+	}
+	keyIndex, err := w.keyColumnIndex(headers)
+	if err != nil {
+		return err
+	}
+
+	w.index = make(map[string]int64)
+	w.lastRow = w.headerRow
+	duplicates := 0
+	for i, row := range res.Values {
+		rowNumber := int64(i) + 1
+		if rowNumber <= w.headerRow {
+			continue
+		}
+		if len(row) > w.width {
+			w.width = len(row)
+		}
+		w.lastRow = rowNumber
+		key := cellKey(row, keyIndex)
+		if key == "" {
+			continue
+		}
+		if _, ok := w.index[key]; ok {
+			duplicates++
+			continue
+		}
+		w.index[key] = rowNumber
+	}
+	if len(headers) > w.width {
+		w.width = len(headers)
+	}
+	if duplicates > 0 {
+		sdk.Logger(ctx).Warn().
+			Str("sheet_name", w.sheetName).
+			Int("duplicate_keys", duplicates).
+			Msg("rows with a key used by a row above are not updated, only the first row with the key is")
+	}
+	return nil
+}
+
+// upsert updates the rows with the keys of the records, appending the records with new keys and deleting
+// the rows of the delete records. The records of a batch are applied in order, the last record of a key wins.
+func (w *Writer) upsert(ctx context.Context, records []sdk.Record) error {
+	var headers []string
+	if w.headerRow > 0 {
+		var err error
+		headers, err = w.readHeaders(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	rows, fields, err := w.parseRecords(records, true)
+	if err != nil {
+		return err
+	}
+
+	keyIndex, err := w.keyColumnIndex(headers)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, len(records))
+	for i, record := range records {
+		row, obj := rows[i], fields[i]
+		if IsDeleteRecord(record) && record.Payload != nil && len(record.Payload.Bytes()) > 0 {
+			// the payload of a delete record is only used to find the key, it may be the row before the delete
+			row, obj, _ = w.format.Decode(record.Payload)
+		}
+		switch {
+		case obj != nil:
+			if val, ok := obj[w.keyColumn]; ok && val != nil {
+				keys[i] = keyString(val)
+			}
+		case row != nil:
+			keys[i] = cellKey(row, keyIndex)
+		}
+		if keys[i] != "" {
+			continue
+		}
+		// the record key is written to the key column, so that the row is found by the next writes
+		if record.Key != nil {
+			keys[i] = strings.TrimSpace(string(record.Key.Bytes()))
+		}
+		if keys[i] == "" {
+			return fmt.Errorf("record(index:%d) has no key, neither in the key column %q nor in the record key", i, w.keyColumn)
+		}
+		switch {
+		case fields[i] != nil:
+			fields[i][w.keyColumn] = keys[i]
+		case rows[i] != nil:
+			if keyIndex < 0 {
+				return fmt.Errorf("key column %q not found in the header row of sheet(%s)", w.keyColumn, w.sheetName)
+			}
+			for len(rows[i]) <= keyIndex {
+				rows[i] = append(rows[i], "")
+			}
+			rows[i][keyIndex] = keys[i]
+		}
+	}
+
+	if hasFields(fields) {
+		headers, err = w.addMissingHeaders(ctx, headers, fields)
+		if err != nil {
+			return err
+		}
+		w.mapFields(ctx, rows, fields, headers)
+	}
+	if len(headers) > w.width {
+		w.width = len(headers)
+	}
+
+	plan := w.planUpsert(records, keys, rows)
+	return w.applyPlan(ctx, plan)
+}
+
+// planUpsert returns the changes to be made to the sheet for the records
+func (w *Writer) planUpsert(records []sdk.Record, keys []string, rows [][]interface{}) upsertPlan {
+	plan := upsertPlan{
+		updates:      make(map[int64][]interface{}),
+		deletes:      make(map[int64]string),
+		appendValues: make(map[string][]interface{}),
+	}
+	for i, record := range records {
+		key := keys[i]
+		row, exists := w.index[key]
+		switch {
+		case IsDeleteRecord(record) && exists:
+			plan.deletes[row] = key
+			delete(plan.updates, row)
+		case IsDeleteRecord(record):
+			if _, ok := plan.appendValues[key]; ok {
+				delete(plan.appendValues, key)
+				plan.appends = removeKey(plan.appends, key)
+			}
+		case exists:
+			plan.updates[row] = rows[i]
+			delete(plan.deletes, row)
+		default:
+			if _, ok := plan.appendValues[key]; !ok {
+				plan.appends = append(plan.appends, key)
+			}
+			plan.appendValues[key] = rows[i]
+		}
+	}
+	return plan
+}
+
+// applyPlan makes the changes to the sheet, updating the index once the changes are made.
+// The rows are removed and the grid is extended first, then the values are written and the deleted rows are cleared.
+func (w *Writer) applyPlan(ctx context.Context, plan upsertPlan) error {
+	deletedRows := make([]int64, 0, len(plan.deletes))
+	for row := range plan.deletes {
+		deletedRows = append(deletedRows, row)
+	}
+	sort.Slice(deletedRows, func(i, j int) bool { return deletedRows[i] > deletedRows[j] })
+
+	var requests []*sheets.Request
+	if w.deleteMode == DeleteModeRemove {
+		// rows are removed from the bottom, so that the row numbers of the rows left to be removed don't change
+		for _, row := range deletedRows {
+			requests = append(requests, &sheets.Request{DeleteDimension: &sheets.DeleteDimensionRequest{
+				Range: &sheets.DimensionRange{SheetId: w.sheetID, Dimension: majorDimension, StartIndex: row - 1, EndIndex: row},
+			}})
+		}
+	}
+	removed := int64(0)
+	if w.deleteMode == DeleteModeRemove {
+		removed = int64(len(deletedRows))
+	}
+	lastRow := w.lastRow - removed
+	if needed := lastRow + int64(len(plan.appends)) - (w.rowCount - removed); needed > 0 {
+		requests = append(requests, &sheets.Request{AppendDimension: &sheets.AppendDimensionRequest{
+			SheetId: w.sheetID, Dimension: majorDimension, Length: needed,
+		}})
+	}
+	if len(requests) > 0 {
+		err := w.withRetries(ctx, func() error {
+			_, err := w.sheetSvc.Spreadsheets.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateSpreadsheetRequest{
+				Requests: requests,
+			}).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("updating rows of sheet(%s) failed: %w", w.sheetName, err)
+		}
+		if w.deleteMode == DeleteModeRemove {
+			w.removeRows(plan.deletes, deletedRows)
+		}
+		w.lastRow = lastRow
+		w.rowCount = w.rowCount - removed
+		if lastRow+int64(len(plan.appends)) > w.rowCount {
+			w.rowCount = lastRow + int64(len(plan.appends))
+		}
+	}
+
+	// rows removed above the updated rows shift them up
+	shifted := func(row int64) int64 {
+		if w.deleteMode != DeleteModeRemove {
+			return row
+		}
+		for _, deleted := range deletedRows {
+			if deleted < row {
+				row--
+			}
+		}
+		return row
+	}
+	data := make([]*sheets.ValueRange, 0, len(plan.updates)+len(plan.appends))
+	updatedRows := make([]int64, 0, len(plan.updates))
+	for row := range plan.updates {
+		updatedRows = append(updatedRows, row)
+	}
+	sort.Slice(updatedRows, func(i, j int) bool { return updatedRows[i] < updatedRows[j] })
+	for _, row := range updatedRows {
+		data = append(data, w.rowValueRange(shifted(row), plan.updates[row]))
+	}
+	for i, key := range plan.appends {
+		data = append(data, w.rowValueRange(w.lastRow+int64(i)+1, plan.appendValues[key]))
+	}
+	if len(data) > 0 {
+		err := w.withRetries(ctx, func() error {
+			_, err := w.sheetSvc.Spreadsheets.Values.BatchUpdate(w.spreadsheetID, &sheets.BatchUpdateValuesRequest{
+				ValueInputOption: w.valueInputOption,
+				Data:             data,
+			}).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("writing rows to sheet(%s) failed: %w", w.sheetName, err)
+		}
+		for i, key := range plan.appends {
+			w.index[key] = w.lastRow + int64(i) + 1
+		}
+		w.lastRow += int64(len(plan.appends))
+	}
+
+	if w.deleteMode != DeleteModeRemove && len(deletedRows) > 0 {
+		ranges := make([]string, 0, len(deletedRows))
+		for _, row := range deletedRows {
+			ranges = append(ranges, fmt.Sprintf("%s!%d:%d", quoteSheetName(w.sheetName), row, row))
+		}
+		err := w.withRetries(ctx, func() error {
+			_, err := w.sheetSvc.Spreadsheets.Values.BatchClear(w.spreadsheetID, &sheets.BatchClearValuesRequest{
+				Ranges: ranges,
+			}).Context(ctx).Do()
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("clearing rows of sheet(%s) failed: %w", w.sheetName, err)
+		}
+		for _, key := range plan.deletes {
+			delete(w.index, key)
+		}
+	}
+	return nil
+}
+
+// removeRows removes the deleted rows from the index, shifting the rows below them up.
+// deletedRows are the row numbers of the deleted rows, in descending order.
+func (w *Writer) removeRows(deletes map[int64]string, deletedRows []int64) {
+	for _, key := range deletes {
+		delete(w.index, key)
+	}
+	for key, row := range w.index {
+		shift := int64(0)
+		for _, deleted := range deletedRows {
+			if deleted < row {
+				shift++
+			}
+		}
+		w.index[key] = row - shift
+	}
+}
+
+// rowValueRange returns the value range writing the values to the row, padded to the width of the sheet
+func (w *Writer) rowValueRange(row int64, values []interface{}) *sheets.ValueRange {
+	if len(values) > w.width {
+		w.width = len(values)
+	}
+	padded := make([]interface{}, w.width)
+	for i := range padded {
+		padded[i] = ""
+		if i < len(values) {
+			padded[i] = values[i]
+		}
+	}
+	return &sheets.ValueRange{
+		MajorDimension: majorDimension,
+		Range:          fmt.Sprintf("%s!A%d", quoteSheetName(w.sheetName), row),
+		Values:         [][]interface{}{padded},
+	}
+}
+
+// keyColumnIndex returns the zero based index of the key column. The key column is looked up in the headers
+// if the header row is set, -1 is returned if it's not found and appending the headers is enabled,
+// or if the header row is empty, as it is then written from the fields of the first record.
+func (w *Writer) keyColumnIndex(headers []string) (int, error) {
+	if w.headerRow == 0 {
+		return int(ColumnIndex(w.keyColumn)), nil
+	}
+	if len(headers) == 0 {
+		return -1, nil
+	}
+	for i, name := range headers {
+		if name == w.keyColumn {
+			return i, nil
+		}
+	}
+	if w.appendHeaders {
+		return -1, nil
+	}
+	return 0, fmt.Errorf("key column %q not found in the header row of sheet(%s)", w.keyColumn, w.sheetName)
+}
+
+// cellKey returns the trimmed value of the cell at index, empty if the row doesn't have the cell
+func cellKey(row []interface{}, index int) string {
+	if index < 0 || index >= len(row) || row[index] == nil {
+		return ""
+	}
+	return keyString(row[index])
+}
+
+// keyString returns the trimmed value of the key, the numbers are formatted without an exponent,
+// e.g. 1000000 rather than 1e+06, the way they are read unformatted
+func keyString(val interface{}) string {
+	return strings.TrimSpace(csvField(val))
+}
+
+func removeKey(keys []string, key string) []string {
+	for i, k := range keys {
+		if k == key {
+			return append(keys[:i], keys[i+1:]...)
+		}
+	}
+	return keys
+}
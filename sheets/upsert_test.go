@@ -0,0 +1,199 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeSheet serves the sheet metadata and values, capturing the update requests
+type fakeSheet struct {
+	t      *testing.T
+	values string
+	// formatted are the values served with the default render option, values if not set
+	formatted    string
+	batchUpdates []sheets.BatchUpdateSpreadsheetRequest
+	valueUpdates []sheets.BatchUpdateValuesRequest
+	clears       []sheets.BatchClearValuesRequest
+	headerReads  int
+}
+
+func (f *fakeSheet) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	switch r.URL.Path {
+	case "/v4/spreadsheets/dummy":
+		_, _ = w.Write([]byte(`{"sheets": [{"properties": {"sheetId": 5, "title": "sheet", "gridProperties": {"rowCount": 4}}}]}`))
+	case "/v4/spreadsheets/dummy/values/'sheet'":
+		values := f.values
+		if f.formatted != "" && r.URL.Query().Get("valueRenderOption") != "UNFORMATTED_VALUE" {
+			values = f.formatted
+		}
+		_, _ = w.Write([]byte(`{"values": ` + values + `}`))
+	case "/v4/spreadsheets/dummy/values/'sheet'!1:1":
+		f.headerReads++
+		_, _ = w.Write([]byte(`{"values": [["id", "name"]]}`))
+	case "/v4/spreadsheets/dummy:batchUpdate":
+		var req sheets.BatchUpdateSpreadsheetRequest
+		assert.NoError(f.t, json.Unmarshal(body, &req))
+		f.batchUpdates = append(f.batchUpdates, req)
+		_, _ = w.Write([]byte(`{}`))
+	case "/v4/spreadsheets/dummy/values:batchUpdate":
+		var req sheets.BatchUpdateValuesRequest
+		assert.NoError(f.t, json.Unmarshal(body, &req))
+		f.valueUpdates = append(f.valueUpdates, req)
+		_, _ = w.Write([]byte(`{}`))
+	case "/v4/spreadsheets/dummy/values:batchClear":
+		var req sheets.BatchClearValuesRequest
+		assert.NoError(f.t, json.Unmarshal(body, &req))
+		f.clears = append(f.clears, req)
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newUpsertWriter(t *testing.T, fake *fakeSheet, deleteMode string) *Writer {
+	testServer := httptest.NewServer(fake)
+	t.Cleanup(testServer.Close)
+	sheetSvc, err := sheets.NewService(
+		context.Background(),
+		option.WithEndpoint(testServer.URL),
+		option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	writer := &Writer{
+		sheetSvc:         sheetSvc,
+		sheetName:        "sheet",
+		spreadsheetID:    "dummy",
+		valueInputOption: "RAW",
+		headerRow:        1,
+		writeMode:        WriteModeUpsert,
+		keyColumn:        "id",
+		deleteMode:       deleteMode,
+	}
+	assert.NoError(t, writer.loadSheet(context.Background()))
+	assert.NoError(t, writer.loadIndex(context.Background()))
+	return writer
+}
+
+func TestWriter_loadIndex(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"], ["1", "a"], [], ["3", "c", "extra"], ["1", "duplicate"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeClear)
+	assert.Equal(t, map[string]int64{"1": 2, "3": 4}, writer.index)
+	assert.Equal(t, int64(5), writer.sheetID)
+	assert.Equal(t, int64(4), writer.rowCount)
+	assert.Equal(t, int64(5), writer.lastRow)
+	assert.Equal(t, 3, writer.width)
+}
+
+func TestWriter_Upsert_Remove(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"], ["1", "a"], ["2", "b"], ["3", "c"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeRemove)
+
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Payload: sdk.StructuredData{"id": "3", "name": "c2"}},
+		{Key: sdk.RawData("1"), Metadata: map[string]string{"action": "delete"}},
+		{Payload: sdk.StructuredData{"name": "d"}, Key: sdk.RawData("4")},
+		{Payload: sdk.RawData(`["5","e"]`)},
+		{Key: sdk.RawData("5"), Metadata: map[string]string{MetadataOperation: OperationDelete}},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []sheets.BatchUpdateSpreadsheetRequest{{Requests: []*sheets.Request{
+		{DeleteDimension: &sheets.DeleteDimensionRequest{Range: &sheets.DimensionRange{SheetId: 5, Dimension: "ROWS", StartIndex: 1, EndIndex: 2}}},
+		{AppendDimension: &sheets.AppendDimensionRequest{SheetId: 5, Dimension: "ROWS", Length: 1}},
+	}}}, fake.batchUpdates)
+	assert.Equal(t, []sheets.BatchUpdateValuesRequest{{
+		ValueInputOption: "RAW",
+		Data: []*sheets.ValueRange{
+			{MajorDimension: "ROWS", Range: "'sheet'!A3", Values: [][]interface{}{{"3", "c2"}}},
+			{MajorDimension: "ROWS", Range: "'sheet'!A4", Values: [][]interface{}{{"4", "d"}}},
+		},
+	}}, fake.valueUpdates)
+	assert.Len(t, fake.clears, 0)
+	assert.Equal(t, map[string]int64{"2": 2, "3": 3, "4": 4}, writer.index)
+	assert.Equal(t, int64(4), writer.lastRow)
+	assert.Equal(t, int64(4), writer.rowCount)
+}
+
+func TestWriter_Upsert_Clear(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"], ["1", "a"], ["2", "b"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeClear)
+
+	err := writer.Write(context.Background(), []sdk.Record{
+		{Payload: sdk.RawData(`{"id":"1","name":"a"}`), Metadata: map[string]string{MetadataOperation: OperationDelete}},
+		{Payload: sdk.StructuredData{"id": "2", "name": "b2"}},
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, fake.batchUpdates, 0)
+	assert.Equal(t, []sheets.BatchUpdateValuesRequest{{
+		ValueInputOption: "RAW",
+		Data: []*sheets.ValueRange{
+			{MajorDimension: "ROWS", Range: "'sheet'!A3", Values: [][]interface{}{{"2", "b2"}}},
+		},
+	}}, fake.valueUpdates)
+	assert.Equal(t, []sheets.BatchClearValuesRequest{{Ranges: []string{"'sheet'!2:2"}}}, fake.clears)
+	assert.Equal(t, map[string]int64{"2": 3}, writer.index)
+	assert.Equal(t, int64(3), writer.lastRow)
+}
+
+func TestWriter_Upsert_NoKey(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeClear)
+
+	err := writer.Write(context.Background(), []sdk.Record{{Payload: sdk.StructuredData{"name": "a"}}})
+	assert.EqualError(t, err, `record(index:0) has no key, neither in the key column "id" nor in the record key`)
+}
+
+func TestWriter_Upsert_NumericKey(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"], [1000000, "a"]]`, formatted: `[["id", "name"], ["1,000,000", "a"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeClear)
+
+	// the key is read unformatted and formatted without an exponent, so the existing row is updated
+	err := writer.Write(context.Background(), []sdk.Record{{Payload: sdk.StructuredData{"id": float64(1000000), "name": "a2"}}})
+	assert.NoError(t, err)
+	assert.Len(t, fake.batchUpdates, 0)
+	assert.Len(t, fake.valueUpdates, 1)
+	assert.Equal(t, "'sheet'!A2", fake.valueUpdates[0].Data[0].Range)
+	assert.Equal(t, map[string]int64{"1000000": 2}, writer.index)
+}
+
+func TestWriter_Upsert_HeaderCache(t *testing.T) {
+	fake := &fakeSheet{t: t, values: `[["id", "name"]]`}
+	writer := newUpsertWriter(t, fake, DeleteModeClear)
+	ctx := context.Background()
+
+	assert.NoError(t, writer.Write(ctx, []sdk.Record{{Payload: sdk.StructuredData{"id": "1", "name": "a"}}}))
+	assert.NoError(t, writer.Write(ctx, []sdk.Record{{Payload: sdk.StructuredData{"id": "2", "name": "b"}}}))
+	assert.Equal(t, 1, fake.headerReads)
+
+	// the header row is read again after a write fails
+	assert.Error(t, writer.Write(ctx, []sdk.Record{{Payload: sdk.StructuredData{"name": "c"}}}))
+	assert.NoError(t, writer.Write(ctx, []sdk.Record{{Payload: sdk.StructuredData{"id": "3", "name": "c"}}}))
+	assert.Equal(t, 2, fake.headerReads)
+}
@@ -0,0 +1,67 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"google.golang.org/api/googleapi"
+)
+
+// checkVersion returns the Drive version of the spreadsheet, and whether it changed since the rows were read last.
+// The version of the rows read last is taken from the position, until the rows are read by the reader.
+// If the version can't be fetched, the spreadsheet is considered changed for this poll. The version check is disabled
+// if the access is denied or the file is not found, e.g. if the token has no Drive scope or the Drive API is not enabled,
+// the rate limit errors back off the reads.
+func (b *BatchReader) checkVersion(ctx context.Context, pos position.SheetPosition) (int64, bool) {
+	if b.driveSvc == nil {
+		return 0, true
+	}
+	if b.version == 0 {
+		b.version = pos.Version
+	}
+
+	file, err := b.driveSvc.Files.Get(b.spreadsheetID).
+		Fields("version").
+		SupportsAllDrives(true).
+		Context(ctx).
+		Do()
+	if err != nil {
+		gerr, ok := err.(*googleapi.Error)
+		switch {
+		case ok && gerr.Code == http.StatusTooManyRequests:
+			b.backOff(ctx, &b.driveRetryCount, gerr)
+			return 0, false
+		case ok && (gerr.Code == http.StatusForbidden || gerr.Code == http.StatusNotFound):
+			sdk.Logger(ctx).Warn().Err(err).
+				Str("spreadsheet_id", b.spreadsheetID).
+				Msg("unable to get the drive version of the spreadsheet, the values are read on every poll")
+			b.driveSvc = nil
+		default:
+			sdk.Logger(ctx).Warn().Err(err).
+				Str("spreadsheet_id", b.spreadsheetID).
+				Msg("unable to get the drive version of the spreadsheet, the values are read")
+		}
+		return 0, true
+	}
+	b.driveRetryCount = 0
+	return file.Version, file.Version != b.version
+}
@@ -0,0 +1,181 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conduitio/conduit-connector-google-sheets/source/position"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// fakeVersionServer serves the drive version of the spreadsheet, and the values of the sheet
+type fakeVersionServer struct {
+	t          *testing.T
+	version    int64
+	driveCode  int
+	valuesCode int
+	valueCalls int
+	rows       [][]interface{}
+}
+
+func (f *fakeVersionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/files/dummy_spreadsheet"):
+		if f.driveCode != 0 {
+			w.WriteHeader(f.driveCode)
+			return
+		}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.File{Version: f.version}))
+	case r.URL.Path == "/v4/spreadsheets/dummy_spreadsheet/values:batchGetByDataFilter":
+		f.valueCalls++
+		if f.valuesCode != 0 {
+			w.WriteHeader(f.valuesCode)
+			return
+		}
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&sheets.BatchGetValuesByDataFilterResponse{
+			ValueRanges: []*sheets.MatchedValueRange{{ValueRange: &sheets.ValueRange{Values: f.rows}}},
+		}))
+	default:
+		f.t.Errorf("unexpected request: %s", r.URL.Path)
+	}
+}
+
+func newVersionReader(t *testing.T, url string) *BatchReader {
+	ctx := context.Background()
+	sheetSvc, err := sheets.NewService(ctx, option.WithEndpoint(url), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	driveSvc, err := drive.NewService(ctx, option.WithEndpoint(url), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	return &BatchReader{
+		spreadsheetID: "dummy_spreadsheet",
+		ranges:        []SheetRange{{GridRange: &sheets.GridRange{SheetId: 1234}}},
+		sheetSvc:      sheetSvc,
+		driveSvc:      driveSvc,
+		pollingPeriod: 10 * time.Second,
+	}
+}
+
+func TestBatchReader_GetSheetRecords_Version(t *testing.T) {
+	fake := &fakeVersionServer{t: t, version: 5, rows: [][]interface{}{{"a"}, {"b"}}}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+	ctx := context.Background()
+
+	br := newVersionReader(t, testServer.URL)
+	recs, err := br.GetSheetRecords(ctx, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 2)
+	assert.Equal(t, 1, fake.valueCalls)
+
+	first, err := position.ParseRecordPosition(recs[0].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), first.Version)
+	last, err := position.ParseRecordPosition(recs[1].Position)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), last.Version)
+
+	// the spreadsheet is not modified, the values are not read
+	recs, err = br.GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	assert.Nil(t, recs)
+	assert.Equal(t, 1, fake.valueCalls)
+
+	// the version is taken from the position after a restart
+	recs, err = newVersionReader(t, testServer.URL).GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	assert.Nil(t, recs)
+	assert.Equal(t, 1, fake.valueCalls)
+
+	// the spreadsheet is modified, the values are read
+	fake.version = 6
+	fake.rows = [][]interface{}{{"c"}}
+	recs, err = br.GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, 2, fake.valueCalls)
+	assert.Equal(t, int64(6), br.version)
+}
+
+func TestBatchReader_GetSheetRecords_VersionForbidden(t *testing.T) {
+	fake := &fakeVersionServer{t: t, driveCode: http.StatusForbidden, rows: [][]interface{}{{"a"}}}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+
+	br := newVersionReader(t, testServer.URL)
+	recs, err := br.GetSheetRecords(context.Background(), position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.Equal(t, 1, fake.valueCalls)
+	assert.Nil(t, br.driveSvc)
+}
+
+func TestBatchReader_GetSheetRecords_VersionUnavailable(t *testing.T) {
+	fake := &fakeVersionServer{t: t, version: 5, driveCode: http.StatusServiceUnavailable, rows: [][]interface{}{{"a"}}}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+	ctx := context.Background()
+
+	// the values are read, the version check is kept
+	br := newVersionReader(t, testServer.URL)
+	recs, err := br.GetSheetRecords(ctx, position.SheetPosition{})
+	assert.NoError(t, err)
+	assert.Len(t, recs, 1)
+	assert.NotNil(t, br.driveSvc)
+	last, err := position.ParseRecordPosition(recs[0].Position)
+	assert.NoError(t, err)
+
+	// the version is checked again by the next poll
+	// as the version of the rows read last is unknown, the values are read once more
+	fake.driveCode = 0
+	_, err = br.GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, fake.valueCalls)
+	recs, err = br.GetSheetRecords(ctx, last)
+	assert.NoError(t, err)
+	assert.Nil(t, recs)
+	assert.Equal(t, 2, fake.valueCalls)
+}
+
+func TestBatchReader_GetSheetRecords_VersionBackOff(t *testing.T) {
+	fake := &fakeVersionServer{t: t, version: 5, valuesCode: http.StatusTooManyRequests}
+	testServer := httptest.NewServer(fake)
+	defer testServer.Close()
+	ctx := context.Background()
+
+	// the successful version checks don't reset the back off of the values reads
+	br := newVersionReader(t, testServer.URL)
+	for i := 1; i <= 2; i++ {
+		br.nextRun = time.Time{}
+		recs, err := br.GetSheetRecords(ctx, position.SheetPosition{})
+		assert.NoError(t, err)
+		assert.Nil(t, recs)
+		assert.Equal(t, int64(i), br.retryCount)
+	}
+	assert.Equal(t, 2, fake.valueCalls)
+	assert.GreaterOrEqual(t, br.nextRun.Unix(), time.Now().Add(19*time.Second).Unix())
+}
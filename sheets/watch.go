@@ -0,0 +1,291 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	sdk "github.com/conduitio/conduit-connector-sdk"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+const (
+	// watchRetryPeriod is the time waited before registering the channel again, after the registration failed
+	watchRetryPeriod = time.Minute
+	// resourceStateSync is the resource state of the message sent by Drive when the channel is registered
+	resourceStateSync = "sync"
+)
+
+// WatchArgs are the arguments of the Drive push notifications, the notifications are disabled if Address is not set
+type WatchArgs struct {
+	// TokenSource provides the tokens used to authenticate the Drive API calls
+	TokenSource   oauth2.TokenSource
+	SpreadsheetID string
+	// Address is the address the HTTP listener receiving the notifications listens on, e.g. :8080
+	Address string
+	// URL is the public HTTPS URL the notifications are sent to by Drive, routed to the listener
+	URL string
+	// TTL is the time to live requested for the channel, the channel is renewed before it expires
+	TTL time.Duration
+	// FallbackPeriod is the time after which the sheet is polled, if no notification is received meanwhile
+	FallbackPeriod time.Duration
+}
+
+// Watcher receives the Drive push notifications of the changes of the spreadsheet,
+// using a files.watch channel pointing at an embedded HTTP listener
+type Watcher struct {
+	// instance of drive service, used to register and stop the channels
+	driveSvc      *drive.Service
+	spreadsheetID string
+	url           string
+	ttl           time.Duration
+
+	listener net.Listener
+	server   *http.Server
+	// notifications receives a value when a change notification is received, pending notifications are coalesced
+	notifications chan struct{}
+
+	// mux guards the channels and the listener state, which are updated concurrently to the notifications
+	mux sync.Mutex
+	// channels are the registered channels keyed by the channel id, the previous channel is
+	// kept till it is stopped after the renewal, so the notifications sent meanwhile are accepted
+	channels map[string]*drive.Channel
+	// current is the last registered channel, nil if no channel is registered
+	current *drive.Channel
+	// serving is set while the listener accepts the notifications, closed is set once the watcher is closing,
+	// the notifications received afterwards don't trigger a read
+	serving bool
+	closed  bool
+	// served is closed when the listener stops serving
+	served chan struct{}
+}
+
+// NewWatcher creates the drive service client and starts listening on the address,
+// the channel is registered by Run
+func NewWatcher(ctx context.Context, args WatchArgs) (*Watcher, error) {
+	driveService, err := drive.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, args.TokenSource)))
+	if err != nil {
+		return nil, fmt.Errorf("error creating drive service client: %w", err)
+	}
+	return newWatcher(driveService, args)
+}
+
+func newWatcher(driveService *drive.Service, args WatchArgs) (*Watcher, error) {
+	listener, err := net.Listen("tcp", args.Address)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %q for the drive notifications: %w", args.Address, err)
+	}
+	w := &Watcher{
+		driveSvc:      driveService,
+		spreadsheetID: args.SpreadsheetID,
+		url:           args.URL,
+		ttl:           args.TTL,
+		listener:      listener,
+		notifications: make(chan struct{}, 1),
+		channels:      make(map[string]*drive.Channel),
+		serving:       true,
+		served:        make(chan struct{}),
+	}
+	w.server = &http.Server{Handler: w, ReadHeaderTimeout: 10 * time.Second}
+	return w, nil
+}
+
+// Notifications returns the channel receiving a value when the spreadsheet is changed
+func (w *Watcher) Notifications() <-chan struct{} {
+	return w.notifications
+}
+
+// Active reports whether the notifications are expected to be received,
+// i.e. the listener is serving and a registered channel has not expired
+func (w *Watcher) Active() bool {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.serving && w.current != nil && time.Now().Before(channelExpiration(w.current))
+}
+
+// Run serves the notifications, registers the channel and renews it before it expires, till the context is done.
+// The channels are stopped and the listener is closed on return.
+func (w *Watcher) Run(ctx context.Context) error {
+	go w.serve(ctx)
+	defer w.close(ctx)
+
+	for {
+		wait := watchRetryPeriod
+		channel, err := w.register(ctx)
+		if err != nil {
+			sdk.Logger(ctx).Error().Err(err).
+				Str("spreadsheet_id", w.spreadsheetID).
+				Float64("retry_after", wait.Seconds()).
+				Msg("unable to register the drive notification channel, falling back to polling")
+		} else {
+			// renewing the channel when 90% of its lifetime is over, Drive may shorten the requested lifetime
+			expiration := channelExpiration(channel)
+			wait = time.Until(expiration) * 9 / 10
+			sdk.Logger(ctx).Info().
+				Str("channel_id", channel.Id).
+				Time("expiration", expiration).
+				Msg("drive notification channel registered")
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// ServeHTTP receives the notifications sent by Drive to the channels
+func (w *Watcher) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	w.mux.Lock()
+	channel, ok := w.channels[r.Header.Get("X-Goog-Channel-ID")]
+	closed := w.closed
+	w.mux.Unlock()
+	if closed {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if !ok || channel.Token != r.Header.Get("X-Goog-Channel-Token") {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+	defer rw.WriteHeader(http.StatusOK)
+
+	if r.Header.Get("X-Goog-Resource-State") == resourceStateSync {
+		return
+	}
+	select {
+	case w.notifications <- struct{}{}:
+	default:
+		// a read is pending already
+	}
+}
+
+// serve accepts the notifications till the listener is closed, the watcher is inactive afterwards
+func (w *Watcher) serve(ctx context.Context) {
+	defer close(w.served)
+	err := w.server.Serve(w.listener)
+	w.mux.Lock()
+	w.serving = false
+	w.mux.Unlock()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		sdk.Logger(ctx).Error().Err(err).Msg("drive notification listener stopped, falling back to polling")
+	}
+}
+
+// register registers a new channel watching the spreadsheet, and stops the previous channel
+func (w *Watcher) register(ctx context.Context) (*drive.Channel, error) {
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	token, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+
+	req := &drive.Channel{
+		Id:         id,
+		Type:       "web_hook",
+		Address:    w.url,
+		Token:      token,
+		Expiration: time.Now().Add(w.ttl).UnixMilli(),
+	}
+	// the channel is added before the call, as the sync message may be received before the call returns
+	w.mux.Lock()
+	w.channels[id] = req
+	w.mux.Unlock()
+
+	channel, err := w.driveSvc.Files.Watch(w.spreadsheetID, req).SupportsAllDrives(true).Context(ctx).Do()
+	w.mux.Lock()
+	if err != nil {
+		delete(w.channels, id)
+		w.mux.Unlock()
+		return nil, fmt.Errorf("error watching spreadsheet(%s): %w", w.spreadsheetID, err)
+	}
+	channel.Token = token
+	previous := w.current
+	w.channels[id] = channel
+	w.current = channel
+	w.mux.Unlock()
+
+	if previous != nil {
+		w.stop(ctx, previous)
+	}
+	return channel, nil
+}
+
+// stop stops the channel, the error is only logged as the channel expires anyway
+func (w *Watcher) stop(ctx context.Context, channel *drive.Channel) {
+	w.mux.Lock()
+	delete(w.channels, channel.Id)
+	w.mux.Unlock()
+
+	err := w.driveSvc.Channels.Stop(&drive.Channel{Id: channel.Id, ResourceId: channel.ResourceId}).Context(ctx).Do()
+	if err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Str("channel_id", channel.Id).Msg("unable to stop the drive notification channel")
+	}
+}
+
+// close stops the current channel and closes the listener. It returns once the listener stopped serving and the
+// notifications being handled are done, so no read is triggered after close.
+func (w *Watcher) close(ctx context.Context) {
+	w.mux.Lock()
+	current := w.current
+	w.current = nil
+	w.closed = true
+	w.mux.Unlock()
+	if current != nil {
+		// the context is done already, the channel is stopped using a new context
+		stopCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		w.stop(stopCtx, current)
+		cancel()
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := w.server.Shutdown(shutdownCtx); err != nil {
+		sdk.Logger(ctx).Warn().Err(err).Msg("unable to shut down the drive notification listener gracefully")
+		_ = w.server.Close()
+	}
+	<-w.served
+}
+
+// channelExpiration returns the expiration time of the channel
+func channelExpiration(channel *drive.Channel) time.Time {
+	return time.UnixMilli(channel.Expiration)
+}
+
+// randomHex returns n random bytes encoded in hex
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
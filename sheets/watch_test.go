@@ -0,0 +1,226 @@
+/*
+Copyright © 2022 Meroxa, Inc. & Gophers Lab Technologies Pvt. Ltd.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/drive/v3"
+	"google.golang.org/api/option"
+)
+
+// fakeDrive registers and stops the notification channels
+type fakeDrive struct {
+	t        *testing.T
+	mux      sync.Mutex
+	watched  []*drive.Channel
+	stopped  []string
+	failures int
+}
+
+func (f *fakeDrive) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	channel := &drive.Channel{}
+	assert.NoError(f.t, json.NewDecoder(r.Body).Decode(channel))
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	switch r.URL.Path {
+	case "/files/dummy_spreadsheet/watch":
+		if f.failures > 0 {
+			f.failures--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.watched = append(f.watched, channel)
+		assert.NoError(f.t, json.NewEncoder(w).Encode(&drive.Channel{
+			Id:         channel.Id,
+			ResourceId: "resource_id",
+			Expiration: channel.Expiration,
+		}))
+	case "/channels/stop":
+		assert.Equal(f.t, "resource_id", channel.ResourceId)
+		f.stopped = append(f.stopped, channel.Id)
+	default:
+		f.t.Errorf("unexpected request: %s", r.URL.Path)
+	}
+}
+
+func (f *fakeDrive) channels() ([]*drive.Channel, []string) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return append([]*drive.Channel(nil), f.watched...), append([]string(nil), f.stopped...)
+}
+
+// notify sends a notification of the channel to the watcher, as Drive does
+func notify(t *testing.T, w *Watcher, channel *drive.Channel, state string) int {
+	req, err := http.NewRequest(http.MethodPost, "http://"+w.listener.Addr().String()+"/notify", nil)
+	assert.NoError(t, err)
+	req.Header.Set("X-Goog-Channel-ID", channel.Id)
+	req.Header.Set("X-Goog-Channel-Token", channel.Token)
+	req.Header.Set("X-Goog-Resource-State", state)
+	res, err := http.DefaultClient.Do(req)
+	assert.NoError(t, err)
+	_ = res.Body.Close()
+	return res.StatusCode
+}
+
+func newTestWatcher(t *testing.T, fake *fakeDrive, ttl time.Duration) *Watcher {
+	testServer := httptest.NewServer(fake)
+	t.Cleanup(testServer.Close)
+	driveSvc, err := drive.NewService(context.Background(), option.WithEndpoint(testServer.URL), option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	w, err := newWatcher(driveSvc, WatchArgs{
+		SpreadsheetID: "dummy_spreadsheet",
+		Address:       "127.0.0.1:0",
+		URL:           "https://example.com/notify",
+		TTL:           ttl,
+	})
+	assert.NoError(t, err)
+	return w
+}
+
+func TestWatcher_Notifications(t *testing.T) {
+	fake := &fakeDrive{t: t}
+	w := newTestWatcher(t, fake, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, w.Run(ctx))
+		close(done)
+	}()
+	assert.Eventually(t, w.Active, time.Second, 10*time.Millisecond)
+
+	watched, _ := fake.channels()
+	assert.Len(t, watched, 1)
+	channel := watched[0]
+	assert.Equal(t, "web_hook", channel.Type)
+	assert.Equal(t, "https://example.com/notify", channel.Address)
+
+	// the sync message doesn't trigger a read
+	assert.Equal(t, http.StatusOK, notify(t, w, channel, "sync"))
+	assert.Len(t, w.Notifications(), 0)
+
+	// the notifications are coalesced till read
+	assert.Equal(t, http.StatusOK, notify(t, w, channel, "update"))
+	assert.Equal(t, http.StatusOK, notify(t, w, channel, "update"))
+	assert.Len(t, w.Notifications(), 1)
+	<-w.Notifications()
+
+	// the notifications of unknown channels are rejected
+	assert.Equal(t, http.StatusNotFound, notify(t, w, &drive.Channel{Id: channel.Id, Token: "wrong"}, "update"))
+	assert.Len(t, w.Notifications(), 0)
+
+	// the channel is stopped on return
+	cancel()
+	<-done
+	_, stopped := fake.channels()
+	assert.Equal(t, []string{channel.Id}, stopped)
+	assert.False(t, w.Active())
+}
+
+func TestWatcher_Renew(t *testing.T) {
+	fake := &fakeDrive{t: t}
+	w := newTestWatcher(t, fake, 200*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, w.Run(ctx))
+	}()
+
+	assert.Eventually(t, func() bool {
+		_, stopped := fake.channels()
+		return len(stopped) >= 1
+	}, 2*time.Second, 10*time.Millisecond)
+	watched, stopped := fake.channels()
+	assert.GreaterOrEqual(t, len(watched), 2)
+	assert.Equal(t, watched[0].Id, stopped[0])
+	assert.True(t, w.Active())
+
+	// the notifications of the stopped channel are rejected
+	assert.Equal(t, http.StatusNotFound, notify(t, w, watched[0], "update"))
+}
+
+func TestWatcher_RegisterError(t *testing.T) {
+	fake := &fakeDrive{t: t, failures: 1}
+	w := newTestWatcher(t, fake, time.Hour)
+
+	_, err := w.register(context.Background())
+	assert.Error(t, err)
+	assert.False(t, w.Active())
+	assert.Empty(t, w.channels)
+
+	channel, err := w.register(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "resource_id", channel.ResourceId)
+	go w.serve(context.Background())
+	defer w.close(context.Background())
+	assert.True(t, w.Active())
+}
+
+func TestWatcher_NoNotificationAfterStop(t *testing.T) {
+	fake := &fakeDrive{t: t}
+	w := newTestWatcher(t, fake, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		assert.NoError(t, w.Run(ctx))
+		close(done)
+	}()
+	assert.Eventually(t, w.Active, time.Second, 10*time.Millisecond)
+	watched, _ := fake.channels()
+	channel := watched[0]
+
+	// the notifications keep being sent by Drive while the watcher is stopped
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			req, err := http.NewRequest(http.MethodPost, "http://"+w.listener.Addr().String()+"/notify", nil)
+			assert.NoError(t, err)
+			req.Header.Set("X-Goog-Channel-ID", channel.Id)
+			req.Header.Set("X-Goog-Channel-Token", channel.Token)
+			req.Header.Set("X-Goog-Resource-State", "update")
+			if res, err := http.DefaultClient.Do(req); err == nil {
+				_ = res.Body.Close()
+			}
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	<-done
+
+	// the notification pending at the stop is drained, no notification is received afterwards
+	select {
+	case <-w.Notifications():
+	default:
+	}
+	<-sent
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, w.Notifications(), 0)
+	assert.False(t, w.Active())
+}
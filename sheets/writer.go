@@ -19,12 +19,16 @@ package sheets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"time"
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/drive/v3"
 	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
@@ -45,41 +49,187 @@ type Writer struct {
 	maxRetries uint64
 	// the number of unsuccessful retries made with error 429, since last successful data write
 	retryCount uint64
+	// headerRow is the row number of the sheet holding the column names, 0 if the sheet has no header row
+	headerRow int64
+	// appendHeaders enables appending the record fields missing in the header row as new columns
+	appendHeaders bool
+
+	// writeMode is WriteModeAppend, WriteModeUpsert, WriteModeOverwrite or WriteModeReplaceOnSnapshot
+	writeMode string
+	// keyColumn is the header name, or the column letters without a header row, of the column holding the row keys
+	keyColumn string
+	// deleteMode is DeleteModeClear to clear the deleted rows, or DeleteModeRemove to remove them from the sheet
+	deleteMode string
+	// index maps the row keys to the row numbers of the sheet in upsert mode, it is built from the sheet on NewWriter
+	index map[string]int64
+	// sheetID is the gid of the sheet, required to insert or remove rows in upsert mode
+	sheetID int64
+	// rowCount and columnCount are the size of the sheet grid, lastRow is the last row number with data or a key
+	rowCount    int64
+	columnCount int64
+	lastRow     int64
+	// format is the format of the record payloads, detected for every payload if not set
+	format Format
+	// createSheet enables creating the sheet with the layout, if it's not found in the spreadsheet
+	createSheet bool
+	layout      SheetLayout
+	// headers are the column names written to the header row, if it is empty
+	headers []string
+	// headerCache are the column names of the header row read last, nil till the header row is read.
+	// It is cleared when a write fails or the sheet written to changes, so that the header row is read again.
+	headerCache []string
+	// inSnapshot is set while the records of a snapshot are written, snapshotMarker is the id of the developer
+	// metadata marking the sheet during the snapshot in overwrite mode, 0 if the sheet is not marked
+	inSnapshot     bool
+	snapshotMarker int64
+	// staging is the writer of the staging sheet the snapshot is written to in replace-on-snapshot mode,
+	// nil if no snapshot is being written
+	staging *Writer
+	// rollover configures rolling over to a new sheet or spreadsheet, the base spreadsheet and sheet are the
+	// configured ones, rolloverSeq is the sequence number of the sheet written to, 1 for the configured sheet
+	rollover          Rollover
+	rolloverState     *RolloverState
+	baseSpreadsheetID string
+	baseSheetName     string
+	rolloverSeq       int64
+	// rolloverRows is the number of rows of the sheet grid when it was rolled over to, otherCells is the number
+	// of cells of the other sheets of the spreadsheet, -1 until they are counted
+	rolloverRows int64
+	otherCells   int64
+	// driveSvc shares the spreadsheets rolled over to like the configured spreadsheet, nil if not rolling over to
+	// spreadsheets
+	driveSvc *drive.Service
+	// width is the number of columns of the widest row written, rows are padded to width so that updates
+	// overwrite all the cells of the previous row values
+	width int
+}
+
+type WriterArgs struct {
+	// TokenSource provides the tokens used to authenticate the Sheets API calls
+	TokenSource      oauth2.TokenSource
+	SpreadsheetID    string
+	SheetName        string
+	ValueInputOption string
+	MaxRetries       uint64
+	// HeaderRow is the row number of the sheet holding the column names, the fields of the structured
+	// or JSON object payloads are written to the columns with the matching names. 0 if the sheet has no header row.
+	HeaderRow int64
+	// AppendHeaders enables appending the record fields missing in the header row as new columns,
+	// the fields are dropped otherwise
+	AppendHeaders bool
+	// WriteMode is WriteModeAppend (default) to append the records, WriteModeUpsert to update the rows by key,
+	// WriteModeOverwrite to rewrite the sheet from the first row below the header row, or WriteModeReplaceOnSnapshot
+	// to replace the values of the sheet with the snapshot written to a staging sheet
+	WriteMode string
+	// KeyColumn is the header name, or the column letters without a header row, of the column holding
+	// the row keys in upsert mode
+	KeyColumn string
+	// DeleteMode is DeleteModeClear (default) to clear the deleted rows, or DeleteModeRemove to remove them
+	DeleteMode string
+	// Format is the format of the record payloads, the format of every payload is detected if not set
+	Format Format
+	// CreateSheet enables creating the sheet with the Layout, if it's not found in the spreadsheet.
+	// An error is returned if the sheet is not found otherwise.
+	CreateSheet bool
+	Layout      SheetLayout
+	// Headers are the column names written to the header row, if it is empty. The header row
+	// is written from the fields of the first record if not set.
+	Headers []string
+	// Rollover configures rolling over to a new sheet or spreadsheet in append mode, before the cell limit
+	// of the spreadsheet is hit
+	Rollover Rollover
+	// RolloverState holds the sheets and spreadsheets rolled over to by the writers sharing the Rollover.State file,
+	// it is read from the file if not set
+	RolloverState *RolloverState
 }
 
-func NewWriter(
-	ctx context.Context,
-	oauthCfg *oauth2.Config,
-	token *oauth2.Token,
-	spreadsheetID, sheetName, valueInputOption string,
-	retries uint64,
-) (*Writer, error) {
-	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(oauthCfg.Client(ctx, token)))
+func NewWriter(ctx context.Context, args WriterArgs) (*Writer, error) {
+	sheetService, err := sheets.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, args.TokenSource)))
 	if err != nil {
-		return nil, fmt.Errorf("error creating sheets(%s) service client: %w", sheetName, err)
+		return nil, fmt.Errorf("error creating sheets(%s) service client: %w", args.SheetName, err)
 	}
-	return &Writer{
-		spreadsheetID:    spreadsheetID,
+	w := &Writer{
+		spreadsheetID:    args.SpreadsheetID,
 		sheetSvc:         sheetService,
-		sheetName:        sheetName,
-		valueInputOption: valueInputOption,
-		maxRetries:       retries,
-	}, nil
+		sheetName:        args.SheetName,
+		valueInputOption: args.ValueInputOption,
+		maxRetries:       args.MaxRetries,
+		headerRow:        args.HeaderRow,
+		appendHeaders:    args.AppendHeaders,
+		writeMode:        args.WriteMode,
+		keyColumn:        args.KeyColumn,
+		deleteMode:       args.DeleteMode,
+		format:           args.Format,
+		createSheet:      args.CreateSheet,
+		layout:           args.Layout,
+		headers:          args.Headers,
+		rollover:         args.Rollover,
+		rolloverState:    args.RolloverState,
+		otherCells:       -1,
+	}
+	if w.rollover.Cells > 0 {
+		if w.rolloverState == nil {
+			w.rolloverState = NewRolloverState(w.rollover.State)
+		}
+		if w.rollover.To == RolloverToSpreadsheet {
+			w.driveSvc, err = drive.NewService(ctx, option.WithHTTPClient(oauth2.NewClient(ctx, args.TokenSource)))
+			if err != nil {
+				return nil, fmt.Errorf("error creating drive service client: %w", err)
+			}
+		}
+		if err := w.restoreRollover(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// the sheet is checked before any record is written, so a missing sheet is reported right away
+	if err := w.loadSheet(ctx); err != nil {
+		return nil, err
+	}
+	if err := w.initHeaders(ctx); err != nil {
+		return nil, err
+	}
+	switch w.writeMode {
+	case WriteModeUpsert:
+		if err := w.loadIndex(ctx); err != nil {
+			return nil, fmt.Errorf("error building the row index of sheet(%s): %w", args.SheetName, err)
+		}
+	case WriteModeOverwrite:
+		if err := w.loadSnapshotMarker(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
 }
 
 // Write function writes the records to google sheet
 func (w *Writer) Write(ctx context.Context, records []sdk.Record) error {
-	var rows [][]interface{}
-
-	// Looping on every record and unmarshalling to google-sheet format.
-	// Row format: [val1, val2, ...]
-	for index, rowRecord := range records {
-		rowArr := make([]interface{}, 0)
-		err := json.Unmarshal(rowRecord.Payload.Bytes(), &rowArr)
-		if err != nil {
-			return fmt.Errorf("unable to marshal the record(index:%d) %w", index, err)
+	if len(records) == 0 {
+		return nil
+	}
+	var err error
+	switch w.writeMode {
+	case WriteModeUpsert:
+		err = w.upsert(ctx, records)
+	case WriteModeOverwrite, WriteModeReplaceOnSnapshot:
+		err = w.writeSnapshots(ctx, records)
+	default:
+		err = w.rollOverIfFull(ctx, len(records))
+		if err == nil {
+			err = w.append(ctx, records)
 		}
-		rows = append(rows, rowArr)
+	}
+	if err != nil {
+		// the header row may have been edited in the sheet, it is read again by the next write
+		w.headerCache = nil
+	}
+	return err
+}
+
+// append appends the records as new rows after the last row with data
+func (w *Writer) append(ctx context.Context, records []sdk.Record) error {
+	rows, err := w.recordsToRows(ctx, records)
+	if err != nil {
+		return err
 	}
 	if len(rows) == 0 {
 		return nil
@@ -93,30 +243,285 @@ func (w *Writer) Write(ctx context.Context, records []sdk.Record) error {
 		Values:         rows,
 	}
 
-	_, err := w.sheetSvc.Spreadsheets.Values.Append(
-		w.spreadsheetID, w.sheetName,
-		sheetValueFormat).ValueInputOption(
-		w.valueInputOption).InsertDataOption(
-		insertDataOption).Context(ctx).Do()
+	err = w.withRetries(ctx, func() error {
+		_, err := w.sheetSvc.Spreadsheets.Values.Append(
+			w.spreadsheetID, w.sheetName,
+			sheetValueFormat).ValueInputOption(
+			w.valueInputOption).InsertDataOption(
+			w.insertOption()).Context(ctx).Do()
+		return err
+	})
+	if err != nil {
+		if isRateLimitError(err) {
+			// the retries are exhausted
+			return err
+		}
+		return fmt.Errorf("appending rows to sheet(%s) failed: %w", w.sheetName, err)
+	}
+	if w.insertOption() == insertDataOption {
+		// the rows are inserted, growing the grid
+		w.rowCount += int64(len(rows))
+	}
+	return nil
+}
+
+// withRetries calls the API, retrying in case of rate limit exceeded error (429) till the retries are exhausted.
+// The back off is linear, the retry waits a second longer than the previous one.
+func (w *Writer) withRetries(ctx context.Context, call func() error) error {
+	for {
+		err := call()
+		if err == nil {
+			w.retryCount = 0
+			return nil
+		}
+		if !isRateLimitError(err) {
+			return err
+		}
+		if w.retryCount >= w.maxRetries {
+			return fmt.Errorf("rate limit exceeded, retries: %d, error: %w", w.retryCount, err)
+		}
+		w.retryCount++
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(w.retryCount) * time.Second):
+		}
+	}
+}
 
+// isRateLimitError reports whether the error is a rate limit exceeded error (429), including the error
+// returned once the retries are exhausted
+func isRateLimitError(err error) bool {
+	var gerr *googleapi.Error
+	return errors.As(err, &gerr) && gerr.Code == http.StatusTooManyRequests
+}
+
+// recordsToRows converts the records to the rows to be appended.
+// Row format: [val1, val2, ...]
+// JSON array payloads are written as is. The fields of structured and JSON object payloads are written to the columns
+// with the matching header names, the columns without a matching field are left blank.
+func (w *Writer) recordsToRows(ctx context.Context, records []sdk.Record) ([][]interface{}, error) {
+	rows, fields, err := w.parseRecords(records, false)
 	if err != nil {
-		// retry mechanism, in case of rate limit exceeded error (429)
-		if gerr, ok := err.(*googleapi.Error); ok && gerr.Code == http.StatusTooManyRequests {
-			if w.retryCount >= w.maxRetries {
-				return fmt.Errorf("rate limit exceeded, retries: %d, error: %w", w.retryCount, err)
+		return nil, err
+	}
+	if !hasFields(fields) {
+		return rows, nil
+	}
+
+	headers, err := w.readHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	headers, err = w.addMissingHeaders(ctx, headers, fields)
+	if err != nil {
+		return nil, err
+	}
+	w.mapFields(ctx, rows, fields, headers)
+	return rows, nil
+}
+
+// parseRecords parses the record payloads, the i-th row holds the values of the JSON array payload of the i-th record,
+// the i-th fields hold the fields of the structured or JSON object payload of the i-th record.
+// The payloads of the delete records are not parsed if skipDeletes is set.
+func (w *Writer) parseRecords(records []sdk.Record, skipDeletes bool) ([][]interface{}, []map[string]interface{}, error) {
+	rows := make([][]interface{}, len(records))
+	fields := make([]map[string]interface{}, len(records))
+	for index, rowRecord := range records {
+		if skipDeletes && IsDeleteRecord(rowRecord) {
+			continue
+		}
+		row, obj, err := w.format.Decode(rowRecord.Payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to marshal the record(index:%d) %w", index, err)
+		}
+		if obj != nil && w.headerRow == 0 {
+			return nil, nil, fmt.Errorf("unable to map the record(index:%d) fields to columns, the header row is not set", index)
+		}
+		rows[index] = row
+		fields[index] = obj
+	}
+	return rows, fields, nil
+}
+
+// mapFields sets the i-th row to the i-th fields mapped to the columns with the matching header names,
+// the columns without a matching field are left blank
+func (w *Writer) mapFields(ctx context.Context, rows [][]interface{}, fields []map[string]interface{}, headers []string) {
+	columns := make(map[string]int, len(headers))
+	for i, name := range headers {
+		columns[name] = i
+	}
+
+	dropped := make(map[string]bool)
+	for index, obj := range fields {
+		if obj == nil {
+			continue
+		}
+		row := make([]interface{}, len(headers))
+		for i := range row {
+			row[i] = ""
+		}
+		for name, val := range obj {
+			i, ok := columns[name]
+			if !ok {
+				dropped[name] = true
+				continue
 			}
-			w.retryCount++
-			// if retry count doesn't exceed maxRetries, retry with exponential back off
-			// block till write either succeeds or all retries are exhausted
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(time.Duration(w.retryCount) * time.Second): // exponential back off
-				return w.Write(ctx, records)
+			row[i] = cellValue(val)
+		}
+		rows[index] = row
+	}
+	if len(dropped) > 0 {
+		sdk.Logger(ctx).Warn().
+			Strs("fields", sortedKeys(dropped)).
+			Str("sheet_name", w.sheetName).
+			Msg("record fields without a matching column in the header row are not written")
+	}
+}
+
+func hasFields(fields []map[string]interface{}) bool {
+	for _, obj := range fields {
+		if obj != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// readHeaders returns the column names of the header row, resolved the same way as the source resolves them,
+// so that the records read from a sheet are written back to the same columns. The header row is read once,
+// and then again after a write fails.
+func (w *Writer) readHeaders(ctx context.Context) ([]string, error) {
+	if w.headerCache != nil {
+		return w.headerCache, nil
+	}
+	res, err := w.sheetSvc.Spreadsheets.Values.Get(w.spreadsheetID, w.headerRange(0)).
+		MajorDimension(majorDimension).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error getting header row of sheet(%s): %w", w.sheetName, err)
+	}
+	var header []interface{}
+	if len(res.Values) > 0 {
+		header = res.Values[0]
+	}
+	w.headerCache = writerHeaderNames(header)
+	return w.headerCache, nil
+}
+
+// writerHeaderNames returns the names of the header row values. The trailing blank header cells are not part
+// of the header, new columns are appended after the last header.
+func writerHeaderNames(header []interface{}) []string {
+	for len(header) > 0 && strings.TrimSpace(fmt.Sprint(header[len(header)-1])) == "" {
+		header = header[:len(header)-1]
+	}
+	return headerNames(header, 0, len(header))
+}
+
+// addMissingHeaders appends the fields missing in the header row to the header row, sorted by name,
+// if appending headers is enabled or the header row is empty. Returns the updated header names.
+func (w *Writer) addMissingHeaders(ctx context.Context, headers []string, fields []map[string]interface{}) ([]string, error) {
+	if !w.appendHeaders && len(headers) > 0 {
+		return headers, nil
+	}
+	known := make(map[string]bool, len(headers))
+	for _, name := range headers {
+		known[name] = true
+	}
+	missing := make(map[string]bool)
+	for _, obj := range fields {
+		for name := range obj {
+			if !known[name] {
+				missing[name] = true
 			}
 		}
-		return fmt.Errorf("appending rows to sheet(%s) failed: %w", w.sheetName, err)
 	}
-	w.retryCount = 0
-	return nil
+	if len(missing) == 0 {
+		return headers, nil
+	}
+
+	return w.writeHeaders(ctx, headers, sortedKeys(missing))
+}
+
+// writeHeaders writes the names to the header row after the headers. Returns the updated header names.
+func (w *Writer) writeHeaders(ctx context.Context, headers, names []string) ([]string, error) {
+	values := make([]interface{}, len(names))
+	for i, name := range names {
+		values[i] = name
+	}
+	_, err := w.sheetSvc.Spreadsheets.Values.Update(w.spreadsheetID, w.headerRange(int64(len(headers))),
+		&sheets.ValueRange{
+			MajorDimension: majorDimension,
+			Values:         [][]interface{}{values},
+		}).ValueInputOption("RAW").Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("error appending headers to sheet(%s): %w", w.sheetName, err)
+	}
+	sdk.Logger(ctx).Info().
+		Strs("headers", names).
+		Str("sheet_name", w.sheetName).
+		Msg("appended new headers")
+	headers = append(headers[:len(headers):len(headers)], names...)
+	w.headerCache = headers
+	if int64(len(headers)) > w.columnCount {
+		w.columnCount = int64(len(headers))
+	}
+	return headers, nil
+}
+
+// headerRange returns the range in A1 notation of the header row, starting from the zero based column index
+func (w *Writer) headerRange(startColumn int64) string {
+	if startColumn == 0 {
+		return fmt.Sprintf("%s!%d:%d", quoteSheetName(w.sheetName), w.headerRow, w.headerRow)
+	}
+	return fmt.Sprintf("%s!%s%d", quoteSheetName(w.sheetName), ColumnLetters(startColumn), w.headerRow)
+}
+
+// parsePayload parses the record payload, returning the row values for JSON array payloads
+// and the fields for structured and JSON object payloads
+func parsePayload(payload sdk.Data) ([]interface{}, map[string]interface{}, error) {
+	if structured, ok := payload.(sdk.StructuredData); ok {
+		return nil, structured, nil
+	}
+	var data interface{}
+	if err := json.Unmarshal(payload.Bytes(), &data); err != nil {
+		return nil, nil, err
+	}
+	switch v := data.(type) {
+	case []interface{}:
+		return v, nil, nil
+	case map[string]interface{}:
+		return nil, v, nil
+	default:
+		return nil, nil, fmt.Errorf("payload must be a JSON array or a JSON object, got: %T", data)
+	}
+}
+
+// cellValue converts the field value to a cell value, nested values are written as JSON
+func cellValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case nil:
+		return ""
+	case string, bool, float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, json.Number:
+		return v
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// quoteSheetName quotes the sheet name to be used in a range in A1 notation, escaping the single quotes
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
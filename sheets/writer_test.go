@@ -17,6 +17,8 @@ package sheets
 
 import (
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -24,16 +26,18 @@ import (
 
 	sdk "github.com/conduitio/conduit-connector-sdk"
 	"github.com/stretchr/testify/assert"
-	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 	"google.golang.org/api/sheets/v4"
 )
 
 func TestWriter_NoRecord(t *testing.T) {
 	ctx := context.Background()
-	writer, err := NewWriter(ctx, &oauth2.Config{}, &oauth2.Token{}, "dummy_spreadsheet_id", "Sheet", "", 3)
-	assert.NoError(t, err)
-	err = writer.Write(ctx, nil)
+	writer := &Writer{
+		spreadsheetID: "dummy_spreadsheet_id",
+		sheetName:     "Sheet",
+		maxRetries:    3,
+	}
+	err := writer.Write(ctx, nil)
 	assert.NoError(t, err)
 }
 
@@ -89,3 +93,55 @@ func TestWriter_429(t *testing.T) {
 	err = writer.Write(ctx, []sdk.Record{{Payload: sdk.RawData(`["1","2","3","4"]`)}})
 	assert.EqualError(t, err, "rate limit exceeded, retries: 2, error: googleapi: got HTTP response code 429 with body: {}")
 }
+
+func TestWriter_HeaderRow(t *testing.T) {
+	var updated, appended sheets.ValueRange
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v4/spreadsheets/dummy/values/'sheet'!1:1":
+			_, _ = w.Write([]byte(`{"values": [["id", "name", "", "email", " "]]}`))
+		case r.Method == http.MethodPut && r.URL.Path == "/v4/spreadsheets/dummy/values/'sheet'!E1":
+			assert.NoError(t, json.Unmarshal(body, &updated))
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/v4/spreadsheets/dummy/values/sheet:append":
+			assert.NoError(t, json.Unmarshal(body, &appended))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer testServer.Close()
+	sheetSvc, err := sheets.NewService(
+		context.Background(),
+		option.WithEndpoint(testServer.URL),
+		option.WithHTTPClient(&http.Client{}))
+	assert.NoError(t, err)
+	writer := &Writer{
+		sheetSvc:         sheetSvc,
+		sheetName:        "sheet",
+		spreadsheetID:    "dummy",
+		valueInputOption: "USER_ENTERED",
+		headerRow:        1,
+		appendHeaders:    true,
+	}
+	err = writer.Write(context.Background(), []sdk.Record{
+		{Payload: sdk.StructuredData{"id": 1, "email": "foo@example.com", "tags": []string{"a"}}},
+		{Payload: sdk.RawData(`{"name":"bar","C":"x","age":30,"deleted":null}`)},
+		{Payload: sdk.RawData(`["3","baz"]`)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"age", "deleted", "tags"}}, updated.Values)
+	assert.Equal(t, [][]interface{}{
+		{float64(1), "", "", "foo@example.com", "", "", `["a"]`},
+		{"", "bar", "x", "", float64(30), "", ""},
+		{"3", "baz"},
+	}, appended.Values)
+}
+
+func TestWriter_HeaderRowNotSet(t *testing.T) {
+	writer := &Writer{sheetName: "sheet", spreadsheetID: "dummy"}
+	err := writer.Write(context.Background(), []sdk.Record{{Payload: sdk.RawData(`{"id":"1"}`)}})
+	assert.EqualError(t, err, "unable to map the record(index:0) fields to columns, the header row is not set")
+}
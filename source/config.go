@@ -18,10 +18,14 @@ package source
 
 import (
 	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/conduitio/conduit-connector-google-sheets/config"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
 )
 
 const (
@@ -30,13 +34,87 @@ const (
 	KeyDateTimeRenderOption = "dateTimeRenderOption"
 	KeyValueRenderOption    = "valueRenderOption"
 
+	// KeySheetName is the config name for the title of the sheet to be read
+	KeySheetName = "sheetName"
+	// KeyRange is the config name for the range to be read in A1 notation, e.g. Orders!A1:F
+	KeyRange = "range"
+	// KeyNamedRange is the config name for the named range to be read
+	KeyNamedRange = "namedRange"
+	// KeySheetNames is the config name for the comma separated titles of the sheets to be read, "*" reads all the sheets
+	KeySheetNames = "sheetNames"
+	// KeyHeaderRow is the config name for the row number holding the field names, relative to the start of the range
+	KeyHeaderRow = "headerRow"
+	// KeyMode is the config name for the read mode, ModeAppend or ModeCDC
+	KeyMode = "mode"
+	// KeyKeyColumn is the config name for the column identifying the rows in cdc mode
+	KeyKeyColumn = "keyColumn"
+	// KeyStateFile is the config name for the path of the file storing the state of the rows in cdc mode
+	KeyStateFile = "stateFile"
+	// KeyPageSize is the config name for the maximum number of rows read from a sheet by a request
+	KeyPageSize = "pageSize"
+	// KeyIncrementalColumn is the config name for the column used as the cursor of the rows read
+	KeyIncrementalColumn = "incrementalColumn"
+	// KeyRowIdentity is the config name for the identification of the rows read in append mode, RowIdentityOffset or RowIdentityHash
+	KeyRowIdentity = "rowIdentity"
+	// KeySnapshot is the config name for enabling the snapshot phase
+	KeySnapshot = "snapshot"
+	// KeyTypedValues is the config name for enabling the conversion of the values using the number formats of the columns
+	KeyTypedValues = "typedValues"
+	// KeySchemaFormat is the config name for the format of the schema definitions set in the record metadata
+	KeySchemaFormat = "schemaFormat"
+	// KeyKeyColumns is the config name for the comma separated columns whose values compose the record key
+	KeyKeyColumns = "keyColumns"
+	// KeyKeySeparator is the config name for the separator joining the values of the key columns in raw record keys
+	KeyKeySeparator = "keySeparator"
+	// KeyKeyFormat is the config name for the format of the record keys composed of the key columns, KeyFormatRaw or KeyFormatStructured
+	KeyKeyFormat = "keyFormat"
+	// KeyEmptyKeyPolicy is the config name for the handling of the rows with an empty record key, EmptyKeySkip or EmptyKeyError
+	KeyEmptyKeyPolicy = "emptyKeyPolicy"
+	// KeyWatchAddress is the config name for the address the listener receiving the Drive push notifications listens on
+	KeyWatchAddress = "watchAddress"
+	// KeyWatchURL is the config name for the public HTTPS URL the Drive push notifications are sent to
+	KeyWatchURL = "watchURL"
+	// KeyWatchTTL is the config name for the time to live of the Drive notification channel
+	KeyWatchTTL = "watchTTL"
+	// KeyWatchFallbackPeriod is the config name for the time after which the sheet is polled, if no notification is received
+	KeyWatchFallbackPeriod = "watchFallbackPeriod"
+
+	// ModeAppend reads the rows appended to the sheet
+	ModeAppend = "append"
+	// ModeCDC reads the created, updated and deleted rows
+	ModeCDC = "cdc"
+
+	// RowIdentityOffset identifies the rows read by the row offset of the last row read
+	RowIdentityOffset = "offset"
+	// RowIdentityHash identifies the rows read by the hash of their values, stored in the state file
+	RowIdentityHash = "hash"
+
+	// KeyFormatRaw joins the values of the key columns by the key separator
+	KeyFormatRaw = "raw"
+	// KeyFormatStructured keys the values of the key columns by the key column names
+	KeyFormatStructured = "structured"
+
+	// EmptyKeySkip skips the rows with an empty record key
+	EmptyKeySkip = "skip"
+	// EmptyKeyError fails the read of the rows with an empty record key
+	EmptyKeyError = "error"
+
 	// defaultPollingPeriod is the value assumed for the pooling period when the
 	// config omits the polling period parameter
 	defaultPollingPeriod        = "6s"
 	defaultDateTimeRenderOption = "FORMATTED_STRING"
 	defaultValueRenderOption    = "FORMATTED_VALUE"
+	defaultPageSize             = 1000
+	defaultKeySeparator         = "|"
+	defaultWatchTTL             = time.Hour
+	defaultWatchFallbackPeriod  = 5 * time.Minute
+	// maxWatchTTL is the maximum time to live of a Drive notification channel watching a file
+	maxWatchTTL = 24 * time.Hour
 )
 
+// columnLettersRegexp matches the column letters. e.g. A, AB
+var columnLettersRegexp = regexp.MustCompile(`^[A-Za-z]+$`)
+
 // Config represents source configuration with Google-Sheets configurations
 type Config struct {
 	config.Config
@@ -46,6 +124,70 @@ type Config struct {
 	// Refer: https://developers.google.com/sheets/api/reference/rest/v4/spreadsheets.values/batchGet#query-parameters
 	DateTimeRenderOption string // values: SERIAL_NUMBER, FORMATTED_STRING // default: SERIAL_NUMBER
 	ValueRenderOption    string // values: FORMATTED_VALUE, UNFORMATTED_VALUE, FORMULA// default: FORMATTED_VALUE
+
+	// range selection, at most one of the values is set. If none is set the sheet gid from sheet URL is read.
+	// These are resolved to the sheet gid and the grid range using the spreadsheet metadata on Open
+	SheetName  string
+	Range      string
+	NamedRange string
+	SheetNames []string
+
+	// HeaderRow is the row number holding the field names, the rows are read as structured data keyed
+	// by the field names if set. 0 if the sheet has no header row, the rows are read as JSON arrays then.
+	HeaderRow int64
+
+	// Mode is ModeAppend to read the appended rows, or ModeCDC to read the created, updated and deleted rows
+	Mode string
+	// KeyColumn identifies the rows in cdc mode, the header name if HeaderRow is set, the column letters otherwise.
+	// The rows are identified by the row number if not set.
+	KeyColumn string
+	// StateFile is the path of the file storing the state of the rows, required in cdc mode
+	StateFile string
+
+	// PageSize is the maximum number of rows read from a sheet by a request, the pages are read
+	// back to back while catching up with the sheet, then the new rows are read every polling period
+	PageSize int64
+
+	// IncrementalColumn is the column holding a timestamp or a sequence, the rows with a value greater than the greatest
+	// value read are read, instead of the rows after the row offset. The header name if HeaderRow is set, the column letters otherwise.
+	// The values are compared unformatted, ValueRenderOption must be UNFORMATTED_VALUE and DateTimeRenderOption SERIAL_NUMBER.
+	IncrementalColumn string
+
+	// HashRows enables identifying the rows read in append mode by the hash of their values instead of the row offset,
+	// so the rows inserted, deleted or moved are reconciled. The ids of the rows read are stored in the StateFile.
+	HashRows bool
+
+	// Snapshot enables the snapshot phase, the rows existing when the source is started are read as snapshots
+	// before the created rows, or the row changes in cdc mode, are read
+	Snapshot bool
+
+	// TypedValues enables converting the values read to numbers, booleans and timestamps using the number formats
+	// of the columns, the values are read unformatted then. The schema of the columns is set in the record metadata.
+	TypedValues bool
+
+	// SchemaFormat is sheets.SchemaFormatAvro or sheets.SchemaFormatJSON to set the schema definition of the sheet,
+	// derived from the header row and the column types, in the record metadata. No definition is set if empty.
+	SchemaFormat string
+
+	// Format is the format of the record payloads, structured data with a header row and JSON arrays otherwise if not set
+	Format sheets.Format
+
+	// KeyColumns are the columns whose values compose the record key, the header names if HeaderRow is set,
+	// the column letters otherwise. The record key is the row number, or the key column in cdc mode, if not set.
+	KeyColumns []string
+	// KeySeparator joins the values of the key columns, unless the record key is structured
+	KeySeparator string
+	// StructuredKey enables structured record keys keyed by the key columns
+	StructuredKey bool
+	// SkipEmptyKeys enables skipping the rows whose key columns are all blank, the read fails on these rows otherwise
+	SkipEmptyKeys bool
+
+	// WatchAddress is the address the listener receiving the Drive push notifications listens on, the reads are
+	// triggered by the notifications if set, and the sheet is polled if no notification is received for WatchFallbackPeriod
+	WatchAddress        string
+	WatchURL            string
+	WatchTTL            time.Duration
+	WatchFallbackPeriod time.Duration
 }
 
 // Parse attempts to parse the configurations into a Config struct that Source could utilize
@@ -87,12 +229,357 @@ func Parse(cfg map[string]string) (Config, error) {
 		)
 	}
 
+	sheetName := strings.TrimSpace(cfg[KeySheetName])
+	a1Range := strings.TrimSpace(cfg[KeyRange])
+	namedRange := strings.TrimSpace(cfg[KeyNamedRange])
+	sheetNames := strings.TrimSpace(cfg[KeySheetNames])
+	if err := validateRangeSelector(commonConfig, sheetName, a1Range, namedRange, sheetNames); err != nil {
+		return Config{}, err
+	}
+
+	var headerRow int64
+	if val := strings.TrimSpace(cfg[KeyHeaderRow]); val != "" {
+		headerRow, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || headerRow < 0 {
+			return Config{}, fmt.Errorf("%q config value must be a non-negative integer, got: %q", KeyHeaderRow, val)
+		}
+	}
+
+	mode, keyColumn, stateFile, err := parseCDCConfig(cfg, headerRow)
+	if err != nil {
+		return Config{}, err
+	}
+
+	pageSize := int64(defaultPageSize)
+	if val := strings.TrimSpace(cfg[KeyPageSize]); val != "" {
+		pageSize, err = strconv.ParseInt(val, 10, 64)
+		if err != nil || pageSize <= 0 {
+			return Config{}, fmt.Errorf("%q config value must be a positive integer, got: %q", KeyPageSize, val)
+		}
+	}
+
+	incrementalColumn := strings.TrimSpace(cfg[KeyIncrementalColumn])
+	if incrementalColumn != "" {
+		if mode != ModeAppend {
+			return Config{}, fmt.Errorf("%q config value can only be set, if %q is %q", KeyIncrementalColumn, KeyMode, ModeAppend)
+		}
+		if headerRow == 0 && !columnLettersRegexp.MatchString(incrementalColumn) {
+			return Config{}, fmt.Errorf(
+				"%q config value must be the column letters, if %q is not set, got: %q",
+				KeyIncrementalColumn, KeyHeaderRow, incrementalColumn,
+			)
+		}
+	}
+
+	hashRows, err := parseRowIdentityConfig(cfg, mode, incrementalColumn, stateFile)
+	if err != nil {
+		return Config{}, err
+	}
+
+	snapshot := true
+	if val := strings.TrimSpace(cfg[KeySnapshot]); val != "" {
+		snapshot, err = strconv.ParseBool(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a boolean, got: %q", KeySnapshot, val)
+		}
+	}
+
+	typedValues := false
+	if val := strings.TrimSpace(cfg[KeyTypedValues]); val != "" {
+		typedValues, err = strconv.ParseBool(val)
+		if err != nil {
+			return Config{}, fmt.Errorf("%q config value must be a boolean, got: %q", KeyTypedValues, val)
+		}
+	}
+	if typedValues {
+		// the values are converted from the unformatted values, the dates being serial numbers
+		for _, key := range []string{KeyDateTimeRenderOption, KeyValueRenderOption} {
+			if strings.TrimSpace(cfg[key]) != "" {
+				return Config{}, fmt.Errorf("%q config value can't be set, if %q is enabled", key, KeyTypedValues)
+			}
+		}
+		dateTimeOption = "SERIAL_NUMBER"
+		valueOption = "UNFORMATTED_VALUE"
+	}
+	if incrementalColumn != "" {
+		// the cursors are compared as numbers, the formatted dates and numbers don't sort as strings
+		if valueOption != "UNFORMATTED_VALUE" {
+			return Config{}, fmt.Errorf("%q config value must be %q, if %q is set and %q is not enabled",
+				KeyValueRenderOption, "UNFORMATTED_VALUE", KeyIncrementalColumn, KeyTypedValues)
+		}
+		if dateTimeOption != "SERIAL_NUMBER" {
+			return Config{}, fmt.Errorf("%q config value must be %q, if %q is set and %q is not enabled",
+				KeyDateTimeRenderOption, "SERIAL_NUMBER", KeyIncrementalColumn, KeyTypedValues)
+		}
+	}
+
+	schemaFormat := strings.TrimSpace(cfg[KeySchemaFormat])
+	if schemaFormat != "" && schemaFormat != sheets.SchemaFormatAvro && schemaFormat != sheets.SchemaFormatJSON {
+		return Config{}, fmt.Errorf(
+			"invalid value received for config(`%s`):`%s`, should be oneof [`%s`, `%s`]",
+			KeySchemaFormat, schemaFormat, sheets.SchemaFormatAvro, sheets.SchemaFormatJSON,
+		)
+	}
+
+	format, err := sheets.ParseFormat(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
+	keys, err := parseKeyConfig(cfg, headerRow)
+	if err != nil {
+		return Config{}, err
+	}
+
+	watch, err := parseWatchConfig(cfg)
+	if err != nil {
+		return Config{}, err
+	}
+
 	sourceConfig := Config{
 		Config:               commonConfig,
 		PollingPeriod:        timeInterval,
 		DateTimeRenderOption: dateTimeOption,
 		ValueRenderOption:    valueOption,
+		SheetName:            sheetName,
+		Range:                a1Range,
+		NamedRange:           namedRange,
+		SheetNames:           config.SplitList(sheetNames),
+		HeaderRow:            headerRow,
+		Mode:                 mode,
+		KeyColumn:            keyColumn,
+		StateFile:            stateFile,
+		PageSize:             pageSize,
+		IncrementalColumn:    incrementalColumn,
+		HashRows:             hashRows,
+		Snapshot:             snapshot,
+		TypedValues:          typedValues,
+		SchemaFormat:         schemaFormat,
+		Format:               format,
+		KeyColumns:           keys.columns,
+		KeySeparator:         keys.separator,
+		StructuredKey:        keys.structured,
+		SkipEmptyKeys:        keys.skipEmpty,
+		WatchAddress:         watch.address,
+		WatchURL:             watch.url,
+		WatchTTL:             watch.ttl,
+		WatchFallbackPeriod:  watch.fallbackPeriod,
 	}
 
 	return sourceConfig, nil
 }
+
+// validateRangeSelector validates that at most one of sheet name, range, named range and sheet names is set.
+// Without the sheet gid from the sheet URL, one of them is required and the range must include the sheet name.
+func validateRangeSelector(commonConfig config.Config, sheetName, a1Range, namedRange, sheetNames string) error {
+	count := 0
+	for _, val := range []string{sheetName, a1Range, namedRange, sheetNames} {
+		if val != "" {
+			count++
+		}
+	}
+	if count > 1 {
+		return fmt.Errorf("only one of %q, %q, %q, %q config value can be set", KeySheetName, KeyRange, KeyNamedRange, KeySheetNames)
+	}
+	if count == 0 && !commonConfig.HasSheetID {
+		return fmt.Errorf(
+			"one of %q, %q, %q, %q config value must be set, if %q is not set",
+			KeySheetName, KeyRange, KeyNamedRange, KeySheetNames, config.KeySheetURL,
+		)
+	}
+	if sheetNames != "" {
+		names := config.SplitList(sheetNames)
+		if len(names) == 0 {
+			return fmt.Errorf("invalid %q config value: no sheet names found", KeySheetNames)
+		}
+		if len(names) > 1 {
+			for _, name := range names {
+				if name == sheets.AllSheets {
+					return fmt.Errorf("invalid %q config value: %q can't be combined with sheet names", KeySheetNames, sheets.AllSheets)
+				}
+			}
+		}
+	}
+	if a1Range == "" {
+		return nil
+	}
+
+	parsed, err := sheets.ParseA1Range(a1Range)
+	if err != nil {
+		return fmt.Errorf("invalid %q config value: %w", KeyRange, err)
+	}
+	if parsed.SheetName == "" && !commonConfig.HasSheetID {
+		return fmt.Errorf("%q config value must include the sheet name, if %q is not set", KeyRange, config.KeySheetURL)
+	}
+	return nil
+}
+
+// parseCDCConfig parses the read mode, the key column and the state file, which are valid in cdc mode only
+func parseCDCConfig(cfg map[string]string, headerRow int64) (string, string, string, error) {
+	mode := strings.TrimSpace(cfg[KeyMode])
+	if mode == "" {
+		mode = ModeAppend
+	}
+	if mode != ModeAppend && mode != ModeCDC {
+		return "", "", "", fmt.Errorf(
+			"invalid value received for config(`%s`):`%s`, should be oneof [`%s`, `%s`]",
+			KeyMode, mode, ModeAppend, ModeCDC,
+		)
+	}
+
+	keyColumn := strings.TrimSpace(cfg[KeyKeyColumn])
+	stateFile := strings.TrimSpace(cfg[KeyStateFile])
+	if mode != ModeCDC {
+		if keyColumn != "" {
+			return "", "", "", fmt.Errorf("%q and %q config values can only be set, if %q is %q", KeyKeyColumn, KeyStateFile, KeyMode, ModeCDC)
+		}
+		// the ids of the rows read are stored in the state file, if the rows are identified by their hash
+		if stateFile != "" && strings.TrimSpace(cfg[KeyRowIdentity]) != RowIdentityHash {
+			return "", "", "", fmt.Errorf("%q config value can only be set, if %q is %q or %q is %q",
+				KeyStateFile, KeyMode, ModeCDC, KeyRowIdentity, RowIdentityHash)
+		}
+		return mode, "", stateFile, nil
+	}
+	if keyColumn != "" && headerRow == 0 && !columnLettersRegexp.MatchString(keyColumn) {
+		return "", "", "", fmt.Errorf("%q config value must be the column letters, if %q is not set, got: %q", KeyKeyColumn, KeyHeaderRow, keyColumn)
+	}
+	// the fingerprints of all the rows would have to be stored in the position of every record otherwise
+	if stateFile == "" {
+		return "", "", "", fmt.Errorf("%q config value must be set, if %q is %q", KeyStateFile, KeyMode, ModeCDC)
+	}
+	return mode, keyColumn, stateFile, nil
+}
+
+// parseRowIdentityConfig parses the identification of the rows read, and returns whether the rows are identified
+// by their hash rather than the row offset
+func parseRowIdentityConfig(cfg map[string]string, mode, incrementalColumn, stateFile string) (bool, error) {
+	rowIdentity := strings.TrimSpace(cfg[KeyRowIdentity])
+	switch rowIdentity {
+	case "", RowIdentityOffset:
+		return false, nil
+	case RowIdentityHash:
+	default:
+		return false, fmt.Errorf(
+			"invalid value received for config(`%s`):`%s`, should be oneof [`%s`, `%s`]",
+			KeyRowIdentity, rowIdentity, RowIdentityOffset, RowIdentityHash,
+		)
+	}
+	if mode != ModeAppend || incrementalColumn != "" {
+		return false, fmt.Errorf("%q config value can only be %q, if %q is %q and %q is not set",
+			KeyRowIdentity, RowIdentityHash, KeyMode, ModeAppend, KeyIncrementalColumn)
+	}
+	if stateFile == "" {
+		return false, fmt.Errorf("%q config value must be set, if %q is %q", KeyStateFile, KeyRowIdentity, RowIdentityHash)
+	}
+	return true, nil
+}
+
+// keyConfig holds the columns composing the record key and the options of the record keys
+type keyConfig struct {
+	columns    []string
+	separator  string
+	structured bool
+	skipEmpty  bool
+}
+
+// parseKeyConfig parses the columns composing the record key, and the options of the record keys,
+// which can only be set along with the key columns
+func parseKeyConfig(cfg map[string]string, headerRow int64) (keyConfig, error) {
+	keyColumns := config.SplitList(cfg[KeyKeyColumns])
+	separator, keyFormat, policy := cfg[KeyKeySeparator], strings.TrimSpace(cfg[KeyKeyFormat]), strings.TrimSpace(cfg[KeyEmptyKeyPolicy])
+	if len(keyColumns) == 0 {
+		if separator != "" || keyFormat != "" || policy != "" {
+			return keyConfig{}, fmt.Errorf("%q, %q and %q config values can only be set, if %q is set",
+				KeyKeySeparator, KeyKeyFormat, KeyEmptyKeyPolicy, KeyKeyColumns)
+		}
+		return keyConfig{}, nil
+	}
+	if headerRow == 0 {
+		for _, column := range keyColumns {
+			if !columnLettersRegexp.MatchString(column) {
+				return keyConfig{}, fmt.Errorf("%q config value must be the column letters, if %q is not set, got: %q",
+					KeyKeyColumns, KeyHeaderRow, column)
+			}
+		}
+	}
+
+	if separator == "" {
+		separator = defaultKeySeparator
+	}
+	if keyFormat == "" {
+		keyFormat = KeyFormatRaw
+	}
+	if keyFormat != KeyFormatRaw && keyFormat != KeyFormatStructured {
+		return keyConfig{}, fmt.Errorf(
+			"invalid value received for config(`%s`):`%s`, should be oneof [`%s`, `%s`]",
+			KeyKeyFormat, keyFormat, KeyFormatRaw, KeyFormatStructured,
+		)
+	}
+	if policy == "" {
+		policy = EmptyKeySkip
+	}
+	if policy != EmptyKeySkip && policy != EmptyKeyError {
+		return keyConfig{}, fmt.Errorf(
+			"invalid value received for config(`%s`):`%s`, should be oneof [`%s`, `%s`]",
+			KeyEmptyKeyPolicy, policy, EmptyKeySkip, EmptyKeyError,
+		)
+	}
+	return keyConfig{
+		columns:    keyColumns,
+		separator:  separator,
+		structured: keyFormat == KeyFormatStructured,
+		skipEmpty:  policy == EmptyKeySkip,
+	}, nil
+}
+
+// watchConfig holds the address and the URL of the Drive push notifications, the time to live of the channel
+// and the fallback period
+type watchConfig struct {
+	address        string
+	url            string
+	ttl            time.Duration
+	fallbackPeriod time.Duration
+}
+
+// parseWatchConfig parses the address and the URL of the Drive push notifications, which are set together,
+// the time to live of the channel and the fallback period, which are only valid with the notifications
+func parseWatchConfig(cfg map[string]string) (watchConfig, error) {
+	address := strings.TrimSpace(cfg[KeyWatchAddress])
+	watchURL := strings.TrimSpace(cfg[KeyWatchURL])
+	ttl := strings.TrimSpace(cfg[KeyWatchTTL])
+	fallbackPeriod := strings.TrimSpace(cfg[KeyWatchFallbackPeriod])
+	if address == "" && watchURL == "" {
+		if ttl != "" || fallbackPeriod != "" {
+			return watchConfig{}, fmt.Errorf("%q and %q config values can only be set, if %q is set", KeyWatchTTL, KeyWatchFallbackPeriod, KeyWatchAddress)
+		}
+		return watchConfig{}, nil
+	}
+	if address == "" || watchURL == "" {
+		return watchConfig{}, fmt.Errorf("%q and %q config values must be set together", KeyWatchAddress, KeyWatchURL)
+	}
+	parsed, err := url.Parse(watchURL)
+	if err != nil || parsed.Scheme != "https" || parsed.Host == "" {
+		return watchConfig{}, fmt.Errorf("%q config value must be an HTTPS URL, got: %q", KeyWatchURL, watchURL)
+	}
+
+	watchTTL := defaultWatchTTL
+	if ttl != "" {
+		watchTTL, err = time.ParseDuration(ttl)
+		if err != nil || watchTTL <= 0 || watchTTL > maxWatchTTL {
+			return watchConfig{}, fmt.Errorf("%q config value must be a duration up to %s, got: %q", KeyWatchTTL, maxWatchTTL, ttl)
+		}
+	}
+	watchFallbackPeriod := defaultWatchFallbackPeriod
+	if fallbackPeriod != "" {
+		watchFallbackPeriod, err = time.ParseDuration(fallbackPeriod)
+		if err != nil || watchFallbackPeriod <= 0 {
+			return watchConfig{}, fmt.Errorf("%q config value must be a positive duration, got: %q", KeyWatchFallbackPeriod, fallbackPeriod)
+		}
+	}
+	return watchConfig{
+		address:        address,
+		url:            watchURL,
+		ttl:            watchTTL,
+		fallbackPeriod: watchFallbackPeriod,
+	}, nil
+}
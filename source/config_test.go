@@ -41,7 +41,7 @@ func TestParse(t *testing.T) {
 		{
 			testCase: "Checking against default values",
 			params:   map[string]string{},
-			err:      fmt.Errorf("error parsing shared config, \"credentialsFile\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, one of \"credentialsFile\", \"credentialsJSON\", \"credentialsJSONBase64\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -52,7 +52,7 @@ func TestParse(t *testing.T) {
 				config.KeySheetURL:        "",
 				KeyPollingPeriod:          "",
 			},
-			err:      fmt.Errorf("error parsing shared config, \"credentialsFile\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, one of \"credentialsFile\", \"credentialsJSON\", \"credentialsJSONBase64\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -63,7 +63,7 @@ func TestParse(t *testing.T) {
 				config.KeySheetURL:        "",
 				KeyPollingPeriod:          "",
 			},
-			err:      fmt.Errorf("error parsing shared config, \"tokensFile\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, auth mode \"oauth\" (detected from credentials): one of \"tokensFile\", \"tokenJSON\", \"tokenJSONBase64\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -73,7 +73,7 @@ func TestParse(t *testing.T) {
 				config.KeyCredentialsFile: validCredFile,
 				config.KeySheetURL:        "",
 			},
-			err:      fmt.Errorf("error parsing shared config, \"sheetsURL\" config value must be set"),
+			err:      fmt.Errorf("error parsing shared config, one of \"sheetsURL\", \"spreadsheetId\" config value must be set"),
 			expected: Config{},
 		},
 		{
@@ -97,10 +97,16 @@ func TestParse(t *testing.T) {
 			err: nil,
 			expected: Config{
 				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
 					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 					GoogleSheetID:       158080911,
+					HasSheetID:          true,
 				},
 				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
 				DateTimeRenderOption: defaultDateTimeRenderOption,
 				ValueRenderOption:    defaultValueRenderOption,
 			},
@@ -116,14 +122,606 @@ func TestParse(t *testing.T) {
 			err: nil,
 			expected: Config{
 				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
 					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
 					GoogleSheetID:       158080911,
+					HasSheetID:          true,
 				},
 				PollingPeriod:        2 * time.Minute,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
 				DateTimeRenderOption: defaultDateTimeRenderOption,
 				ValueRenderOption:    defaultValueRenderOption,
 			},
 		},
+		{
+			testCase: "Checking for spreadsheet id with range",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeyRange:                  "Orders!A1:F",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				Range:                "Orders!A1:F",
+			},
+		},
+		{
+			testCase: "Checking for spreadsheet id without sheet selection",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+			},
+			err:      fmt.Errorf("one of \"sheetName\", \"range\", \"namedRange\", \"sheetNames\" config value must be set, if \"sheetsURL\" is not set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for spreadsheet id with range without sheet name",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeyRange:                  "A1:F",
+			},
+			err:      fmt.Errorf("\"range\" config value must include the sheet name, if \"sheetsURL\" is not set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for multiple sheet selections",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySheetURL:        "https://docs.google.com/spreadsheets/d/19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4/edit#gid=158080911",
+				KeySheetName:              "Orders",
+				KeyNamedRange:             "open_tickets",
+			},
+			err:      fmt.Errorf("only one of \"sheetName\", \"range\", \"namedRange\", \"sheetNames\" config value can be set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for spreadsheet id with sheet names",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetNames:             "Jan, Feb,,Mar",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetNames:           []string{"Jan", "Feb", "Mar"},
+			},
+		},
+		{
+			testCase: "Checking for all sheets combined with sheet names",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetNames:             "*,Jan",
+			},
+			err:      fmt.Errorf("invalid \"sheetNames\" config value: \"*\" can't be combined with sheet names"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "1",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+				HeaderRow:            1,
+			},
+		},
+		{
+			testCase: "Checking for invalid header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "-1",
+			},
+			err:      fmt.Errorf("\"headerRow\" config value must be a non-negative integer, got: \"-1\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for page size",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyPageSize:               "500",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             500,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+			},
+		},
+		{
+			testCase: "Checking for invalid page size",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyPageSize:               "0",
+			},
+			err:      fmt.Errorf("\"pageSize\" config value must be a positive integer, got: \"0\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for typed values",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyTypedValues:            "true",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				TypedValues:          true,
+				DateTimeRenderOption: "SERIAL_NUMBER",
+				ValueRenderOption:    "UNFORMATTED_VALUE",
+				SheetName:            "Orders",
+			},
+		},
+		{
+			testCase: "Checking for typed values with render option",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyTypedValues:            "true",
+				KeyValueRenderOption:      "FORMATTED_VALUE",
+			},
+			err:      fmt.Errorf("\"valueRenderOption\" config value can't be set, if \"typedValues\" is enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for invalid schema format",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeySchemaFormat:           "protobuf",
+			},
+			err:      fmt.Errorf("invalid value received for config(`schemaFormat`):`protobuf`, should be oneof [`avro`, `json`]"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for record key columns",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "1",
+				KeyKeyColumns:             "region, order id",
+				KeyKeyFormat:              "structured",
+				KeyEmptyKeyPolicy:         "error",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+				HeaderRow:            1,
+				KeyColumns:           []string{"region", "order id"},
+				KeySeparator:         "|",
+				StructuredKey:        true,
+			},
+		},
+		{
+			testCase: "Checking for record key columns without header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyKeyColumns:             "A,order id",
+			},
+			err:      fmt.Errorf("\"keyColumns\" config value must be the column letters, if \"headerRow\" is not set, got: \"order id\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for record key separator without key columns",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyKeySeparator:           "-",
+			},
+			err:      fmt.Errorf("\"keySeparator\", \"keyFormat\" and \"emptyKeyPolicy\" config values can only be set, if \"keyColumns\" is set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for invalid empty key policy",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyKeyColumns:             "A",
+				KeyEmptyKeyPolicy:         "ignore",
+			},
+			err:      fmt.Errorf("invalid value received for config(`emptyKeyPolicy`):`ignore`, should be oneof [`skip`, `error`]"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for row identity by hash",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyRowIdentity:            "hash",
+				KeyStateFile:              "/tmp/orders.json",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+				StateFile:            "/tmp/orders.json",
+				HashRows:             true,
+			},
+		},
+		{
+			testCase: "Checking for row identity by hash in cdc mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyMode:                   "cdc",
+				KeyStateFile:              "/tmp/orders.json",
+				KeyRowIdentity:            "hash",
+			},
+			err:      fmt.Errorf("\"rowIdentity\" config value can only be \"hash\", if \"mode\" is \"append\" and \"incrementalColumn\" is not set"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for row identity by hash without state file",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyRowIdentity:            "hash",
+			},
+			err:      fmt.Errorf("\"stateFile\" config value must be set, if \"rowIdentity\" is \"hash\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for drive notifications",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyWatchAddress:           ":8080",
+				KeyWatchURL:               "https://example.com/notify",
+				KeyWatchTTL:               "2h",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+				WatchAddress:         ":8080",
+				WatchURL:             "https://example.com/notify",
+				WatchTTL:             2 * time.Hour,
+				WatchFallbackPeriod:  5 * time.Minute,
+			},
+		},
+		{
+			testCase: "Checking for watch address without URL",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyWatchAddress:           ":8080",
+			},
+			err:      fmt.Errorf("\"watchAddress\" and \"watchURL\" config values must be set together"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for HTTP watch URL",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyWatchAddress:           ":8080",
+				KeyWatchURL:               "http://example.com/notify",
+			},
+			err:      fmt.Errorf("\"watchURL\" config value must be an HTTPS URL, got: \"http://example.com/notify\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for watch TTL over a day",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyWatchAddress:           ":8080",
+				KeyWatchURL:               "https://example.com/notify",
+				KeyWatchTTL:               "25h",
+			},
+			err:      fmt.Errorf("\"watchTTL\" config value must be a duration up to 24h0m0s, got: \"25h\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for incremental column",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "1",
+				KeyIncrementalColumn:      "updated_at",
+				KeyValueRenderOption:      "UNFORMATTED_VALUE",
+				KeyDateTimeRenderOption:   "SERIAL_NUMBER",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: "SERIAL_NUMBER",
+				ValueRenderOption:    "UNFORMATTED_VALUE",
+				SheetName:            "Orders",
+				HeaderRow:            1,
+				IncrementalColumn:    "updated_at",
+			},
+		},
+		{
+			testCase: "Checking for incremental column with formatted values",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "1",
+				KeyIncrementalColumn:      "updated_at",
+			},
+			err: fmt.Errorf("\"valueRenderOption\" config value must be \"UNFORMATTED_VALUE\", " +
+				"if \"incrementalColumn\" is set and \"typedValues\" is not enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for incremental column with formatted dates",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyHeaderRow:              "1",
+				KeyIncrementalColumn:      "updated_at",
+				KeyValueRenderOption:      "UNFORMATTED_VALUE",
+			},
+			err: fmt.Errorf("\"dateTimeRenderOption\" config value must be \"SERIAL_NUMBER\", " +
+				"if \"incrementalColumn\" is set and \"typedValues\" is not enabled"),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for incremental column in cdc mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyMode:                   "cdc",
+				KeyStateFile:              "/tmp/orders.json",
+				KeyIncrementalColumn:      "B",
+			},
+			err:      fmt.Errorf("\"incrementalColumn\" config value can only be set, if \"mode\" is \"append\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for snapshot disabled",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeySnapshot:               "false",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeAppend,
+				PageSize:             1000,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+			},
+		},
+		{
+			testCase: "Checking for invalid snapshot",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeySnapshot:               "maybe",
+			},
+			err:      fmt.Errorf("\"snapshot\" config value must be a boolean, got: \"maybe\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for cdc mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyMode:                   "cdc",
+				KeyKeyColumn:              "B",
+				KeyStateFile:              "/tmp/orders.json",
+			},
+			err: nil,
+			expected: Config{
+				Config: config.Config{
+					AuthMode:            config.AuthModeOAuth,
+					TokenStore:          config.FileTokenStore{Path: validCredFile},
+					GoogleSpreadsheetID: "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				},
+				PollingPeriod:        6 * time.Second,
+				Mode:                 ModeCDC,
+				PageSize:             1000,
+				Snapshot:             true,
+				DateTimeRenderOption: defaultDateTimeRenderOption,
+				ValueRenderOption:    defaultValueRenderOption,
+				SheetName:            "Orders",
+				KeyColumn:            "B",
+				StateFile:            "/tmp/orders.json",
+			},
+		},
+		{
+			testCase: "Checking for key column name without header row",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyMode:                   "cdc",
+				KeyKeyColumn:              "order_id",
+			},
+			err:      fmt.Errorf("\"keyColumn\" config value must be the column letters, if \"headerRow\" is not set, got: \"order_id\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for cdc mode without state file",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyMode:                   "cdc",
+			},
+			err:      fmt.Errorf("\"stateFile\" config value must be set, if \"mode\" is \"cdc\""),
+			expected: Config{},
+		},
+		{
+			testCase: "Checking for key column in append mode",
+			params: map[string]string{
+				config.KeyTokensFile:      validCredFile,
+				config.KeyCredentialsFile: validCredFile,
+				config.KeySpreadsheetID:   "19VVe4M-j8MGw-a3B7fcJQnx5JnHjiHf9dwChUkqQ4",
+				KeySheetName:              "Orders",
+				KeyKeyColumn:              "A",
+			},
+			err:      fmt.Errorf("\"keyColumn\" and \"stateFile\" config values can only be set, if \"mode\" is \"cdc\""),
+			expected: Config{},
+		},
 	}
 
 	for _, tc := range cases {
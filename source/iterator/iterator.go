@@ -32,8 +32,8 @@ import (
 type SheetsIterator struct {
 	// sheetsReader is the instance of BatchReader, which is a wrapper calling BatchGet Google sheets API
 	sheetsReader *sheets.BatchReader
-	// rowOffset is the row number of the last fetched row
-	rowOffset int64
+	// position is the position of the last fetched row, holding the row offset of every sheet read
+	position position.SheetPosition
 	// tomb is used to manage the go routines lifecycle
 	tomb *tomb.Tomb
 	// ticker is used to poll for new data in regular intervals
@@ -43,68 +43,129 @@ type SheetsIterator struct {
 	// buffer is subscribed by Next function to read for new data
 	// and block till new data becomes available, in case all the records have been read
 	buffer chan sdk.Record
+	// watcher receives the Drive push notifications triggering the reads, the sheet is polled by the ticker if nil
+	watcher *sheets.Watcher
+	// fallbackPeriod is the time after which the sheet is polled, if no read was triggered by a notification meanwhile
+	fallbackPeriod time.Duration
+	// lastRead is the time of the last read
+	lastRead time.Time
 }
 
 // NewSheetsIterator creates a new instance of sheets iterator and starts polling google sheets api for new changes
-// using the row offset of last successful row read in a separate go routine, row offset is received in sheet position
+// using the row offsets of last successful row read in a separate go routine, row offsets are received in sheet position.
+// If the watch address is set, the reads are triggered by the Drive push notifications, and the ticker is used as a fallback.
 func NewSheetsIterator(ctx context.Context,
 	tp position.SheetPosition,
 	args sheets.BatchReaderArgs,
+	watch sheets.WatchArgs,
 ) (*SheetsIterator, error) {
 	tmbWithCtx, _ := tomb.WithContext(ctx)
 	sheetsReader, err := sheets.NewBatchReader(ctx, args)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing sheets BatchReader: %w", err)
 	}
+	var watcher *sheets.Watcher
+	if watch.Address != "" {
+		watcher, err = sheets.NewWatcher(ctx, watch)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing drive notifications watcher: %w", err)
+		}
+	}
 
 	cdc := &SheetsIterator{
 		sheetsReader: sheetsReader,
-		rowOffset:    tp.RowOffset,
+		position:     tp,
 		tomb:         tmbWithCtx,
 		ticker:       time.NewTicker(args.PollingPeriod),
 		// keeping the length as 1 to be able to have 2nd cache of records ready when the first batch of records are successfully read
 		caches: make(chan []sdk.Record, 1),
 		// keeping the buffer size as one, to enable checking the availability of records using len() function on channel
-		buffer: make(chan sdk.Record, 1),
+		buffer:         make(chan sdk.Record, 1),
+		watcher:        watcher,
+		fallbackPeriod: watch.FallbackPeriod,
 	}
 
 	cdc.tomb.Go(cdc.startIterator(ctx))
 	cdc.tomb.Go(cdc.flush)
+	if watcher != nil {
+		cdc.tomb.Go(func() error {
+			return watcher.Run(cdc.tomb.Context(ctx))
+		})
+	}
 
 	return cdc, nil
 }
 
-// startIterator is the go routine function used to poll the google sheets API for new changes at regular intervals
+// startIterator is the go routine function used to poll the google sheets API for new changes at regular intervals,
+// or on the Drive push notifications if watched
 func (c *SheetsIterator) startIterator(ctx context.Context) func() error {
 	return func() error {
 		defer close(c.caches)
+		var notifications <-chan struct{}
+		if c.watcher != nil {
+			notifications = c.watcher.Notifications()
+		}
 		for {
 			select {
 			case <-c.tomb.Dying():
 				return c.tomb.Err()
-			case <-c.ticker.C:
-				records, err := c.sheetsReader.GetSheetRecords(ctx, c.rowOffset)
-				if err != nil {
-					return fmt.Errorf("unable to fetch records: %w", err)
+			case <-notifications:
+				// the notification received along with the stop doesn't trigger a read
+				if !c.tomb.Alive() {
+					return c.tomb.Err()
 				}
-				if len(records) == 0 {
+				if err := c.read(ctx); err != nil {
+					return err
+				}
+			case <-c.ticker.C:
+				if c.watching() {
 					continue
 				}
-				select {
-				case c.caches <- records:
-					pos, err := position.ParseRecordPosition(records[len(records)-1].Position)
-					if err != nil {
-						return fmt.Errorf("failed to parse record position: %w", err)
-					}
-					c.rowOffset = pos.RowOffset
-				case <-c.tomb.Dying():
-					return c.tomb.Err()
+				if err := c.read(ctx); err != nil {
+					return err
 				}
 			}
 		}
 	}
 }
 
+// watching reports whether the ticks are skipped, as the reads are triggered by the notifications.
+// The sheet is polled if the watcher is inactive, or if no read was triggered during the fallback period.
+func (c *SheetsIterator) watching() bool {
+	return c.watcher != nil && c.watcher.Active() && time.Since(c.lastRead) < c.fallbackPeriod
+}
+
+// read fetches the new records and pushes them into the caches, the pages of rows are read back to back
+// while catching up with the sheets, falling back to the polling period once the end of the sheets is reached
+func (c *SheetsIterator) read(ctx context.Context) error {
+	c.lastRead = time.Now()
+	for more := true; more; {
+		records, err := c.sheetsReader.GetSheetRecords(ctx, c.position)
+		if err != nil {
+			return fmt.Errorf("unable to fetch records: %w", err)
+		}
+		more = c.sheetsReader.HasMore()
+		if len(records) == 0 {
+			// a full page yields no records if its rows are blank or skipped, the next page is read after its rows
+			if more {
+				c.position = c.sheetsReader.PagePosition()
+			}
+			continue
+		}
+		select {
+		case c.caches <- records:
+			pos, err := position.ParseRecordPosition(records[len(records)-1].Position)
+			if err != nil {
+				return fmt.Errorf("failed to parse record position: %w", err)
+			}
+			c.position = pos
+		case <-c.tomb.Dying():
+			return c.tomb.Err()
+		}
+	}
+	return nil
+}
+
 // flush is the go routine, responsible for getting the array of records in caches channel
 // and pushing them into read buffer to be returned by Next function
 func (c *SheetsIterator) flush() error {
@@ -145,9 +206,23 @@ func (c *SheetsIterator) Next(ctx context.Context) (sdk.Record, error) {
 	}
 }
 
-// Stop the go routines and ticker
+// Ack acknowledges the record position, the state of the rows read in cdc mode is persisted if stored in a state file
+func (c *SheetsIterator) Ack(pos position.SheetPosition) error {
+	if c.sheetsReader == nil {
+		return nil
+	}
+	return c.sheetsReader.Ack(pos)
+}
+
+// Stop the go routines and ticker, saving the state of the acknowledged rows read in cdc mode
 func (c *SheetsIterator) Stop(ctx context.Context) {
 	sdk.Logger(ctx).Trace().Msg("iterator stopped")
 	c.ticker.Stop()
 	c.tomb.Kill(errors.New("iterator stopped"))
+	if c.sheetsReader == nil {
+		return
+	}
+	if err := c.sheetsReader.Close(); err != nil {
+		sdk.Logger(ctx).Error().Err(err).Msg("unable to save the state file")
+	}
 }
@@ -56,7 +56,7 @@ func TestNewSheetsIterator(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			res, err := NewSheetsIterator(context.Background(), tt.tp, tt.args)
+			res, err := NewSheetsIterator(context.Background(), tt.tp, tt.args, sheets.WatchArgs{})
 			if tt.err != nil {
 				assert.EqualError(t, err, tt.err.Error())
 			} else {
@@ -27,6 +27,63 @@ type SheetPosition struct {
 	RowOffset     int64  `json:"row_offset"`
 	SpreadsheetID string `json:"spreadsheet_id"`
 	SheetID       int64  `json:"sheet_id"`
+	// Offsets holds the row offset of every sheet read by the source, keyed by the sheet gid.
+	// It is only set when multiple sheets are read, RowOffset and SheetID are used otherwise.
+	Offsets map[int64]int64 `json:"offsets,omitempty"`
+
+	// Mode is ModeCDC if the position was returned by the source reading the changes of the rows, or the rows
+	// identified by their hash, empty if the source is reading the appended rows only
+	Mode string `json:"mode,omitempty"`
+	// Seq is the sequence number of the change, incremented for every change read in cdc mode, or every row read
+	// if the rows are identified by their hash. The state of the rows after the change is stored in the state file.
+	Seq int64 `json:"seq,omitempty"`
+
+	// Version is the Drive version of the spreadsheet, whose rows have all been read up to this position.
+	// It is only set in the position of the last record read from a version, 0 otherwise.
+	Version int64 `json:"version,omitempty"`
+
+	// Cursors holds the greatest value of the incremental column read from every sheet, keyed by the sheet gid.
+	// It is only set if the source reads the rows after the cursor, the row offsets are not used then.
+	Cursors map[int64]string `json:"cursors,omitempty"`
+
+	// CursorKeys holds the record keys, or the hashes of the values without key columns, of the rows read with the
+	// value of the cursor of every sheet, keyed by the sheet gid, so that the rows sharing the value are read once.
+	CursorKeys map[int64][]string `json:"cursorKeys,omitempty"`
+
+	// Phase is PhaseSnapshot for the rows read during the snapshot, PhaseCDC once all the rows have been read once.
+	// The position of the last row read by the snapshot is the first position with PhaseCDC.
+	Phase string `json:"phase,omitempty"`
+
+	// Schemas holds the version of the schema of every sheet, keyed by the sheet gid.
+	// It is only set if the source sets the schema definitions in the record metadata.
+	Schemas map[int64]SchemaVersion `json:"schemas,omitempty"`
+}
+
+// SchemaVersion is the version of the schema of a sheet, along with the fingerprint of the columns it was built from
+type SchemaVersion struct {
+	Version     int64  `json:"version"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// ModeCDC is the position mode of the source reading the changes of the rows
+const ModeCDC = "cdc"
+
+const (
+	// PhaseSnapshot is the phase of the source reading the rows existing when the source was started
+	PhaseSnapshot = "snapshot"
+	// PhaseCDC is the phase of the source reading the rows created, or the row changes, after the snapshot
+	PhaseCDC = "cdc"
+)
+
+// Offset returns the row offset of the sheet
+func (s SheetPosition) Offset(sheetID int64) int64 {
+	if offset, ok := s.Offsets[sheetID]; ok {
+		return offset
+	}
+	if s.SheetID == sheetID {
+		return s.RowOffset
+	}
+	return 0
 }
 
 // ParseRecordPosition is used to parse the sdk.Position to SheetPosition type
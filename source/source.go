@@ -39,6 +39,7 @@ type Iterator interface {
 	HasNext() bool
 	Next(ctx context.Context) (sdk.Record, error)
 	Stop(ctx context.Context)
+	Ack(pos position.SheetPosition) error
 }
 
 func NewSource() sdk.Source {
@@ -62,15 +63,42 @@ func (s *Source) Open(ctx context.Context, rp sdk.Position) error {
 		return fmt.Errorf("couldn't parse position: %w", err)
 	}
 
+	tokenSource := s.conf.TokenSource(ctx)
 	s.iterator, err = iterator.NewSheetsIterator(ctx, pos,
 		sheets.BatchReaderArgs{
-			OAuthConfig:          s.conf.OAuthConfig,
-			OAuthToken:           s.conf.OAuthToken,
+			TokenSource:          tokenSource,
 			SpreadsheetID:        s.conf.GoogleSpreadsheetID,
 			SheetID:              s.conf.GoogleSheetID,
+			SheetName:            s.conf.SheetName,
+			Range:                s.conf.Range,
+			NamedRange:           s.conf.NamedRange,
+			SheetNames:           s.conf.SheetNames,
 			DateTimeRenderOption: s.conf.DateTimeRenderOption,
 			ValueRenderOption:    s.conf.ValueRenderOption,
 			PollingPeriod:        s.conf.PollingPeriod,
+			HeaderRow:            s.conf.HeaderRow,
+			CDC:                  s.conf.Mode == ModeCDC,
+			KeyColumn:            s.conf.KeyColumn,
+			StateFile:            s.conf.StateFile,
+			PageSize:             s.conf.PageSize,
+			IncrementalColumn:    s.conf.IncrementalColumn,
+			Snapshot:             s.conf.Snapshot,
+			TypedValues:          s.conf.TypedValues,
+			SchemaFormat:         s.conf.SchemaFormat,
+			Format:               s.conf.Format,
+			HashRows:             s.conf.HashRows,
+			KeyColumns:           s.conf.KeyColumns,
+			KeySeparator:         s.conf.KeySeparator,
+			StructuredKey:        s.conf.StructuredKey,
+			SkipEmptyKeys:        s.conf.SkipEmptyKeys,
+		},
+		sheets.WatchArgs{
+			TokenSource:    tokenSource,
+			SpreadsheetID:  s.conf.GoogleSpreadsheetID,
+			Address:        s.conf.WatchAddress,
+			URL:            s.conf.WatchURL,
+			TTL:            s.conf.WatchTTL,
+			FallbackPeriod: s.conf.WatchFallbackPeriod,
 		},
 	)
 
@@ -105,12 +133,20 @@ func (s *Source) Teardown(ctx context.Context) error {
 }
 
 // Ack is called by the conduit server after the record has been successfully processed by all destination connectors
-// We do not need to send any ack to Google sheets as we poll the Sheets API for data, so there is no data to be ack'd
+// We do not need to send any ack to Google sheets as we poll the Sheets API for data, so there is no data to be ack'd.
+// In cdc mode, the acknowledged changes are persisted to the state file.
 func (s *Source) Ack(ctx context.Context, tp sdk.Position) error {
 	pos, err := position.ParseRecordPosition(tp)
 	if err != nil {
 		sdk.Logger(ctx).Error().Err(err).Msg("invalid position received")
+		return nil
+	}
+	sdk.Logger(ctx).Trace().Int64("row_offset", pos.RowOffset).Int64("seq", pos.Seq).Msg("message ack received")
+	if s.iterator == nil {
+		return nil
+	}
+	if err := s.iterator.Ack(pos); err != nil {
+		return fmt.Errorf("error persisting acknowledged position: %w", err)
 	}
-	sdk.Logger(ctx).Trace().Int64("row_offset", pos.RowOffset).Msg("message ack received")
 	return nil
 }
@@ -19,6 +19,7 @@ package googlesheets
 import (
 	"github.com/conduitio/conduit-connector-google-sheets/config"
 	"github.com/conduitio/conduit-connector-google-sheets/destination"
+	"github.com/conduitio/conduit-connector-google-sheets/sheets"
 	"github.com/conduitio/conduit-connector-google-sheets/source"
 	sdk "github.com/conduitio/conduit-connector-sdk"
 )
@@ -34,18 +35,68 @@ func Specification() sdk.Specification {
 		DestinationParams: map[string]sdk.Parameter{
 			config.KeyCredentialsFile: {
 				Default:     "",
-				Required:    true,
-				Description: "path to credentials.json file used",
+				Required:    false,
+				Description: "path to credentials.json file used, OAuth client credentials or a service account JSON key. One of credentialsFile, credentialsJSON, credentialsJSONBase64 is required.",
+			},
+			config.KeyCredentialsJSON: {
+				Default:     "",
+				Required:    false,
+				Description: "credentials JSON passed inline, alternative to credentialsFile.",
+			},
+			config.KeyCredentialsJSONBase64: {
+				Default:     "",
+				Required:    false,
+				Description: "base64 encoded credentials JSON, alternative to credentialsFile.",
 			},
 			config.KeyTokensFile: {
 				Default:     "",
-				Required:    true,
-				Description: "path to token.json file containing a json with at least refresh_token.",
+				Required:    false,
+				Description: "path to token.json file containing a json with at least refresh_token. One of tokensFile, tokenJSON, tokenJSONBase64 is required for oauth auth mode.",
+			},
+			config.KeyTokenJSON: {
+				Default:     "",
+				Required:    false,
+				Description: "token JSON passed inline, alternative to tokensFile.",
+			},
+			config.KeyTokenJSONBase64: {
+				Default:     "",
+				Required:    false,
+				Description: "base64 encoded token JSON, alternative to tokensFile.",
+			},
+			config.KeyAuthMode: {
+				Default:     "",
+				Required:    false,
+				Description: "Authentication mode. Valid values: oauth, serviceAccount. Detected from the credentials if not set.",
+			},
+			config.KeySubject: {
+				Default:     "",
+				Required:    false,
+				Description: "Email of the user to impersonate using domain-wide delegation, serviceAccount auth mode only.",
 			},
 			config.KeySheetURL: {
 				Default:     "",
-				Required:    true,
-				Description: "Google sheet url to fetch the records from",
+				Required:    false,
+				Description: "Google sheet url to fetch the records from. One of sheetsURL, spreadsheetId is required.",
+			},
+			config.KeySpreadsheetID: {
+				Default:     "",
+				Required:    false,
+				Description: "Google spreadsheet ID, alternative to sheetsURL.",
+			},
+			sheets.KeyFormat: {
+				Default:     "",
+				Required:    false,
+				Description: "Format of the record payloads. Valid values: jsonArray, jsonObject, csv, structured. If not set, the payload format of every record is detected.",
+			},
+			sheets.KeyCSVDelimiter: {
+				Default:     "",
+				Required:    false,
+				Description: "Delimiter of the fields of the CSV payloads, csv format only. Default: ,",
+			},
+			sheets.KeyCSVQuote: {
+				Default:     "",
+				Required:    false,
+				Description: "Quoting of the fields of the CSV payloads, csv format only. Valid values: minimal to quote the fields only if needed, all to quote all the fields. Default: minimal",
 			},
 			destination.KeySheetName: {
 				Default:     "",
@@ -62,27 +113,287 @@ func Specification() sdk.Specification {
 				Required:    false,
 				Description: "Max API retries to be attempted, in case of 429 error, before returning error",
 			},
+			destination.KeyHeaderRow: {
+				Default:     "0",
+				Required:    false,
+				Description: "Row number of the sheet holding the column names. If set, the fields of structured and JSON object payloads are written to the matching columns.",
+			},
+			destination.KeyAppendHeaders: {
+				Default:     "false",
+				Required:    false,
+				Description: "Whether to append the record fields missing in the header row as new columns, the fields are dropped otherwise. Requires headerRow.",
+			},
+			destination.KeyWriteMode: {
+				Default:     "append",
+				Required:    false,
+				Description: "Write mode. Valid values: append to append the records, upsert to update the rows with the record keys, overwrite to clear the rows at every snapshot, replace-on-snapshot to replace the rows with each snapshot once it is completed.",
+			},
+			destination.KeyKeyColumn: {
+				Default:     "",
+				Required:    false,
+				Description: "Column holding the row keys in upsert mode, the header name if headerRow is set, the column letters otherwise. Required for upsert.",
+			},
+			destination.KeyDeleteMode: {
+				Default:     "clear",
+				Required:    false,
+				Description: "How the delete records are handled in upsert mode. Valid values: clear to clear the row, remove to remove the row from the sheet.",
+			},
+			destination.KeyRouteByMetadata: {
+				Default:     "false",
+				Required:    false,
+				Description: "Whether to write the records to the spreadsheet and the sheet of their google.sheets.spreadsheetId and google.sheets.sheetTitle metadata, if set.",
+			},
+			destination.KeySheetNameTemplate: {
+				Default:     "",
+				Required:    false,
+				Description: "Go template rendering the sheet name of each record, e.g. {{ .Metadata.table }}. The sheets missing are created, the configured sheet is used if the template renders an empty string.",
+			},
+			destination.KeySpreadsheetIDTemplate: {
+				Default:     "",
+				Required:    false,
+				Description: "Go template rendering the spreadsheet ID of each record, the configured spreadsheet is used if the template renders an empty string.",
+			},
+			destination.KeyRolloverCells: {
+				Default:     "",
+				Required:    false,
+				Description: "Number of cells of the grid at which the destination rolls over to a new sheet or spreadsheet in append mode, up to the 10000000 cells limit of a spreadsheet. Rolling over is disabled if not set.",
+			},
+			destination.KeyRolloverTo: {
+				Default:     "sheet",
+				Required:    false,
+				Description: "What the destination rolls over to, sheet for a new sheet named after the sheet with a sequence number (e.g. Log_0002), or spreadsheet for a new spreadsheet named after the spreadsheet with a sequence number, moved to the folders of the spreadsheet and shared like it.",
+			},
+			destination.KeyStateFile: {
+				Default:     "",
+				Required:    false,
+				Description: "Path of the file recording the sheets and spreadsheets rolled over to, so that the destination keeps writing to them after a restart. Required for rolloverCells.",
+			},
+			destination.KeySchema: {
+				Default:     "",
+				Required:    false,
+				Description: "Declared schema the records are validated against before they are written, a JSON array of the columns, e.g. [{\"name\":\"id\",\"type\":\"integer\"}]. The records not matching the schema are rejected.",
+			},
+			destination.KeyCreateSheet: {
+				Default:     "false",
+				Required:    false,
+				Description: "Whether to create the sheet on Open, if it's not found in the spreadsheet. An error listing the sheets found is returned otherwise. Defaults to true if sheetNameTemplate is set.",
+			},
+			destination.KeyFrozenRows: {
+				Default:     "",
+				Required:    false,
+				Description: "Number of rows frozen at the top of the sheet created. Defaults to the header row.",
+			},
+			destination.KeyColumnWidths: {
+				Default:     "",
+				Required:    false,
+				Description: "Comma separated widths in pixels of the first columns of the sheet created.",
+			},
+			destination.KeyGridRows: {
+				Default:     "",
+				Required:    false,
+				Description: "Number of rows of the grid of the sheet created. Defaults to the Google Sheets default.",
+			},
+			destination.KeyGridColumns: {
+				Default:     "",
+				Required:    false,
+				Description: "Number of columns of the grid of the sheet created. Defaults to the Google Sheets default.",
+			},
+			destination.KeyHeaders: {
+				Default:     "",
+				Required:    false,
+				Description: "Comma separated column names written to the header row on Open, if it is empty. Requires headerRow. The header row is written from the fields of the first record if not set.",
+			},
 			destination.KeyBufferSize: {
 				Default:     "100",
 				Required:    false,
 				Description: "max rows to be appended in one API call",
 			},
+			destination.KeyFlushInterval: {
+				Default:     "",
+				Required:    false,
+				Description: "Interval the partially filled buffers are written at, e.g. 5s. The buffers are written once full only if not set.",
+			},
+			destination.KeyMaxBufferBytes: {
+				Default:     "",
+				Required:    false,
+				Description: "Maximum size in bytes of the payloads of a buffer, the buffer is written before a record makes it exceed it. Not limited if not set.",
+			},
 		},
 		SourceParams: map[string]sdk.Parameter{
 			config.KeyCredentialsFile: {
 				Default:     "",
-				Required:    true,
-				Description: "path to credentials.json file used",
+				Required:    false,
+				Description: "path to credentials.json file used, OAuth client credentials or a service account JSON key. One of credentialsFile, credentialsJSON, credentialsJSONBase64 is required.",
+			},
+			config.KeyCredentialsJSON: {
+				Default:     "",
+				Required:    false,
+				Description: "credentials JSON passed inline, alternative to credentialsFile.",
+			},
+			config.KeyCredentialsJSONBase64: {
+				Default:     "",
+				Required:    false,
+				Description: "base64 encoded credentials JSON, alternative to credentialsFile.",
 			},
 			config.KeyTokensFile: {
 				Default:     "",
-				Required:    true,
-				Description: "path to token.json file containing a json with atleast refresh_token.",
+				Required:    false,
+				Description: "path to token.json file containing a json with atleast refresh_token. One of tokensFile, tokenJSON, tokenJSONBase64 is required for oauth auth mode.",
+			},
+			config.KeyTokenJSON: {
+				Default:     "",
+				Required:    false,
+				Description: "token JSON passed inline, alternative to tokensFile.",
+			},
+			config.KeyTokenJSONBase64: {
+				Default:     "",
+				Required:    false,
+				Description: "base64 encoded token JSON, alternative to tokensFile.",
+			},
+			config.KeyAuthMode: {
+				Default:     "",
+				Required:    false,
+				Description: "Authentication mode. Valid values: oauth, serviceAccount. Detected from the credentials if not set.",
+			},
+			config.KeySubject: {
+				Default:     "",
+				Required:    false,
+				Description: "Email of the user to impersonate using domain-wide delegation, serviceAccount auth mode only.",
 			},
 			config.KeySheetURL: {
 				Default:     "",
-				Required:    true,
-				Description: "Google sheet url to fetch the records from",
+				Required:    false,
+				Description: "Google sheet url to fetch the records from. One of sheetsURL, spreadsheetId is required.",
+			},
+			config.KeySpreadsheetID: {
+				Default:     "",
+				Required:    false,
+				Description: "Google spreadsheet ID, alternative to sheetsURL.",
+			},
+			sheets.KeyFormat: {
+				Default:     "",
+				Required:    false,
+				Description: "Format of the record payloads. Valid values: jsonArray, jsonObject, csv, structured. If not set, the rows are read as structured data with a header row, as JSON arrays otherwise.",
+			},
+			sheets.KeyCSVDelimiter: {
+				Default:     "",
+				Required:    false,
+				Description: "Delimiter of the fields of the CSV payloads, csv format only. Default: ,",
+			},
+			sheets.KeyCSVQuote: {
+				Default:     "",
+				Required:    false,
+				Description: "Quoting of the fields of the CSV payloads, csv format only. Valid values: minimal to quote the fields only if needed, all to quote all the fields. Default: minimal",
+			},
+			source.KeySheetName: {
+				Default:     "",
+				Required:    false,
+				Description: "Title of the sheet to fetch the records from, takes precedence over the gid in sheetsURL.",
+			},
+			source.KeyRange: {
+				Default:     "",
+				Required:    false,
+				Description: "Range in A1 notation to fetch the records from, e.g. Orders!A1:F. Only the columns inside the range are read.",
+			},
+			source.KeyNamedRange: {
+				Default:     "",
+				Required:    false,
+				Description: "Named range to fetch the records from.",
+			},
+			source.KeySheetNames: {
+				Default:     "",
+				Required:    false,
+				Description: "Comma separated titles of the sheets to fetch the records from, or * to fetch from all the sheets.",
+			},
+			source.KeyHeaderRow: {
+				Default:     "0",
+				Required:    false,
+				Description: "Row number holding the field names, relative to the start of the range. If set, the rows are read as structured data keyed by the field names.",
+			},
+			source.KeyMode: {
+				Default:     "append",
+				Required:    false,
+				Description: "Read mode. Valid values: append to read the appended rows, cdc to read the created, updated and deleted rows.",
+			},
+			source.KeyKeyColumn: {
+				Default:     "",
+				Required:    false,
+				Description: "Column identifying the rows in cdc mode, the header name if headerRow is set, the column letters otherwise. The rows are identified by the row number if not set.",
+			},
+			source.KeyStateFile: {
+				Default:     "",
+				Required:    false,
+				Description: "Path of the file storing the state of the rows in cdc mode, or the ids of the rows read if rowIdentity is hash. Required then.",
+			},
+			source.KeyIncrementalColumn: {
+				Default:     "",
+				Required:    false,
+				Description: "Column holding a timestamp or a sequence, the header name if headerRow is set, the column letters otherwise. If set, the rows with a value greater than the greatest value read, or equal to it and not read yet, are read, instead of the rows after the last row read. Requires valueRenderOption UNFORMATTED_VALUE and dateTimeRenderOption SERIAL_NUMBER, or typedValues.",
+			},
+			source.KeySnapshot: {
+				Default:     "true",
+				Required:    false,
+				Description: "Whether the rows existing when the source is started are read as snapshots, before the created rows or the row changes are read.",
+			},
+			source.KeyTypedValues: {
+				Default:     "false",
+				Required:    false,
+				Description: "Whether the values are converted to numbers, booleans and RFC 3339 timestamps using the number formats of the columns. The schema of the columns is set in the record metadata.",
+			},
+			source.KeySchemaFormat: {
+				Default:     "",
+				Required:    false,
+				Description: "Format of the schema definition of the sheet set in the record metadata, derived from the header row and the column types. Valid values: avro, json. No definition is set if empty.",
+			},
+			source.KeyRowIdentity: {
+				Default:     "offset",
+				Required:    false,
+				Description: "Identification of the rows read in append mode. Valid values: offset to store the row offset of the last row read, hash to store the hashes of the rows read in the stateFile, so the rows inserted, deleted or moved are reconciled.",
+			},
+			source.KeyKeyColumns: {
+				Default:     "",
+				Required:    false,
+				Description: "Comma separated columns whose values compose the record key, the header names if headerRow is set, the column letters otherwise. The record key is the row number, or the key column in cdc mode, if empty.",
+			},
+			source.KeyKeySeparator: {
+				Default:     "|",
+				Required:    false,
+				Description: "Separator joining the values of the key columns in the record key.",
+			},
+			source.KeyKeyFormat: {
+				Default:     "raw",
+				Required:    false,
+				Description: "Format of the record key composed of the key columns. Valid values: raw to join the values by the key separator, structured for structured data keyed by the key columns.",
+			},
+			source.KeyEmptyKeyPolicy: {
+				Default:     "skip",
+				Required:    false,
+				Description: "Handling of the rows whose key columns are all blank. Valid values: skip to skip the rows, error to fail the read.",
+			},
+			source.KeyPageSize: {
+				Default:     "1000",
+				Required:    false,
+				Description: "Maximum number of rows read from a sheet by a request. The pages are read back to back while catching up with the sheet.",
+			},
+			source.KeyWatchAddress: {
+				Default:     "",
+				Required:    false,
+				Description: "Address the listener receiving the Drive push notifications listens on. If set, the reads are triggered by the notifications.",
+			},
+			source.KeyWatchURL: {
+				Default:     "",
+				Required:    false,
+				Description: "Public HTTPS URL the Drive push notifications are sent to, routed to the watchAddress listener. Required if watchAddress is set.",
+			},
+			source.KeyWatchTTL: {
+				Default:     "1h",
+				Required:    false,
+				Description: "Time to live of the Drive notification channel, up to 24h. The channel is renewed before it expires.",
+			},
+			source.KeyWatchFallbackPeriod: {
+				Default:     "5m",
+				Required:    false,
+				Description: "Time after which the sheet is polled, if no notification is received meanwhile.",
 			},
 			source.KeyPollingPeriod: {
 				Default:     "6s",